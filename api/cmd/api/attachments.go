@@ -0,0 +1,447 @@
+package main
+
+import (
+	"ForumAPI/internal/data"
+	"ForumAPI/internal/media"
+	"ForumAPI/internal/storage"
+	"ForumAPI/internal/validator"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/alexedwards/flow"
+)
+
+const (
+	maxAttachmentsPerPost = 10
+	thumbnailSize         = 320
+	avatarSize            = 256
+)
+
+// randomName returns a random name for a stored file, so that its URL cannot be guessed
+func randomName() (string, error) {
+
+	randomBytes := make([]byte, 16)
+
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes)), nil
+}
+
+// sanitizeFilename keeps the base name of the uploaded file without its control characters
+func sanitizeFilename(filename string) string {
+
+	filename = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, path.Base(strings.ReplaceAll(filename, "\\", "/")))
+
+	if filename == "." || filename == "/" || filename == "" {
+		filename = "file"
+	}
+
+	runes := []rune(filename)
+	if len(runes) > 255 {
+		filename = string(runes[len(runes)-255:])
+	}
+
+	return filename
+}
+
+// storeFile puts the processed file in the storage under the key
+func (app *application) storeFile(key string, file *media.File) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	return app.storage.Put(ctx, key, bytes.NewReader(file.Data), int64(len(file.Data)), file.ContentType)
+}
+
+// removeFiles deletes the files stored under the keys in the background, ignoring the empty keys
+func (app *application) removeFiles(keys ...string) {
+
+	app.background(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		for _, key := range keys {
+			if key == "" {
+				continue
+			}
+			err := app.storage.Delete(ctx, key)
+			if err != nil {
+				app.logger.Error(err.Error())
+			}
+		}
+	})
+}
+
+func (app *application) cleanOrphanAttachments(frequency, timeout time.Duration) {
+	defer func() {
+		if err := recover(); err != nil {
+			app.logger.Error(fmt.Sprintf("%v", err))
+		}
+	}()
+	time.Sleep(timeout)
+	for {
+		err := app.removeOrphanAttachments()
+		if err != nil {
+			app.logger.Error(err.Error())
+		}
+		time.Sleep(frequency)
+	}
+}
+
+// removeOrphanAttachments removes the files and then the records of the attachments which do not belong to a post anymore
+func (app *application) removeOrphanAttachments() error {
+
+	for {
+		attachments, err := app.models.Attachments.GetOrphans(100)
+		if err != nil {
+			return err
+		}
+		if len(attachments) == 0 {
+			return nil
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+
+		for _, attachment := range attachments {
+			err = app.storage.Delete(ctx, attachment.StorageKey)
+			if err == nil && attachment.ThumbnailKey != "" {
+				err = app.storage.Delete(ctx, attachment.ThumbnailKey)
+			}
+			if err == nil {
+				err = app.models.Attachments.Delete(attachment.ID)
+			}
+			if err != nil {
+				cancel()
+				return err
+			}
+		}
+
+		cancel()
+	}
+}
+
+func (app *application) uploadAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	post, err := app.models.Posts.GetByID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	user := app.contextGetUser(r)
+	if !user.HasPermission(post.Author.ID) {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	content, filename, err := app.readFile(w, r, "file", app.config.upload.maxSize)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	v.Check(len(post.Attachments) < maxAttachmentsPerPost, "file", fmt.Sprintf("a post must not have more than %d attachments", maxAttachmentsPerPost))
+
+	file, thumbnail, err := media.Process(content, thumbnailSize)
+	if err != nil {
+		switch {
+		case errors.Is(err, media.ErrUnsupportedType):
+			v.AddError("file", "must be a JPEG, PNG, GIF or WebP image, a PDF or a plain text file")
+		case errors.Is(err, media.ErrTooManyPixels):
+			v.AddError("file", fmt.Sprintf("must not have more than %d pixels", media.MaxPixels))
+		default:
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	size := int64(len(file.Data))
+	var thumbSize int64
+	if thumbnail != nil {
+		thumbSize = int64(len(thumbnail.Data))
+	}
+
+	used, err := app.models.Attachments.UsedQuota(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	v.Check(used+size+thumbSize <= app.config.upload.quota, "file", fmt.Sprintf("exceeds your storage quota (%d of %d bytes used)", used, app.config.upload.quota))
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	name, err := randomName()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// the extension follows the stored content, which may have been re-encoded
+	filename = sanitizeFilename(filename)
+	filename = strings.TrimSuffix(filename, path.Ext(filename)) + file.Extension
+
+	attachment := &data.Attachment{
+		PostID:      post.ID,
+		UploaderID:  user.ID,
+		Filename:    filename,
+		ContentType: file.ContentType,
+		Size:        size,
+		StorageKey:  fmt.Sprintf("attachments/%d/%s%s", post.ID, name, file.Extension),
+		Width:       file.Width,
+		Height:      file.Height,
+	}
+
+	err = app.storeFile(attachment.StorageKey, file)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if thumbnail != nil {
+		attachment.ThumbnailKey = fmt.Sprintf("attachments/%d/%s_thumb%s", post.ID, name, thumbnail.Extension)
+		attachment.ThumbnailSize = thumbSize
+
+		err = app.storeFile(attachment.ThumbnailKey, thumbnail)
+		if err != nil {
+			app.removeFiles(attachment.StorageKey)
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	err = app.models.Attachments.Insert(attachment)
+	if err != nil {
+		app.removeFiles(attachment.StorageKey, attachment.ThumbnailKey)
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.audit(r, data.AuditAction.Create, data.AuditEntity.Attachment, attachment.ID, nil, attachment)
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"attachment": attachment}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	attachment, authorID, err := app.models.Attachments.GetByID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if !app.contextGetUser(r).HasPermission(authorID) {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	// the files are removed by the cleaning of the orphan attachments
+	err = app.models.Attachments.Detach(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.audit(r, data.AuditAction.Delete, data.AuditEntity.Attachment, id, attachment, nil)
+
+	response := envelope{
+		"message": fmt.Sprintf("deleted attachment with id %d", id),
+	}
+
+	err = app.writeJSON(w, http.StatusOK, response, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateAvatarHandler(w http.ResponseWriter, r *http.Request) {
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrUserIDNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.badRequestResponse(w, r, err)
+		}
+		return
+	}
+
+	user, err := app.models.Users.GetByID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	content, _, err := app.readFile(w, r, "avatar", app.config.upload.maxSize)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	avatar, err := media.Avatar(content, avatarSize)
+	if err != nil {
+		switch {
+		case errors.Is(err, media.ErrUnsupportedType):
+			v.AddError("avatar", "must be a JPEG, PNG, GIF or WebP image")
+		case errors.Is(err, media.ErrTooManyPixels):
+			v.AddError("avatar", fmt.Sprintf("must not have more than %d pixels", media.MaxPixels))
+		default:
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	name, err := randomName()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	key := fmt.Sprintf("avatars/%d-%s%s", user.ID, name, avatar.Extension)
+
+	err = app.storeFile(key, avatar)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	before := app.auditSnapshot(user)
+	previousAvatar := user.Avatar
+
+	user.Avatar = data.FileURL(key)
+
+	err = app.models.Users.Update(user)
+	if err != nil {
+		app.removeFiles(key)
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// the previous avatar is only removed if it was uploaded as well
+	if previousKey, ok := data.FileKey(previousAvatar); ok {
+		app.removeFiles(previousKey)
+	}
+
+	app.audit(r, data.AuditAction.Update, data.AuditEntity.User, user.ID, before, user)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"avatar": user.Avatar}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// serveFileHandler sends the stored file, which is public to anyone knowing its URL
+func (app *application) serveFileHandler(w http.ResponseWriter, r *http.Request) {
+
+	key := flow.Param(r.Context(), "...")
+	if !storage.ValidKey(key) {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	file, err := app.storage.Get(r.Context(), key)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound), errors.Is(err, storage.ErrInvalidKey):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	defer file.Close()
+
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	// only the images are displayed by the browsers, the other files being downloaded
+	if !media.IsImage(contentType) {
+		w.Header().Set("Content-Disposition", "attachment")
+	}
+
+	// the stored files never change, a new file getting a new key
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	_, err = io.Copy(w, file)
+	if err != nil {
+		app.logError(r, err)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"ForumAPI/internal/data"
+	"ForumAPI/internal/validator"
+	"fmt"
+	"github.com/tomasen/realip"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+type auditForm struct {
+	ActorID    int    `form:"actor_id"`
+	EntityType string `form:"entity_type"`
+	EntityID   int    `form:"entity_id"`
+	From       string `form:"from"`
+	To         string `form:"to"`
+	data.Filters
+	validator.Validator `form:"-"`
+}
+
+// auditSnapshot captures the state of an entity before it is modified
+// (an empty snapshot if it fails, so that the callers can still add to it)
+func (app *application) auditSnapshot(entity any) map[string]any {
+
+	snapshot, err := data.AuditSnapshot(entity)
+	if err != nil {
+		app.logger.Error(err.Error())
+		return map[string]any{}
+	}
+
+	return snapshot
+}
+
+// audit records in the audit log the action performed by the current user on an entity.
+// before and after are either snapshots or the entity itself (nil when it doesn't exist on that side).
+// Failing to record the action is logged and doesn't interrupt the request.
+func (app *application) audit(r *http.Request, action, entityType string, entityID int, before, after any) {
+
+	user := app.contextGetUser(r)
+	client := app.contextGetClient(r)
+
+	entry := &data.AuditEntry{
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Changes:    data.AuditDiff(app.auditSnapshot(before), app.auditSnapshot(after)),
+		IP:         realip.FromRequest(r),
+	}
+	entry.Actor.ID = user.ID
+	entry.Actor.Name = user.Name
+	entry.Client.ID = client.ID
+	entry.Client.Name = client.Name
+
+	app.background(func() {
+		err := app.models.Audit.Insert(entry)
+		if err != nil {
+			app.logger.Error("failed to record audit entry", slog.String("action", action), slog.String("entity_type", entityType), slog.Int("entity_id", entityID), slog.String("error", err.Error()))
+		}
+	})
+}
+
+// parseAuditTime accepts either a full RFC 3339 timestamp or a date in the format YYYY-MM-DD
+func parseAuditTime(value string) (time.Time, error) {
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err == nil {
+		return t, nil
+	}
+
+	return time.Parse("2006-01-02", value)
+}
+
+func (app *application) getAuditHandler(w http.ResponseWriter, r *http.Request) {
+
+	form := newAuditForm()
+
+	err := app.decodeForm(r, &form)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if form.Page == 0 {
+		form.Page = 1
+	}
+	if form.PageSize == 0 {
+		form.PageSize = 50
+	}
+	if form.Sort == "" {
+		form.Sort = form.SortSafelist[0]
+	}
+
+	data.ValidateFilters(&form.Validator, form.Filters)
+
+	auditFilters := data.AuditFilters{
+		ActorID:  form.ActorID,
+		EntityID: form.EntityID,
+	}
+
+	if form.EntityType != "" {
+		form.Check(validator.PermittedValue(form.EntityType, data.PermittedAuditEntities...), "entity_type", fmt.Sprintf("incorrect value %s", form.EntityType))
+		auditFilters.EntityType = form.EntityType
+	}
+	form.Check(form.EntityID == 0 || form.EntityType != "", "entity_type", "must be provided with entity_id")
+	if form.From != "" {
+		auditFilters.From, err = parseAuditTime(form.From)
+		if err != nil {
+			form.AddError("from", "must be a valid date in the format YYYY-MM-DD or RFC 3339")
+		}
+	}
+	if form.To != "" {
+		auditFilters.To, err = parseAuditTime(form.To)
+		if err != nil {
+			form.AddError("to", "must be a valid date in the format YYYY-MM-DD or RFC 3339")
+		}
+	}
+	if !auditFilters.From.IsZero() && !auditFilters.To.IsZero() {
+		form.Check(auditFilters.From.Before(auditFilters.To), "to", "must be after from")
+	}
+
+	if !form.Valid() {
+		err = app.writeJSON(w, http.StatusBadRequest, envelope{"errors": form.Errors}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	entries, metadata, err := app.models.Audit.Get(auditFilters, form.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"_metadata": metadata, "audit": entries}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
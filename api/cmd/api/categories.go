@@ -55,7 +55,12 @@ func (app *application) getCategoriesHandler(w http.ResponseWriter, r *http.Requ
 
 	categories, metadata, err := app.models.Categories.Get(form.Search, form.Filters)
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
+		switch {
+		case errors.Is(err, data.ErrInvalidCursor):
+			app.badRequestResponse(w, r, err)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
@@ -112,12 +117,17 @@ func (app *application) createCategoryHandler(w http.ResponseWriter, r *http.Req
 		case errors.Is(err, data.ErrDuplicateName):
 			v.AddError("name", "a category with this name already exists")
 			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("parent_category_id", "must refer to an existing category")
+			app.failedValidationResponse(w, r, v.Errors)
 		default:
 			app.serverErrorResponse(w, r, err)
 		}
 		return
 	}
 
+	app.audit(r, data.AuditAction.Create, data.AuditEntity.Category, category.ID, nil, category)
+
 	err = app.writeJSON(w, http.StatusCreated, envelope{"category": category}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -237,6 +247,8 @@ func (app *application) updateCategoryHandler(w http.ResponseWriter, r *http.Req
 		}
 	}
 
+	before := app.auditSnapshot(category)
+
 	var input struct {
 		Name             *string `json:"name"`
 		ParentCategoryID *int    `json:"parent_category_id"`
@@ -278,6 +290,8 @@ func (app *application) updateCategoryHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	app.audit(r, data.AuditAction.Update, data.AuditEntity.Category, category.ID, before, category)
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"category": category}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -309,9 +323,13 @@ func (app *application) deleteCategoryHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	err = app.models.Categories.Delete(id)
+	err = app.models.Categories.Delete(id, user.ID)
 	if err != nil {
 		switch {
+		case errors.Is(err, data.ErrNotEmpty):
+			v := validator.New()
+			v.AddError("id", "the category still contains threads or subcategories")
+			app.failedValidationResponse(w, r, v.Errors)
 		case errors.Is(err, data.ErrRecordNotFound):
 			app.notFoundResponse(w, r)
 		default:
@@ -320,6 +338,8 @@ func (app *application) deleteCategoryHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	app.audit(r, data.AuditAction.Delete, data.AuditEntity.Category, id, category, nil)
+
 	response := envelope{
 		"message": fmt.Sprintf("deleted category with id %d", id),
 	}
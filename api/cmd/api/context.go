@@ -8,7 +8,10 @@ import (
 
 type contextKey string
 
-const userContextKey = contextKey("user")
+const (
+	userContextKey   = contextKey("user")
+	clientContextKey = contextKey("client")
+)
 
 func (app *application) contextSetUser(r *http.Request, user *data.User) *http.Request {
 	ctx := context.WithValue(r.Context(), userContextKey, user)
@@ -23,3 +26,17 @@ func (app *application) contextGetUser(r *http.Request) *data.User {
 
 	return user
 }
+
+func (app *application) contextSetClient(r *http.Request, client *data.User) *http.Request {
+	ctx := context.WithValue(r.Context(), clientContextKey, client)
+	return r.WithContext(ctx)
+}
+
+func (app *application) contextGetClient(r *http.Request) *data.User {
+	client, ok := r.Context().Value(clientContextKey).(*data.User)
+	if !ok {
+		panic("missing client value in request context")
+	}
+
+	return client
+}
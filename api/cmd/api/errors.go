@@ -1,9 +1,13 @@
 package main
 
 import (
+	"ForumAPI/internal/data"
 	"fmt"
 	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 func (app *application) logError(r *http.Request, err error) {
@@ -60,6 +64,13 @@ func (app *application) rateLimitExceededResponse(w http.ResponseWriter, r *http
 	app.errorResponse(w, r, http.StatusTooManyRequests, message)
 }
 
+func (app *application) tooManyLoginAttemptsResponse(w http.ResponseWriter, r *http.Request, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	message := fmt.Sprintf("too many failed login attempts, please try again in %s", time.Duration(seconds)*time.Second)
+	app.errorResponse(w, r, http.StatusTooManyRequests, message)
+}
+
 func (app *application) invalidCredentialsResponse(w http.ResponseWriter, r *http.Request) {
 	message := "invalid authentication credentials"
 	app.errorResponse(w, r, http.StatusUnauthorized, message)
@@ -89,7 +100,23 @@ func (app *application) inactiveAccountResponse(w http.ResponseWriter, r *http.R
 	app.errorResponse(w, r, http.StatusForbidden, message)
 }
 
+func (app *application) suspendedAccountResponse(w http.ResponseWriter, r *http.Request, user *data.User) {
+	message := "your user account has been suspended"
+	if user.SuspendedUntil != nil {
+		message += fmt.Sprintf(" until %s", user.SuspendedUntil.Format(time.RFC1123))
+	}
+	if user.SuspensionReason != "" {
+		message += fmt.Sprintf(" (reason: %s)", user.SuspensionReason)
+	}
+	app.errorResponse(w, r, http.StatusForbidden, message)
+}
+
 func (app *application) notPermittedResponse(w http.ResponseWriter, r *http.Request) {
 	message := "your user account doesn't have the necessary permissions to access this resource"
 	app.errorResponse(w, r, http.StatusForbidden, message)
 }
+
+func (app *application) serverShuttingDownResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the server is shutting down, please try again later"
+	app.errorResponse(w, r, http.StatusServiceUnavailable, message)
+}
@@ -9,7 +9,12 @@ import (
 	"strings"
 )
 
-func (app *application) getFriendsByUser(user *data.User) error {
+// getFriendsByUser sets the user's friends if the viewer may see them, and the pending invitations to the owner only
+func (app *application) getFriendsByUser(user *data.User, access *profileAccess) error {
+
+	if !access.allows(access.privacy.Friends) {
+		return nil
+	}
 
 	sentFriends, receivedFriends, err := app.models.Users.GetFriendsByUserID(user.ID)
 	if err != nil {
@@ -19,7 +24,9 @@ func (app *application) getFriendsByUser(user *data.User) error {
 	for _, friend := range sentFriends {
 		switch friend.Status {
 		case data.FriendStatus.Pending:
-			user.Invitations.Sent = append(user.Invitations.Sent, friend)
+			if access.owner {
+				user.Invitations.Sent = append(user.Invitations.Sent, friend)
+			}
 		case data.FriendStatus.Accepted:
 			user.Friends = append(user.Friends, friend)
 		case data.FriendStatus.Rejected:
@@ -32,7 +39,9 @@ func (app *application) getFriendsByUser(user *data.User) error {
 	for _, friend := range receivedFriends {
 		switch friend.Status {
 		case data.FriendStatus.Pending:
-			user.Invitations.Received = append(user.Invitations.Sent, friend)
+			if access.owner {
+				user.Invitations.Received = append(user.Invitations.Received, friend)
+			}
 		case data.FriendStatus.Accepted:
 			user.Friends = append(user.Friends, friend)
 		case data.FriendStatus.Rejected:
@@ -70,6 +79,17 @@ func (app *application) friendRequestHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	notification := &data.Notification{
+		UserID: id,
+		Type:   data.NotificationType.FriendRequest,
+	}
+	notification.Actor.ID = user.ID
+	notification.Actor.Name = user.Name
+
+	app.notify(notification)
+
+	app.audit(r, data.AuditAction.FriendRequest, data.AuditEntity.User, id, nil, nil)
+
 	response := envelope{
 		"message": fmt.Sprintf("requested friend with id %d", id),
 	}
@@ -130,6 +150,19 @@ func (app *application) friendResponseHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	if input.Status == data.FriendStatus.Accepted {
+		notification := &data.Notification{
+			UserID: id,
+			Type:   data.NotificationType.FriendAccepted,
+		}
+		notification.Actor.ID = user.ID
+		notification.Actor.Name = user.Name
+
+		app.notify(notification)
+	}
+
+	app.audit(r, data.AuditAction.FriendResponse, data.AuditEntity.User, id, nil, envelope{"status": input.Status})
+
 	response := envelope{
 		"message": fmt.Sprintf("%sed friend with id %d", input.Status, id),
 	}
@@ -161,6 +194,8 @@ func (app *application) friendDeleteHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	app.audit(r, data.AuditAction.Unfriend, data.AuditEntity.User, id, nil, nil)
+
 	response := envelope{
 		"message": fmt.Sprintf("removed friend with id %d", id),
 	}
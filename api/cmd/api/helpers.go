@@ -31,6 +31,15 @@ func newUserByIDForm() *userByIDForm {
 	}
 }
 
+func newGetUsersForm() *getUsersForm {
+	return &getUsersForm{
+		Validator: *validator.New(),
+		Filters: data.Filters{
+			SortSafelist: []string{"Created_at", "Username", "Role", "Status", "-Created_at", "-Username", "-Role", "-Status"},
+		},
+	}
+}
+
 func newCategoryByIDForm() *categoryByIDForm {
 	return &categoryByIDForm{
 		PermittedFields: []string{"categories", "threads"},
@@ -43,6 +52,7 @@ func newGetCategoriesForm() *getCategoriesForm {
 		Validator: *validator.New(),
 		Filters: data.Filters{
 			SortSafelist: []string{"Created_at", "Updated_at", "Name", "Id_author", "Id_parent_categories", "-Created_at", "-Updated_at", "-Name", "-Id_author", "-Id_parent_categories"},
+			Cursorable:   true,
 		},
 	}
 }
@@ -59,6 +69,7 @@ func newGetThreadsForm() *getThreadsForm {
 		Validator: *validator.New(),
 		Filters: data.Filters{
 			SortSafelist: []string{"Created_at", "Updated_at", "Title", "Is_public", "Status", "Id_author", "Id_categories", "-Created_at", "-Updated_at", "-Title", "-Is_public", "-Status", "-Id_author", "-Id_categories"},
+			Cursorable:   true,
 		},
 	}
 }
@@ -75,6 +86,7 @@ func newGetTagsForm() *getTagsForm {
 		Validator: *validator.New(),
 		Filters: data.Filters{
 			SortSafelist: []string{"Created_at", "Updated_at", "Name", "Id_author", "-Created_at", "-Updated_at", "-Name", "-Id_author"},
+			Cursorable:   true,
 		},
 	}
 }
@@ -86,11 +98,99 @@ func newPostByIDForm() *postByIDForm {
 	}
 }
 
+func newPostRepliesForm() *postRepliesForm {
+	return &postRepliesForm{
+		Validator: *validator.New(),
+	}
+}
+
 func newGetPostsForm() *getPostsForm {
 	return &getPostsForm{
 		Validator: *validator.New(),
 		Filters: data.Filters{
 			SortSafelist: []string{"Created_at", "Updated_at", "Id_author", "Id_threads", "-Created_at", "-Updated_at", "-Id_author", "-Id_threads"},
+			Cursorable:   true,
+		},
+	}
+}
+
+func newRecommendationsForm() *recommendationsForm {
+	return &recommendationsForm{
+		Validator: *validator.New(),
+		Filters: data.Filters{
+			SortSafelist: []string{"-Score", "Score", "-Created_at", "Created_at"},
+		},
+	}
+}
+
+func newModerationQueueForm() *moderationQueueForm {
+	return &moderationQueueForm{
+		Validator: *validator.New(),
+		Filters: data.Filters{
+			SortSafelist: []string{"Created_at", "-Created_at", "Resolved_at", "-Resolved_at"},
+		},
+	}
+}
+
+func newAuditForm() *auditForm {
+	return &auditForm{
+		Validator: *validator.New(),
+		Filters: data.Filters{
+			SortSafelist: []string{"-Created_at", "Created_at"},
+		},
+	}
+}
+
+func newNotificationsForm() *notificationsForm {
+	return &notificationsForm{
+		Validator: *validator.New(),
+		Filters: data.Filters{
+			SortSafelist: []string{"-Created_at", "Created_at"},
+		},
+	}
+}
+
+func newStreamForm() *streamForm {
+	return &streamForm{
+		Validator: *validator.New(),
+	}
+}
+
+func newConversationsForm() *conversationsForm {
+	return &conversationsForm{
+		Validator: *validator.New(),
+		Filters: data.Filters{
+			SortSafelist: []string{"-Updated_at", "Updated_at"},
+		},
+	}
+}
+
+func newMessagesForm() *messagesForm {
+	return &messagesForm{
+		Validator: *validator.New(),
+	}
+}
+
+func newRevisionDiffForm() *revisionDiffForm {
+	return &revisionDiffForm{
+		Validator: *validator.New(),
+	}
+}
+
+func newTrashForm() *trashForm {
+	return &trashForm{
+		Validator: *validator.New(),
+		Filters: data.Filters{
+			SortSafelist: []string{"-Deleted_at", "Deleted_at"},
+		},
+	}
+}
+
+func newSearchForm() *searchForm {
+	return &searchForm{
+		Validator: *validator.New(),
+		Filters: data.Filters{
+			SortSafelist: []string{"-Score", "Score", "-Created_at", "Created_at"},
 		},
 	}
 }
@@ -138,6 +238,16 @@ func (app *application) readIDParam(r *http.Request) (int, error) {
 	return int(id), nil
 }
 
+func (app *application) readVersionParam(r *http.Request) (int, error) {
+
+	version, err := strconv.ParseInt(flow.Param(r.Context(), "version"), 10, 64)
+	if err != nil || version < 1 {
+		return 0, errors.New("invalid version parameter")
+	}
+
+	return int(version), nil
+}
+
 func (app *application) writeJSON(w http.ResponseWriter, status int, data envelope, headers http.Header) error {
 
 	js, err := json.MarshalIndent(data, "", "\t")
@@ -213,6 +323,51 @@ func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any
 	return nil
 }
 
+// readFile returns the content and the name of the file uploaded in the field of the multipart form,
+// which must not be larger than maxSize bytes
+func (app *application) readFile(w http.ResponseWriter, r *http.Request, field string, maxSize int64) ([]byte, string, error) {
+
+	// leaving some room for the rest of the multipart form
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize+1_048_576)
+
+	err := r.ParseMultipartForm(1_048_576)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+
+		switch {
+		case errors.As(err, &maxBytesError):
+			return nil, "", fmt.Errorf("the file must not be larger than %d bytes", maxSize)
+		default:
+			return nil, "", errors.New("body must be a valid multipart form")
+		}
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile(field)
+	if err != nil {
+		switch {
+		case errors.Is(err, http.ErrMissingFile):
+			return nil, "", fmt.Errorf("the %s field must contain a file", field)
+		default:
+			return nil, "", err
+		}
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(io.LimitReader(file, maxSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if int64(len(content)) > maxSize {
+		return nil, "", fmt.Errorf("the file must not be larger than %d bytes", maxSize)
+	}
+	if len(content) == 0 {
+		return nil, "", errors.New("the file must not be empty")
+	}
+
+	return content, header.Filename, nil
+}
+
 func (app *application) readString(qs url.Values, key string, defaultValue string) string {
 	s := qs.Get(key)
 
@@ -3,12 +3,16 @@ package main
 import (
 	"ForumAPI/internal/data"
 	"ForumAPI/internal/mailer"
+	"ForumAPI/internal/oidc"
+	"ForumAPI/internal/storage"
+	"ForumAPI/internal/stream"
 	"context"
 	"database/sql"
 	"expvar"
 	"flag"
 	"fmt"
 	"log/slog"
+	"net/netip"
 	"os"
 	"runtime"
 	"strconv"
@@ -39,6 +43,17 @@ type config struct {
 		burst   int
 		enabled bool
 	}
+	login struct {
+		window         time.Duration
+		backoff        time.Duration
+		maxBackoff     time.Duration
+		freeFailures   int
+		maxFailures    int
+		ipFreeFailures int
+		ipMaxFailures  int
+		lockout        time.Duration
+		trustedProxies []netip.Prefix
+	}
 	smtp struct {
 		host     string
 		port     int64
@@ -49,6 +64,45 @@ type config struct {
 	cors struct {
 		trustedOrigins []string
 	}
+	oidc struct {
+		providers []string
+		github    struct {
+			clientID     string
+			clientSecret string
+			url          string
+			apiURL       string
+		}
+	}
+	stream struct {
+		heartbeat  time.Duration
+		bufferSize int
+	}
+	cursor struct {
+		secret string
+	}
+	unsubscribe struct {
+		secret string
+	}
+	trash struct {
+		retention time.Duration
+	}
+	storage struct {
+		backend  string
+		dir      string
+		filesURL string
+		s3       struct {
+			endpoint  string
+			accessKey string
+			secretKey string
+			bucket    string
+			region    string
+			useSSL    bool
+		}
+	}
+	upload struct {
+		maxSize int64
+		quota   int64
+	}
 	pem struct {
 		privateKey []byte
 		publicKey  []byte
@@ -57,12 +111,15 @@ type config struct {
 }
 
 type application struct {
-	config      config
-	logger      *slog.Logger
-	models      data.Models
-	formDecoder *form.Decoder
-	mailer      mailer.Mailer
-	wg          sync.WaitGroup
+	config            config
+	logger            *slog.Logger
+	models            data.Models
+	formDecoder       *form.Decoder
+	mailer            mailer.Mailer
+	hub               *stream.Hub
+	storage           storage.Storage
+	identityProviders []oidc.Provider
+	wg                sync.WaitGroup
 }
 
 func main() {
@@ -82,6 +139,35 @@ func main() {
 	flag.IntVar(&cfg.limiter.burst, "limiter-burst", 100, "Rate limiter maximum burst")
 	flag.BoolVar(&cfg.limiter.enabled, "limiter-enabled", true, "Enable rate limiter")
 
+	flag.DurationVar(&cfg.login.window, "login-failures-window", time.Hour, "Time after which the failed logins are forgotten")
+	flag.DurationVar(&cfg.login.backoff, "login-backoff", time.Second, "Delay imposed after the first failed login beyond the free ones (doubling with each failure)")
+	flag.DurationVar(&cfg.login.maxBackoff, "login-backoff-max", 5*time.Minute, "Maximum delay imposed between two failed logins")
+	flag.IntVar(&cfg.login.freeFailures, "login-free-failures", 3, "Failed logins of an account allowed without delay")
+	flag.IntVar(&cfg.login.maxFailures, "login-max-failures", 10, "Failed logins after which an account is locked")
+	flag.IntVar(&cfg.login.ipFreeFailures, "login-ip-free-failures", 20, "Failed logins from an IP address allowed without delay")
+	flag.IntVar(&cfg.login.ipMaxFailures, "login-ip-max-failures", 100, "Failed logins after which an IP address is locked")
+	flag.DurationVar(&cfg.login.lockout, "login-lockout", 30*time.Minute, "Duration of the lockout of an account or IP address")
+
+	cfg.login.trustedProxies = []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8"), netip.MustParsePrefix("::1/128")}
+	flag.Func("login-trusted-proxies", "Addresses or CIDR ranges allowed to forward the IP address of the users logging in (space separated, default loopback)", func(val string) error {
+		cfg.login.trustedProxies = nil
+		for _, field := range strings.Fields(val) {
+			if !strings.Contains(field, "/") {
+				addr, err := netip.ParseAddr(field)
+				if err != nil {
+					return err
+				}
+				field = netip.PrefixFrom(addr, addr.BitLen()).String()
+			}
+			prefix, err := netip.ParsePrefix(field)
+			if err != nil {
+				return err
+			}
+			cfg.login.trustedProxies = append(cfg.login.trustedProxies, prefix)
+		}
+		return nil
+	})
+
 	flag.StringVar(&cfg.smtp.host, "smtp-host", "", "SMTP host")
 	flag.Int64Var(&cfg.smtp.port, "smtp-port", 2525, "SMTP port")
 	flag.StringVar(&cfg.smtp.username, "smtp-username", "", "SMTP username")
@@ -93,6 +179,37 @@ func main() {
 		return nil
 	})
 
+	flag.Func("oidc-provider", "OpenID Connect provider as key,Name,issuer,client_id,client_secret (repeatable)", func(val string) error {
+		cfg.oidc.providers = append(cfg.oidc.providers, val)
+		return nil
+	})
+	flag.StringVar(&cfg.oidc.github.clientID, "github-client-id", "", "GitHub OAuth app client id (GitHub login disabled if empty)")
+	flag.StringVar(&cfg.oidc.github.clientSecret, "github-client-secret", "", "GitHub OAuth app client secret")
+	flag.StringVar(&cfg.oidc.github.url, "github-url", oidc.GitHubURL, "GitHub URL (authorization and token endpoints)")
+	flag.StringVar(&cfg.oidc.github.apiURL, "github-api-url", oidc.GitHubAPIURL, "GitHub API URL")
+
+	flag.DurationVar(&cfg.stream.heartbeat, "stream-heartbeat", 15*time.Second, "Server-sent events heartbeat interval")
+	flag.IntVar(&cfg.stream.bufferSize, "stream-buffer", 32, "Server-sent events buffered per subscriber before it is considered too slow")
+
+	flag.StringVar(&cfg.cursor.secret, "cursor-secret", "", "Secret signing the pagination cursors (random at each start if empty)")
+
+	flag.StringVar(&cfg.unsubscribe.secret, "unsubscribe-secret", "", "Secret signing the unsubscribe links sent by email (required outside development, random at each start if empty)")
+
+	flag.DurationVar(&cfg.trash.retention, "trash-retention", 30*24*time.Hour, "Time the deleted content stays in the trash before being purged")
+
+	flag.StringVar(&cfg.storage.backend, "storage", "local", "Storage of the uploaded files (local|s3)")
+	flag.StringVar(&cfg.storage.dir, "storage-dir", "./uploads", "Directory of the uploaded files with the local storage")
+	flag.StringVar(&cfg.storage.filesURL, "storage-files-url", "http://localhost:4000/v1/files", "Public URL under which the uploaded files are served")
+	flag.StringVar(&cfg.storage.s3.endpoint, "s3-endpoint", "localhost:9000", "S3 compatible storage endpoint (host:port)")
+	flag.StringVar(&cfg.storage.s3.accessKey, "s3-access-key", "", "S3 access key")
+	flag.StringVar(&cfg.storage.s3.secretKey, "s3-secret-key", "", "S3 secret key")
+	flag.StringVar(&cfg.storage.s3.bucket, "s3-bucket", "threadive", "S3 bucket of the uploaded files")
+	flag.StringVar(&cfg.storage.s3.region, "s3-region", "", "S3 region")
+	flag.BoolVar(&cfg.storage.s3.useSSL, "s3-ssl", false, "Connect to the S3 storage with TLS")
+
+	flag.Int64Var(&cfg.upload.maxSize, "upload-max-size", 5<<20, "Maximum size of an uploaded file (bytes)")
+	flag.Int64Var(&cfg.upload.quota, "upload-quota", 50<<20, "Maximum size of the attachments uploaded by a user (bytes)")
+
 	frequency := flag.Duration("frequency", time.Hour*2, "expired tokens and unactivated users cleaning frequency")
 
 	displayVersion := flag.Bool("version", false, "Display version and exit")
@@ -120,6 +237,20 @@ func main() {
 		}
 	}
 
+	// setting the secret signing the pagination cursors
+	if cfg.cursor.secret != "" {
+		data.SetCursorKey(cfg.cursor.secret)
+	}
+
+	// setting the secret signing the unsubscribe links
+	// (a random one would break the links of the emails already sent at each restart)
+	if cfg.unsubscribe.secret != "" {
+		data.SetUnsubscribeKey(cfg.unsubscribe.secret)
+	} else if cfg.env != "development" {
+		fmt.Println("The unsubscribe secret is required outside development")
+		os.Exit(1)
+	}
+
 	// creating the logger with level corresponding to the environment (development|staging|production)
 	var logger *slog.Logger
 	if cfg.env == "development" {
@@ -128,6 +259,24 @@ func main() {
 		logger = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}))
 	}
 
+	// setting the public URL of the uploaded files
+	data.SetFilesURL(cfg.storage.filesURL)
+
+	// opening the storage of the uploaded files
+	fileStorage, err := openStorage(cfg)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+	logger.Info("file storage opened", "storage", cfg.storage.backend)
+
+	// setting the identity providers the users may log in with
+	identityProviders, err := openIdentityProviders(cfg)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
 	// opening the database connection pool
 	db, err := openDB(cfg)
 	if err != nil {
@@ -150,19 +299,34 @@ func main() {
 
 	// creating the application
 	app := &application{
-		config:      cfg,
-		logger:      logger,
-		models:      data.NewModels(db),
-		formDecoder: form.NewDecoder(),
-		mailer:      mailer.New(cfg.smtp.host, int(cfg.smtp.port), cfg.smtp.username, cfg.smtp.password, cfg.smtp.sender),
+		config:            cfg,
+		logger:            logger,
+		models:            data.NewModels(db),
+		formDecoder:       form.NewDecoder(),
+		mailer:            mailer.New(cfg.smtp.host, int(cfg.smtp.port), cfg.smtp.username, cfg.smtp.password, cfg.smtp.sender),
+		hub:               stream.NewHub(cfg.stream.bufferSize),
+		storage:           fileStorage,
+		identityProviders: identityProviders,
 	}
 
-	// Clean expired tokens every N duration with no timeout
+	// Clean expired tokens (along with the empty sessions and the old failed logins) every N duration with no timeout
 	go app.cleanExpiredTokens(*frequency, time.Hour*0)
 
 	// Clean expired unactivated users every N duration with 1 hour timeout
 	go app.cleanExpiredUnactivatedUsers(*frequency, time.Hour)
 
+	// Clear the expired suspensions (already ignored by IsBlocked) every N duration, starting right away
+	go app.liftExpiredSuspensions(*frequency)
+
+	// Purge the content kept in the trash longer than the retention every N duration with no timeout
+	go app.purgeTrash(*frequency, time.Hour*0)
+
+	// Remove the files of the attachments which do not belong to a post anymore every N duration with 5 minutes timeout
+	go app.cleanOrphanAttachments(*frequency, time.Minute*5)
+
+	// Send the daily digests of the subscribed threads every hour with 10 minutes timeout
+	go app.sendDigests(time.Hour, time.Minute*10)
+
 	// Retrieving or generating RSA keys
 	err = app.getPEM()
 	if err != nil {
@@ -201,6 +365,19 @@ func openDB(cfg config) (*sql.DB, error) {
 	return db, nil
 }
 
+func openStorage(cfg config) (storage.Storage, error) {
+
+	switch cfg.storage.backend {
+	case "local":
+		return storage.NewLocal(cfg.storage.dir)
+	case "s3":
+		s3 := cfg.storage.s3
+		return storage.NewS3(s3.endpoint, s3.accessKey, s3.secretKey, s3.bucket, s3.region, s3.useSSL)
+	default:
+		return nil, fmt.Errorf("unknown storage %s", cfg.storage.backend)
+	}
+}
+
 func (cfg *config) loadEnv() error {
 
 	err := godotenv.Load()
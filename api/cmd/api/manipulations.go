@@ -1,11 +1,38 @@
 package main
 
 import (
+	"ForumAPI/internal/data"
+	"ForumAPI/internal/validator"
 	"database/sql"
 	"errors"
+	"fmt"
 	"net/http"
+	"slices"
+	"time"
 )
 
+type recommendationsForm struct {
+	ID int `form:"-"`
+	data.Filters
+	validator.Validator `form:"-"`
+}
+
+type searchForm struct {
+	Search               string   `form:"q"`
+	Types                []string `form:"type"`
+	CategoryID           int      `form:"category"`
+	IncludeSubcategories bool     `form:"include_subcategories"`
+	TagID                int      `form:"tag"`
+	TagIDs               []int    `form:"tag_id[]"`
+	TagMode              string   `form:"tag_mode"`
+	Status               string   `form:"status"`
+	AuthorID             int      `form:"author"`
+	CreatedAfter         string   `form:"created_after"`
+	CreatedBefore        string   `form:"created_before"`
+	data.Filters
+	validator.Validator `form:"-"`
+}
+
 func (app *application) getPopularHandler(w http.ResponseWriter, r *http.Request) {
 
 	// get popular tags
@@ -39,16 +66,151 @@ func (app *application) getPopularHandler(w http.ResponseWriter, r *http.Request
 	}
 }
 
-//func (app *application) getRecommendations(w http.ResponseWriter, r *http.Request) {
-//	err := app.writeJSON(w, http.StatusOK, envelope{"recommendations": "get_recommendations"}, nil)
-//	if err != nil {
-//		app.serverErrorResponse(w, r, err)
-//	}
-//}
-//
-//func (app *application) searchHandler(w http.ResponseWriter, r *http.Request) {
-//	err := app.writeJSON(w, http.StatusOK, envelope{"search": "results"}, nil)
-//	if err != nil {
-//		app.serverErrorResponse(w, r, err)
-//	}
-//}
+func (app *application) getRecommendationsHandler(w http.ResponseWriter, r *http.Request) {
+
+	form := newRecommendationsForm()
+
+	err := app.decodeForm(r, &form)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	form.ID, err = app.readIDParam(r)
+	if err != nil {
+		form.AddError("id", "must be an integer")
+	}
+
+	if form.Page == 0 {
+		form.Page = 1
+	}
+	if form.PageSize == 0 {
+		form.PageSize = 10
+	}
+	if form.Sort == "" {
+		form.Sort = form.SortSafelist[0]
+	}
+
+	form.Check(form.ID > 0, "id", "must contain a valid id")
+	data.ValidateFilters(&form.Validator, form.Filters)
+
+	if !form.Valid() {
+		err = app.writeJSON(w, http.StatusBadRequest, envelope{"errors": form.Errors}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// recommendations are built from the user's activity: only the user and the staff may see them
+	user := app.contextGetUser(r)
+	if !user.HasPermission(form.ID) {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	recommendations, metadata, err := app.models.Recommendations.Get(form.ID, form.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"_metadata": metadata, "recommendations": recommendations}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) searchHandler(w http.ResponseWriter, r *http.Request) {
+
+	form := newSearchForm()
+
+	err := app.decodeForm(r, &form)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if form.Page == 0 {
+		form.Page = 1
+	}
+	if form.PageSize == 0 {
+		form.PageSize = 20
+	}
+	if form.Sort == "" {
+		form.Sort = form.SortSafelist[0]
+	}
+	if form.TagMode == "" {
+		form.TagMode = data.TagMatch.Any
+	}
+
+	// tag is kept for the single tag filter, tag_id[] allows several tags
+	if form.TagID > 0 && !slices.Contains(form.TagIDs, form.TagID) {
+		form.TagIDs = append(form.TagIDs, form.TagID)
+	}
+
+	form.StringCheck(form.Search, 2, 100, true, "q")
+	form.Check(validator.Unique(form.Types), "type", "duplicate values")
+	for _, value := range form.Types {
+		form.Check(validator.PermittedValue(value, data.PermittedSearchTypes...), "type", fmt.Sprintf("incorrect value %s", value))
+	}
+	form.Check(form.CategoryID >= 0, "category", "must be a valid id")
+	form.Check(!form.IncludeSubcategories || form.CategoryID > 0, "include_subcategories", "requires category")
+	form.Check(form.TagID >= 0, "tag", "must be a valid id")
+	form.Check(len(form.TagIDs) <= 20, "tag_id[]", "must not contain more than 20 tags")
+	form.Check(validator.Unique(form.TagIDs), "tag_id[]", "duplicate values")
+	for _, id := range form.TagIDs {
+		form.Check(id > 0, "tag_id[]", fmt.Sprintf("incorrect value %d", id))
+	}
+	form.Check(validator.PermittedValue(form.TagMode, data.TagMatch.Any, data.TagMatch.All), "tag_mode", "invalid tag_mode value")
+	if form.Status != "" {
+		form.Check(validator.PermittedValue(form.Status, data.ThreadStatus.Active, data.ThreadStatus.Archived), "status", "invalid status value")
+	}
+	form.Check(form.AuthorID >= 0, "author", "must be a valid id")
+
+	data.ValidateFilters(&form.Validator, form.Filters)
+
+	searchFilters := data.SearchFilters{
+		Types:                form.Types,
+		CategoryID:           form.CategoryID,
+		IncludeSubcategories: form.IncludeSubcategories,
+		TagIDs:               form.TagIDs,
+		AllTags:              form.TagMode == data.TagMatch.All,
+		Status:               form.Status,
+		AuthorID:             form.AuthorID,
+	}
+
+	if form.CreatedAfter != "" {
+		searchFilters.CreatedAfter, err = time.Parse("2006-01-02", form.CreatedAfter)
+		if err != nil {
+			form.AddError("created_after", "must be a valid date in the format YYYY-MM-DD")
+		}
+	}
+	if form.CreatedBefore != "" {
+		searchFilters.CreatedBefore, err = time.Parse("2006-01-02", form.CreatedBefore)
+		if err != nil {
+			form.AddError("created_before", "must be a valid date in the format YYYY-MM-DD")
+		}
+	}
+	if !searchFilters.CreatedAfter.IsZero() && !searchFilters.CreatedBefore.IsZero() {
+		form.Check(searchFilters.CreatedAfter.Before(searchFilters.CreatedBefore), "created_before", "must be after created_after")
+	}
+
+	if !form.Valid() {
+		err = app.writeJSON(w, http.StatusBadRequest, envelope{"errors": form.Errors}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	hits, metadata, err := app.models.Search.Search(form.Search, searchFilters, form.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"_metadata": metadata, "results": hits}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"ForumAPI/internal/data"
+	"ForumAPI/internal/stream"
+	"ForumAPI/internal/validator"
+	"errors"
+	"net/http"
+)
+
+type conversationsForm struct {
+	data.Filters
+	validator.Validator `form:"-"`
+}
+
+type messagesForm struct {
+	Before              int `form:"before"`
+	Limit               int `form:"limit"`
+	validator.Validator `form:"-"`
+}
+
+// checkRecipient checks that the user can send messages to the user otherID:
+// they must be accepted friends and the recipient must not be blocked
+func (app *application) checkRecipient(user *data.User, otherID int, v *validator.Validator) (*data.User, error) {
+
+	other, err := app.models.Users.GetByID(otherID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("user_id", "must refer to an existing user")
+			return nil, nil
+		default:
+			return nil, err
+		}
+	}
+
+	if other.IsBlocked() {
+		v.AddError("user_id", "this user cannot receive messages")
+		return nil, nil
+	}
+
+	friends, err := app.models.Users.AreFriends(user.ID, other.ID)
+	if err != nil {
+		return nil, err
+	}
+	v.Check(friends, "user_id", "must be one of your friends")
+
+	return other, nil
+}
+
+// getConversation fetches the conversation from the id parameter and checks that the user takes part in it
+func (app *application) getConversation(w http.ResponseWriter, r *http.Request) (*data.Conversation, bool) {
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return nil, false
+	}
+
+	user := app.contextGetUser(r)
+
+	conversation, err := app.models.Messages.GetConversation(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	// other users' conversations don't exist for the user
+	if !conversation.HasParticipant(user.ID) {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	return conversation, true
+}
+
+func (app *application) getConversationsHandler(w http.ResponseWriter, r *http.Request) {
+
+	form := newConversationsForm()
+
+	err := app.decodeForm(r, &form)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if form.Page == 0 {
+		form.Page = 1
+	}
+	if form.PageSize == 0 {
+		form.PageSize = 20
+	}
+	if form.Sort == "" {
+		form.Sort = form.SortSafelist[0]
+	}
+
+	data.ValidateFilters(&form.Validator, form.Filters)
+
+	if !form.Valid() {
+		err = app.writeJSON(w, http.StatusBadRequest, envelope{"errors": form.Errors}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	conversations, metadata, err := app.models.Messages.GetConversations(user.ID, form.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"_metadata": metadata, "conversations": conversations}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createConversationHandler(w http.ResponseWriter, r *http.Request) {
+
+	var input struct {
+		UserID *int `json:"user_id"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	v := validator.New()
+
+	if input.UserID == nil {
+		v.AddError("user_id", "must be provided")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	v.Check(*input.UserID > 0, "user_id", "must be greater than zero")
+	v.Check(*input.UserID != user.ID, "user_id", "must not be yourself")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	other, err := app.checkRecipient(user, *input.UserID, v)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// the conversation between two users is unique
+	conversation, err := app.models.Messages.GetConversationBetween(user.ID, other.ID)
+	if err == nil {
+		err = app.writeJSON(w, http.StatusOK, envelope{"conversation": conversation}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if !errors.Is(err, data.ErrRecordNotFound) {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	conversation = &data.Conversation{
+		UserIDs: [2]int{user.ID, other.ID},
+	}
+	conversation.With.ID = other.ID
+	conversation.With.Name = other.Name
+	conversation.With.Avatar = other.Avatar
+
+	err = app.models.Messages.InsertConversation(conversation)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateEntry):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"conversation": conversation}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) getMessagesHandler(w http.ResponseWriter, r *http.Request) {
+
+	conversation, ok := app.getConversation(w, r)
+	if !ok {
+		return
+	}
+
+	form := newMessagesForm()
+
+	err := app.decodeForm(r, &form)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if form.Limit == 0 {
+		form.Limit = 30
+	}
+
+	form.Check(form.Before >= 0, "before", "must be a valid message id")
+	form.Check(form.Limit > 0, "limit", "must be greater than zero")
+	form.Check(form.Limit <= 100, "limit", "must be a maximum of 100")
+
+	if !form.Valid() {
+		err = app.writeJSON(w, http.StatusBadRequest, envelope{"errors": form.Errors}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	messages, cursor, err := app.models.Messages.Get(conversation.ID, form.Before, form.Limit)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// opening the conversation reads its latest messages
+	if form.Before == 0 {
+		err = app.models.Messages.MarkAsRead(conversation.ID, user.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	response := envelope{
+		"conversation": conversation,
+		"messages":     messages,
+	}
+	if cursor != 0 {
+		response["next_cursor"] = cursor
+	}
+
+	err = app.writeJSON(w, http.StatusOK, response, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createMessageHandler(w http.ResponseWriter, r *http.Request) {
+
+	conversation, ok := app.getConversation(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Content *string `json:"content"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if input.Content == nil {
+		v.AddError("content", "must be provided")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	v.StringCheck(*input.Content, 1, 1_020, true, "content")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	// the users must still be friends and the recipient not blocked
+	_, err = app.checkRecipient(user, conversation.Recipient(user.ID), v)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	message := &data.Message{
+		ConversationID: conversation.ID,
+		AuthorID:       user.ID,
+		Content:        *input.Content,
+	}
+
+	err = app.models.Messages.Insert(message)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.hub.Publish(stream.UserChannel(conversation.Recipient(user.ID)), stream.EventType.Message, message)
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"message": message}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
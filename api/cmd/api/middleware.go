@@ -214,6 +214,8 @@ func (app *application) authenticateClient(next http.Handler) http.Handler {
 			r.Header.Del("Authorization")
 		}
 
+		r = app.contextSetClient(r, user)
+
 		next.ServeHTTP(w, r)
 	})
 }
@@ -255,6 +257,19 @@ func (app *application) authenticateUser(next http.Handler) http.Handler {
 			return
 		}
 
+		if user.IsBlocked() {
+			app.suspendedAccountResponse(w, r, user)
+			return
+		}
+
+		// keeping track of the last activity of the user's device
+		if user.SessionID != 0 {
+			err = app.models.Sessions.Touch(user.SessionID)
+			if err != nil {
+				app.logger.Error(err.Error())
+			}
+		}
+
 		r = app.contextSetUser(r, user)
 
 		next.ServeHTTP(w, r)
@@ -291,6 +306,38 @@ func (app *application) requireActivatedUser(next http.Handler) http.Handler {
 	return app.requireAuthenticatedUser(fn)
 }
 
+func (app *application) requireModerator(next http.Handler) http.Handler {
+	fn := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		user := app.contextGetUser(r)
+
+		if !user.IsModerator() {
+			app.notPermittedResponse(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+
+	return app.requireActivatedUser(fn)
+}
+
+func (app *application) requireAdmin(next http.Handler) http.Handler {
+	fn := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		user := app.contextGetUser(r)
+
+		if user.Role != data.UserRole.Admin {
+			app.notPermittedResponse(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+
+	return app.requireActivatedUser(fn)
+}
+
 func (app *application) guardUserHandlers(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
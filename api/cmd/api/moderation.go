@@ -0,0 +1,331 @@
+package main
+
+import (
+	"ForumAPI/internal/data"
+	"ForumAPI/internal/validator"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+type moderationQueueForm struct {
+	Status string `form:"status"`
+	Type   string `form:"type"`
+	data.Filters
+	validator.Validator `form:"-"`
+}
+
+func (app *application) reportPostHandler(w http.ResponseWriter, r *http.Request) {
+	app.createReport(w, r, data.ReportTarget.Post)
+}
+
+func (app *application) reportThreadHandler(w http.ResponseWriter, r *http.Request) {
+	app.createReport(w, r, data.ReportTarget.Thread)
+}
+
+func (app *application) reportUserHandler(w http.ResponseWriter, r *http.Request) {
+	app.createReport(w, r, data.ReportTarget.User)
+}
+
+func (app *application) createReport(w http.ResponseWriter, r *http.Request, targetType string) {
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var input struct {
+		Reason *string `json:"reason"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if input.Reason == nil {
+		v.AddError("reason", "must be provided")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// checking that the target exists
+	authorID, err := app.getReportTargetAuthor(targetType, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	report := &data.Report{
+		TargetType: targetType,
+		TargetID:   id,
+		Reason:     *input.Reason,
+	}
+	report.Reporter.ID = user.ID
+	report.Reporter.Name = user.Name
+
+	v.Check(authorID != user.ID, "target", "cannot report yourself or your own content")
+	if report.Validate(v); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Reports.Insert(report)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateReport):
+			v.AddError("target", "you already reported it, a moderator will review it soon")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.audit(r, data.AuditAction.Create, data.AuditEntity.Report, report.ID, nil, report)
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"report": report}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getReportTargetAuthor returns the id of the user responsible for the target of a report
+// (the author of a post or a thread, or the reported user)
+func (app *application) getReportTargetAuthor(targetType string, id int) (int, error) {
+
+	switch targetType {
+	case data.ReportTarget.Post:
+		post, err := app.models.Posts.GetByID(id)
+		if err != nil {
+			return 0, err
+		}
+		return post.Author.ID, nil
+	case data.ReportTarget.Thread:
+		thread, err := app.models.Threads.GetByID(id)
+		if err != nil {
+			return 0, err
+		}
+		return thread.Author.ID, nil
+	case data.ReportTarget.User:
+		user, err := app.models.Users.GetByID(id)
+		if err != nil {
+			return 0, err
+		}
+		return user.ID, nil
+	default:
+		return 0, fmt.Errorf("unknown report target %s", targetType)
+	}
+}
+
+func (app *application) getModerationQueueHandler(w http.ResponseWriter, r *http.Request) {
+
+	form := newModerationQueueForm()
+
+	err := app.decodeForm(r, &form)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if form.Status == "" {
+		form.Status = data.ReportStatus.Pending
+	}
+	if form.Page == 0 {
+		form.Page = 1
+	}
+	if form.PageSize == 0 {
+		form.PageSize = 20
+	}
+	if form.Sort == "" {
+		form.Sort = form.SortSafelist[0]
+	}
+
+	form.Check(validator.PermittedValue(form.Status, data.PermittedReportStatuses...), "status", fmt.Sprintf("incorrect value %s", form.Status))
+	if form.Type != "" {
+		form.Check(validator.PermittedValue(form.Type, data.PermittedReportTargets...), "type", fmt.Sprintf("incorrect value %s", form.Type))
+	}
+
+	data.ValidateFilters(&form.Validator, form.Filters)
+
+	if !form.Valid() {
+		err = app.writeJSON(w, http.StatusBadRequest, envelope{"errors": form.Errors}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	reports, metadata, err := app.models.Reports.GetQueue(form.Status, form.Type, form.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"_metadata": metadata, "reports": reports}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) getSingleReportHandler(w http.ResponseWriter, r *http.Request) {
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	report, err := app.models.Reports.GetByID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.Reports.GetActions(report)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"report": report}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) resolveReportHandler(w http.ResponseWriter, r *http.Request) {
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	report, err := app.models.Reports.GetByID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Action *string `json:"action"`
+		Note   *string `json:"note"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if input.Action == nil {
+		v.AddError("action", "must be provided")
+	} else {
+		v.Check(validator.PermittedValue(*input.Action, data.PermittedModerationActions(report.TargetType)...), "action", fmt.Sprintf("incorrect value for a %s report", report.TargetType))
+	}
+	if input.Note == nil {
+		v.AddError("note", "must be provided")
+	} else {
+		v.StringCheck(*input.Note, 2, 500, true, "note")
+	}
+	v.Check(report.Status == data.ReportStatus.Pending, "report", "already settled")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// the target may have been deleted since it was reported
+	authorID, err := app.getReportTargetAuthor(report.TargetType, report.TargetID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("target", "doesn't exist anymore")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	moderator := app.contextGetUser(r)
+
+	// only an administrator may block a member of the moderation
+	if *input.Action == data.ModerationActionType.BlockUser {
+		author, err := app.models.Users.GetByID(authorID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+		if author.IsModerator() && moderator.Role != data.UserRole.Admin {
+			app.notPermittedResponse(w, r)
+			return
+		}
+	}
+
+	action := &data.ModerationAction{
+		Action: *input.Action,
+		Note:   *input.Note,
+	}
+	action.Moderator.ID = moderator.ID
+	action.Moderator.Name = moderator.Name
+
+	before := app.auditSnapshot(report)
+
+	err = app.models.Reports.Resolve(report, action, authorID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	report.Actions = append(report.Actions, *action)
+
+	app.audit(r, data.AuditAction.Resolve, data.AuditEntity.Report, report.ID, before, report)
+
+	// logging the blocked user out of every device
+	if action.Action == data.ModerationActionType.BlockUser {
+		err = app.models.Tokens.DeleteAllForUser("*", authorID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"report": report}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
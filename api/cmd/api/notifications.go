@@ -0,0 +1,255 @@
+package main
+
+import (
+	"ForumAPI/internal/data"
+	"ForumAPI/internal/stream"
+	"ForumAPI/internal/validator"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// maxMentionNotifications is how many of the users mentioned in a post are notified, the others being ignored
+// (so that a post can't send an email to every user)
+const maxMentionNotifications = 10
+
+type notificationsForm struct {
+	Unread bool `form:"unread"`
+	data.Filters
+	validator.Validator `form:"-"`
+}
+
+// notify creates the notification in the background and pushes it to the recipient's stream,
+// unless the user would be notified of their own action
+func (app *application) notify(notification *data.Notification) {
+
+	if notification.UserID == notification.Actor.ID {
+		return
+	}
+
+	app.background(func() {
+		err := app.models.Notifications.Insert(notification)
+		if err != nil {
+			app.logger.Error(err.Error())
+			return
+		}
+
+		notification.CreatedAt = time.Now()
+		app.hub.Publish(stream.UserChannel(notification.UserID), stream.EventType.Notification, notification)
+	})
+}
+
+// notifyMentions notifies the first users mentioned in the post, except the ones in excluded, and sends them an email in the background
+func (app *application) notifyMentions(post *data.Post, actor *data.User, excluded ...int) {
+
+	ids := post.MentionedUserIDs()
+	if len(ids) > maxMentionNotifications {
+		ids = ids[:maxMentionNotifications]
+	}
+
+	for _, id := range ids {
+		if id == actor.ID || slices.Contains(excluded, id) {
+			continue
+		}
+
+		notification := &data.Notification{
+			UserID: id,
+			Type:   data.NotificationType.Mention,
+			PostID: post.ID,
+		}
+		notification.Actor.ID = actor.ID
+		notification.Actor.Name = actor.Name
+		notification.Thread.ID = post.Thread.ID
+		notification.Thread.Title = post.Thread.Title
+
+		app.notify(notification)
+
+		app.background(func() {
+			user, err := app.models.Users.GetByID(id)
+			if err != nil {
+				app.logger.Error(err.Error())
+				return
+			}
+
+			mailData := map[string]any{
+				"username":  user.Name,
+				"actorName": actor.Name,
+				"threadID":  post.Thread.ID,
+				"postID":    post.ID,
+			}
+
+			err = app.mailer.Send(user.Email, "post_mention.tmpl", mailData)
+			if err != nil {
+				app.logger.Error(err.Error())
+			}
+		})
+	}
+}
+
+// notifyReaction notifies the author of the post of the user's reaction, in the background
+// (the reaction being stored, failing to load the post doesn't fail the request)
+func (app *application) notifyReaction(postID int, actor *data.User) {
+
+	app.background(func() {
+		post, err := app.models.Posts.GetByID(postID)
+		if err != nil {
+			app.logger.Error(err.Error())
+			return
+		}
+
+		notification := &data.Notification{
+			UserID: post.Author.ID,
+			Type:   data.NotificationType.Reaction,
+			PostID: post.ID,
+		}
+		notification.Actor.ID = actor.ID
+		notification.Actor.Name = actor.Name
+		notification.Thread.ID = post.Thread.ID
+		notification.Thread.Title = post.Thread.Title
+
+		app.notify(notification)
+	})
+}
+
+// notifySubscribers notifies in the background every user subscribed to the notification's thread,
+// and sends an email to the ones who chose to receive them immediately
+func (app *application) notifySubscribers(notification *data.Notification, excluded ...int) {
+
+	app.background(func() {
+		notifications, err := app.models.Notifications.InsertForSubscribers(notification, excluded...)
+		if err != nil {
+			app.logger.Error(err.Error())
+			return
+		}
+
+		for _, created := range notifications {
+			app.hub.Publish(stream.UserChannel(created.UserID), stream.EventType.Notification, created)
+		}
+
+		subscribers, err := app.models.Subscriptions.GetImmediateSubscribers(notification.Thread.ID, append(excluded, notification.Actor.ID)...)
+		if err != nil {
+			app.logger.Error(err.Error())
+			return
+		}
+
+		if len(subscribers) > 0 && notification.Thread.Title == "" {
+			thread, err := app.models.Threads.GetByID(notification.Thread.ID)
+			if err != nil {
+				app.logger.Error(err.Error())
+				return
+			}
+			notification.Thread.Title = thread.Title
+		}
+
+		for _, subscriber := range subscribers {
+			mailData := map[string]any{
+				"username":         subscriber.Name,
+				"actorName":        notification.Actor.Name,
+				"threadID":         notification.Thread.ID,
+				"threadTitle":      notification.Thread.Title,
+				"postID":           notification.PostID,
+				"unsubscribeToken": data.UnsubscribeToken(subscriber.ID, notification.Thread.ID),
+			}
+
+			err = app.mailer.Send(subscriber.Email, "thread_post.tmpl", mailData)
+			if err != nil {
+				app.logger.Error(err.Error())
+			}
+		}
+	})
+}
+
+func (app *application) getNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+
+	form := newNotificationsForm()
+
+	err := app.decodeForm(r, &form)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if form.Page == 0 {
+		form.Page = 1
+	}
+	if form.PageSize == 0 {
+		form.PageSize = 20
+	}
+	if form.Sort == "" {
+		form.Sort = form.SortSafelist[0]
+	}
+
+	data.ValidateFilters(&form.Validator, form.Filters)
+
+	if !form.Valid() {
+		err = app.writeJSON(w, http.StatusBadRequest, envelope{"errors": form.Errors}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	notifications, metadata, err := app.models.Notifications.Get(user.ID, form.Unread, form.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	unread, err := app.models.Notifications.CountUnread(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"_metadata": metadata, "unread": unread, "notifications": notifications}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) readNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+
+	var input struct {
+		IDs []int `json:"ids"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	v.Check(validator.Unique(input.IDs), "ids", "duplicate values")
+	for _, id := range input.IDs {
+		v.Check(id > 0, "ids", "must only contain ids greater than zero")
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	// no ids means every notification of the user
+	updated, err := app.models.Notifications.MarkAsRead(user.ID, input.IDs)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	unread, err := app.models.Notifications.CountUnread(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"updated": updated, "unread": unread}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,439 @@
+package main
+
+import (
+	"ForumAPI/internal/data"
+	"ForumAPI/internal/oidc"
+	"ForumAPI/internal/validator"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"github.com/alexedwards/flow"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// oidcTimeout is how long the identity providers have to answer (discovery, token exchange and user info)
+	oidcTimeout = 10 * time.Second
+	// maxUsernameAttempts is the number of usernames tried (with a random suffix) for a new user
+	maxUsernameAttempts = 5
+	// maxIdentityNameLength leaves room for the random suffix within the 70 bytes of the usernames
+	maxIdentityNameLength = 64
+)
+
+var (
+	errUnverifiedIdentityEmail = errors.New("unverified identity email")
+	errUnconfirmedAccount      = errors.New("unconfirmed account")
+	errInvalidIdentityUser     = errors.New("invalid identity user")
+)
+
+// openIdentityProviders returns the configured OpenID Connect providers ("key,Name,issuer,client_id,client_secret") and GitHub
+func openIdentityProviders(cfg config) ([]oidc.Provider, error) {
+
+	var providers []oidc.Provider
+
+	for _, spec := range cfg.oidc.providers {
+		fields := strings.Split(spec, ",")
+		if len(fields) != 5 {
+			return nil, fmt.Errorf("invalid OpenID Connect provider %q (want key,Name,issuer,client_id,client_secret)", spec)
+		}
+		if fields[0] == oidc.GitHubKey {
+			return nil, fmt.Errorf("the OpenID Connect provider key %q is reserved", oidc.GitHubKey)
+		}
+		providers = append(providers, oidc.NewOpenID(fields[0], fields[1], fields[2], fields[3], fields[4]))
+	}
+
+	if cfg.oidc.github.clientID != "" {
+		github := cfg.oidc.github
+		providers = append(providers, oidc.NewGitHub(github.url, github.apiURL, github.clientID, github.clientSecret))
+	}
+
+	return providers, nil
+}
+
+func (app *application) readProviderParam(r *http.Request) (oidc.Provider, error) {
+	return oidc.Find(app.identityProviders, flow.Param(r.Context(), "provider"))
+}
+
+func (app *application) getIdentityProvidersHandler(w http.ResponseWriter, r *http.Request) {
+
+	providers := make([]envelope, len(app.identityProviders))
+	for i, provider := range app.identityProviders {
+		providers[i] = envelope{
+			"key":  provider.Key(),
+			"name": provider.Name(),
+		}
+	}
+
+	err := app.writeJSON(w, http.StatusOK, envelope{"providers": providers}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getAuthorizationURLHandler returns the URL of the provider the client redirects the user to.
+// The client generates the state, the nonce and the PKCE code verifier and keeps them until the callback.
+func (app *application) getAuthorizationURLHandler(w http.ResponseWriter, r *http.Request) {
+
+	provider, err := app.readProviderParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	qs := r.URL.Query()
+
+	redirectURI := qs.Get("redirect_uri")
+	state := qs.Get("state")
+	codeChallenge := qs.Get("code_challenge")
+	nonce := qs.Get("nonce")
+
+	v := validator.New()
+
+	redirect, err := url.Parse(redirectURI)
+	v.Check(err == nil && (redirect.Scheme == "http" || redirect.Scheme == "https") && redirect.Host != "", "redirect_uri", "must be an absolute URL")
+	v.StringCheck(state, 16, 255, true, "state")
+	v.StringCheck(codeChallenge, 43, 128, true, "code_challenge")
+	v.StringCheck(nonce, 16, 255, true, "nonce")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), oidcTimeout)
+	defer cancel()
+
+	authURL, err := provider.AuthCodeURL(ctx, redirectURI, state, codeChallenge, nonce)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"url": authURL}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// identityUser returns the user linked to the identity, linking it by verified email address or creating a new user otherwise
+// (the boolean telling whether the user was created). The users created by a provider are activated once it verified their address.
+func (app *application) identityUser(r *http.Request, identity *oidc.Identity, v *validator.Validator) (*data.User, bool, error) {
+
+	userID, err := app.models.Identities.GetUserID(identity.Provider, identity.Subject)
+	if err == nil {
+		user, err := app.models.Users.GetByID(userID)
+		if err != nil {
+			return nil, false, err
+		}
+		return user, false, app.activateIdentityUser(r, identity, user)
+	}
+	if !errors.Is(err, data.ErrRecordNotFound) {
+		return nil, false, err
+	}
+
+	var created bool
+
+	user, err := app.models.Users.GetByEmail(identity.Email)
+	switch {
+	case err == nil:
+		// only a provider which verified the address proves the user owns the existing account
+		if !identity.EmailVerified {
+			return nil, false, errUnverifiedIdentityEmail
+		}
+
+		// an unconfirmed account may have been registered by someone else with this address (and their password),
+		// it must be activated with the email first
+		if user.IsToConfirm() {
+			return nil, false, errUnconfirmedAccount
+		}
+
+	case errors.Is(err, data.ErrRecordNotFound):
+		user, err = app.newIdentityUser(identity, v)
+		if err != nil {
+			return nil, false, err
+		}
+		created = true
+
+		app.audit(r, data.AuditAction.Create, data.AuditEntity.User, user.ID, nil, user)
+
+	default:
+		return nil, false, err
+	}
+
+	link := &data.Identity{
+		UserID:   user.ID,
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	}
+
+	err = app.models.Identities.Insert(link)
+	if err != nil {
+		return nil, false, err
+	}
+
+	app.audit(r, data.AuditAction.LinkIdentity, data.AuditEntity.User, user.ID, nil, link)
+
+	return user, created, nil
+}
+
+// activateIdentityUser activates the user created by the provider (without password) once it verified the email address
+func (app *application) activateIdentityUser(r *http.Request, identity *oidc.Identity, user *data.User) error {
+
+	if !user.IsToConfirm() || !identity.EmailVerified || !strings.EqualFold(user.Email, identity.Email) {
+		return nil
+	}
+
+	before := app.auditSnapshot(user)
+
+	err := app.models.Users.Activate(user)
+	if err != nil {
+		return err
+	}
+
+	err = app.models.Tokens.DeleteAllForUser(data.TokenScope.Activation, user.ID)
+	if err != nil {
+		return err
+	}
+
+	app.audit(r, data.AuditAction.Activate, data.AuditEntity.User, user.ID, before, user)
+
+	return nil
+}
+
+// identityUsername returns the provider's login or the email address' local part (truncated on a character boundary)
+func identityUsername(identity *oidc.Identity) string {
+
+	name := strings.TrimSpace(identity.Name)
+	if len(name) < 2 {
+		name, _, _ = strings.Cut(identity.Email, "@")
+	}
+	if len(name) < 2 {
+		name = "user"
+	}
+
+	if len(name) > maxIdentityNameLength {
+		// cutting at the last character starting within the limit
+		cut := 0
+		for i := range name {
+			if i > maxIdentityNameLength {
+				break
+			}
+			cut = i
+		}
+		name = name[:cut]
+	}
+
+	return name
+}
+
+// newIdentityUser creates a user without password (activated if the provider verified the email address),
+// named after the provider's login or the email address, with a random suffix if the name is taken
+func (app *application) newIdentityUser(identity *oidc.Identity, v *validator.Validator) (*data.User, error) {
+
+	name := identityUsername(identity)
+
+	user := &data.User{
+		Name:   name,
+		Email:  identity.Email,
+		Status: data.UserStatus.ToConfirm,
+	}
+	if identity.EmailVerified {
+		user.Status = data.UserStatus.Activated
+	}
+
+	user.NoLogin()
+
+	if user.Validate(v); !v.Valid() {
+		return nil, errInvalidIdentityUser
+	}
+
+	for attempt := 0; ; attempt++ {
+
+		err := app.models.Users.Insert(user)
+		if !errors.Is(err, data.ErrDuplicateUsername) || attempt == maxUsernameAttempts {
+			return user, err
+		}
+
+		suffix, err := rand.Int(rand.Reader, big.NewInt(10_000))
+		if err != nil {
+			return nil, err
+		}
+
+		user.Name = fmt.Sprintf("%s-%04d", name, suffix)
+	}
+}
+
+// createOIDCAuthenticationTokenHandler logs the user in with the authorization code returned by the identity provider
+// to the client's callback, along with the PKCE code verifier and the nonce of the authorization request
+func (app *application) createOIDCAuthenticationTokenHandler(w http.ResponseWriter, r *http.Request) {
+
+	var input struct {
+		Provider     string `json:"provider"`
+		Code         string `json:"code"`
+		CodeVerifier string `json:"code_verifier"`
+		RedirectURI  string `json:"redirect_uri"`
+		Nonce        string `json:"nonce"`
+		UserAgent    string `json:"user_agent"`
+		IP           string `json:"ip"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	v.StringCheck(input.Provider, 1, 50, true, "provider")
+	v.StringCheck(input.Code, 1, 2048, true, "code")
+	v.StringCheck(input.CodeVerifier, 43, 128, true, "code_verifier")
+	v.StringCheck(input.RedirectURI, 1, 2048, true, "redirect_uri")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	provider, err := oidc.Find(app.identityProviders, input.Provider)
+	if err != nil {
+		v.AddError("provider", "unknown identity provider")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), oidcTimeout)
+	defer cancel()
+
+	identity, err := provider.Exchange(ctx, input.Code, input.CodeVerifier, input.RedirectURI, input.Nonce)
+	if err != nil {
+		switch {
+		case errors.Is(err, oidc.ErrNoEmail):
+			v.AddError("email", "the identity provider must give an email address")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.logError(r, err)
+			app.invalidCredentialsResponse(w, r)
+		}
+		return
+	}
+
+	if data.ValidateEmail(v, identity.Email); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, created, err := app.identityUser(r, identity, v)
+	if err != nil {
+		switch {
+		case errors.Is(err, errInvalidIdentityUser):
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, errUnverifiedIdentityEmail):
+			v.AddError("email", "a user with this email address already exists, log in with your password")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, errUnconfirmedAccount):
+			v.AddError("email", "a user with this email address must be activated first with the link sent by email")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrDuplicateEmail), errors.Is(err, data.ErrDuplicateUsername), errors.Is(err, data.ErrDuplicateEntry):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if user.IsBlocked() {
+		app.suspendedAccountResponse(w, r, user)
+		return
+	}
+
+	// the users whose email address isn't verified by the provider activate their account as if registered with a password
+	if user.IsToConfirm() {
+		if created {
+			token, err := app.models.Tokens.New(user.ID, 3*24*time.Hour, data.TokenScope.Activation)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+
+			app.background(func() {
+
+				mailData := map[string]any{
+					"activationToken": token.Plaintext,
+					"userID":          user.ID,
+				}
+
+				err = app.mailer.Send(user.Email, "user_welcome.tmpl", mailData)
+				if err != nil {
+					app.logger.Error(err.Error())
+				}
+			})
+		}
+
+		response := envelope{
+			"user": envelope{
+				"id":        user.ID,
+				"name":      user.Name,
+				"email":     user.Email,
+				"createdAt": user.CreatedAt,
+				"message":   fmt.Sprintf("the account must be activated with the link sent to %s", user.Email),
+			},
+		}
+
+		err = app.writeJSON(w, http.StatusAccepted, response, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	userTOTP, err := app.models.TOTP.Get(user.ID)
+	if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// the provider replaces the password, not the second factor
+	if userTOTP.IsEnabled() {
+		err = app.models.Tokens.DeleteAllForUser(data.TokenScope.MFAPending, user.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		mfaToken, err := app.models.Tokens.New(user.ID, mfaPendingTTL, data.TokenScope.MFAPending)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		err = app.writeJSON(w, http.StatusAccepted, envelope{"mfa_token": mfaToken}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	session, err := app.newSession(r, user, input.UserAgent, input.IP)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	token, err := app.newAuthenticationToken(user, session, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, token, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -2,16 +2,27 @@ package main
 
 import (
 	"ForumAPI/internal/data"
+	"ForumAPI/internal/markdown"
+	"ForumAPI/internal/stream"
 	"ForumAPI/internal/validator"
 	"errors"
 	"fmt"
 	"net/http"
 	"slices"
 	"strconv"
+	"time"
 )
 
 type getPostsForm struct {
-	Search string `form:"q"`
+	Search               string `form:"q"`
+	AuthorID             int    `form:"author_id"`
+	ThreadID             int    `form:"thread_id"`
+	CategoryID           int    `form:"category_id"`
+	IncludeSubcategories bool   `form:"include_subcategories"`
+	TagIDs               []int  `form:"tag_id[]"`
+	TagMode              string `form:"tag_mode"`
+	CreatedAfter         string `form:"created_after"`
+	CreatedBefore        string `form:"created_before"`
 	data.Filters
 	validator.Validator `form:"-"`
 }
@@ -23,6 +34,12 @@ type postByIDForm struct {
 	validator.Validator `form:"-"`
 }
 
+type postRepliesForm struct {
+	ID                  int `form:"-"`
+	Depth               int `form:"depth"`
+	validator.Validator `form:"-"`
+}
+
 func (app *application) getPostsHandler(w http.ResponseWriter, r *http.Request) {
 
 	form := newGetPostsForm()
@@ -43,8 +60,48 @@ func (app *application) getPostsHandler(w http.ResponseWriter, r *http.Request)
 		form.Sort = form.SortSafelist[0]
 	}
 
+	if form.TagMode == "" {
+		form.TagMode = data.TagMatch.Any
+	}
+
 	data.ValidateFilters(&form.Validator, form.Filters)
 
+	form.Check(form.AuthorID >= 0, "author_id", "must be a valid id")
+	form.Check(form.ThreadID >= 0, "thread_id", "must be a valid id")
+	form.Check(form.CategoryID >= 0, "category_id", "must be a valid id")
+	form.Check(!form.IncludeSubcategories || form.CategoryID > 0, "include_subcategories", "requires category_id")
+	form.Check(len(form.TagIDs) <= 20, "tag_id[]", "must not contain more than 20 tags")
+	form.Check(validator.Unique(form.TagIDs), "tag_id[]", "duplicate values")
+	for _, id := range form.TagIDs {
+		form.Check(id > 0, "tag_id[]", fmt.Sprintf("incorrect value %d", id))
+	}
+	form.Check(validator.PermittedValue(form.TagMode, data.TagMatch.Any, data.TagMatch.All), "tag_mode", "invalid tag_mode value")
+
+	postFilters := data.PostFilters{
+		AuthorID:             form.AuthorID,
+		ThreadID:             form.ThreadID,
+		CategoryID:           form.CategoryID,
+		IncludeSubcategories: form.IncludeSubcategories,
+		TagIDs:               form.TagIDs,
+		AllTags:              form.TagMode == data.TagMatch.All,
+	}
+
+	if form.CreatedAfter != "" {
+		postFilters.CreatedAfter, err = time.Parse("2006-01-02", form.CreatedAfter)
+		if err != nil {
+			form.AddError("created_after", "must be a valid date in the format YYYY-MM-DD")
+		}
+	}
+	if form.CreatedBefore != "" {
+		postFilters.CreatedBefore, err = time.Parse("2006-01-02", form.CreatedBefore)
+		if err != nil {
+			form.AddError("created_before", "must be a valid date in the format YYYY-MM-DD")
+		}
+	}
+	if !postFilters.CreatedAfter.IsZero() && !postFilters.CreatedBefore.IsZero() {
+		form.Check(postFilters.CreatedAfter.Before(postFilters.CreatedBefore), "created_before", "must be after created_after")
+	}
+
 	if !form.Valid() {
 		err = app.writeJSON(w, http.StatusBadRequest, envelope{"errors": form.Errors}, nil)
 		if err != nil {
@@ -53,9 +110,14 @@ func (app *application) getPostsHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	posts, metadata, err := app.models.Posts.Get(form.Search, form.Filters)
+	posts, metadata, err := app.models.Posts.Get(form.Search, postFilters, form.Filters)
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
+		switch {
+		case errors.Is(err, data.ErrInvalidCursor):
+			app.badRequestResponse(w, r, err)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
@@ -65,6 +127,18 @@ func (app *application) getPostsHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	err = app.models.Posts.GetMentions(posts)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.Posts.GetAttachments(posts)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"_metadata": metadata, "posts": posts}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -92,7 +166,7 @@ func (app *application) createPostHandler(w http.ResponseWriter, r *http.Request
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
-	v.StringCheck(*input.Content, 1, 1_020, true, "content")
+	v.StringCheck(*input.Content, 1, 10_000, true, "content")
 
 	if input.ThreadID == nil {
 		v.AddError("thread", "must be provided")
@@ -110,6 +184,31 @@ func (app *application) createPostHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	// a reply must stay in the thread of the post it answers
+	var parentAuthorID int
+	var threadTitle string
+	if input.ParentPostID != nil {
+		parentPost, err := app.models.Posts.GetByID(*input.ParentPostID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				v.AddError("parent_post_id", "must refer to an existing post")
+			default:
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+		} else {
+			v.Check(parentPost.Thread.ID == *input.ThreadID, "parent_post_id", "must belong to the same thread")
+			parentAuthorID = parentPost.Author.ID
+			threadTitle = parentPost.Thread.Title
+		}
+
+		if !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+	}
+
 	user := app.contextGetUser(r)
 
 	post := &data.Post{
@@ -138,6 +237,35 @@ func (app *application) createPostHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	app.audit(r, data.AuditAction.Create, data.AuditEntity.Post, post.ID, nil, post)
+
+	app.hub.Publish(stream.ThreadChannel(post.Thread.ID), stream.EventType.PostCreated, post)
+
+	// notifying the author of the parent post and the users subscribed to the thread
+	notification := &data.Notification{
+		UserID: parentAuthorID,
+		Type:   data.NotificationType.Reply,
+		PostID: post.ID,
+	}
+	notification.Actor.ID = user.ID
+	notification.Actor.Name = user.Name
+	notification.Thread.ID = post.Thread.ID
+	notification.Thread.Title = threadTitle
+
+	if parentAuthorID != 0 {
+		app.notify(notification)
+	}
+
+	app.notifySubscribers(&data.Notification{
+		Type:   data.NotificationType.ThreadPost,
+		Actor:  notification.Actor,
+		Thread: notification.Thread,
+		PostID: post.ID,
+	}, parentAuthorID)
+
+	// the author of the parent post is already notified of the reply
+	app.notifyMentions(post, user, parentAuthorID)
+
 	// DEBUG
 	app.logger.Debug(fmt.Sprintf("created post: %+v", post))
 
@@ -147,6 +275,45 @@ func (app *application) createPostHandler(w http.ResponseWriter, r *http.Request
 	}
 }
 
+// previewPostHandler renders the Markdown content as it would appear once posted
+func (app *application) previewPostHandler(w http.ResponseWriter, r *http.Request) {
+
+	var input struct {
+		Content *string `json:"content"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if input.Content == nil {
+		v.AddError("content", "must be provided")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	v.StringCheck(*input.Content, 1, 10_000, true, "content")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	contentHTML, err := markdown.Render(*input.Content)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"content_html": contentHTML}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
 func (app *application) getSinglePostHandler(w http.ResponseWriter, r *http.Request) {
 
 	form := newPostByIDForm()
@@ -186,6 +353,12 @@ func (app *application) getSinglePostHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	// hidden posts are only visible to the moderation
+	if post.Status == data.PostStatus.Hidden && !app.contextGetUser(r).IsModerator() {
+		app.notFoundResponse(w, r)
+		return
+	}
+
 	if slices.Contains(form.Includes, "popularity") || slices.Contains(form.Includes, "reactions") {
 		posts := []*data.Post{post}
 		err = app.models.Posts.GetReactions(posts)
@@ -202,6 +375,74 @@ func (app *application) getSinglePostHandler(w http.ResponseWriter, r *http.Requ
 	}
 }
 
+func (app *application) getPostRepliesHandler(w http.ResponseWriter, r *http.Request) {
+
+	form := newPostRepliesForm()
+
+	err := app.decodeForm(r, &form)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	form.ID, err = app.readIDParam(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if form.Depth == 0 {
+		form.Depth = 5
+	}
+
+	form.Check(form.Depth > 0, "depth", "must be greater than zero")
+	form.Check(form.Depth <= 10, "depth", "must be a maximum of 10")
+
+	if !form.Valid() {
+		err = app.writeJSON(w, http.StatusBadRequest, envelope{"errors": form.Errors}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	replies, err := app.models.Posts.GetReplies(form.ID, form.Depth)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if len(replies) > 0 {
+		err = app.models.Posts.GetReactions(replies)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		err = app.models.Posts.GetMentions(replies)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		err = app.models.Posts.GetAttachments(replies)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"replies": data.NestPosts(replies)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
 func (app *application) updatePostHandler(w http.ResponseWriter, r *http.Request) {
 
 	id, err := app.readIDParam(r)
@@ -233,6 +474,10 @@ func (app *application) updatePostHandler(w http.ResponseWriter, r *http.Request
 		}
 	}
 
+	before := app.auditSnapshot(post)
+	oldThreadID := post.Thread.ID
+	alreadyMentioned := post.MentionedUserIDs()
+
 	var input struct {
 		Content *string `json:"content"`
 		Thread  *int    `json:"thread"`
@@ -247,7 +492,7 @@ func (app *application) updatePostHandler(w http.ResponseWriter, r *http.Request
 	v := validator.New()
 
 	if input.Content != nil {
-		v.StringCheck(*input.Content, 1, 1_020, true, "name")
+		v.StringCheck(*input.Content, 1, 10_000, true, "content")
 		post.Content = *input.Content
 	}
 	if input.Thread != nil {
@@ -259,7 +504,7 @@ func (app *application) updatePostHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	err = app.models.Posts.Update(*post)
+	err = app.models.Posts.Update(post, user.ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrEditConflict):
@@ -270,6 +515,19 @@ func (app *application) updatePostHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	app.audit(r, data.AuditAction.Update, data.AuditEntity.Post, post.ID, before, post)
+
+	// a post moved to another thread disappears from the old one
+	if post.Thread.ID != oldThreadID {
+		app.hub.Publish(stream.ThreadChannel(oldThreadID), stream.EventType.PostDeleted, envelope{"id": post.ID})
+		app.hub.Publish(stream.ThreadChannel(post.Thread.ID), stream.EventType.PostCreated, post)
+	} else {
+		app.hub.Publish(stream.ThreadChannel(post.Thread.ID), stream.EventType.PostUpdated, post)
+	}
+
+	// only the users mentioned by the edit are notified
+	app.notifyMentions(post, user, alreadyMentioned...)
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"post": post}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -301,7 +559,7 @@ func (app *application) deletePostHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	err = app.models.Posts.Delete(id)
+	err = app.models.Posts.Delete(id, user.ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -312,6 +570,10 @@ func (app *application) deletePostHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	app.audit(r, data.AuditAction.Delete, data.AuditEntity.Post, id, post, nil)
+
+	app.hub.Publish(stream.ThreadChannel(post.Thread.ID), stream.EventType.PostDeleted, envelope{"id": id})
+
 	response := envelope{
 		"message": fmt.Sprintf("deleted post with id %d", id),
 	}
@@ -357,6 +619,12 @@ func (app *application) reactToPostHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	app.notifyReaction(id, user)
+
+	app.publishReactions(id)
+
+	app.audit(r, data.AuditAction.React, data.AuditEntity.Post, id, nil, envelope{"reaction": input.Reaction})
+
 	response := envelope{
 		"message": fmt.Sprintf("added reaction %s to post with id %d", input.Reaction, id),
 	}
@@ -398,6 +666,10 @@ func (app *application) changeReactionPostHandler(w http.ResponseWriter, r *http
 		return
 	}
 
+	app.audit(r, data.AuditAction.React, data.AuditEntity.Post, id, nil, envelope{"reaction": input.Reaction})
+
+	app.publishReactions(id)
+
 	response := envelope{
 		"message": fmt.Sprintf("updated reaction %s to post with id %d", input.Reaction, id),
 	}
@@ -429,6 +701,10 @@ func (app *application) removeReactionPostHandler(w http.ResponseWriter, r *http
 		return
 	}
 
+	app.audit(r, data.AuditAction.Unreact, data.AuditEntity.Post, id, nil, nil)
+
+	app.publishReactions(id)
+
 	response := envelope{
 		"message": fmt.Sprintf("reaction removed from post with id %d", id),
 	}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"ForumAPI/internal/data"
+	"ForumAPI/internal/validator"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// profileAccess is what the viewer of a profile is allowed to see according to the owner's privacy settings
+type profileAccess struct {
+	privacy *data.Privacy
+	owner   bool // the viewer is the owner of the profile or an administrator
+	friend  bool
+}
+
+func (a *profileAccess) allows(visibility string) bool {
+	return data.Visible(visibility, a.owner, a.friend)
+}
+
+// profileAccessFor returns the access of the viewer to the user's profile, based on their relationship
+func (app *application) profileAccessFor(viewer, user *data.User) (*profileAccess, error) {
+
+	privacy, err := app.models.Privacy.GetByUserID(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	access := &profileAccess{
+		privacy: privacy,
+		owner:   viewer.ID == user.ID || viewer.Role == data.UserRole.Admin,
+	}
+
+	if !access.owner {
+		access.friend, err = app.models.Users.AreFriends(viewer.ID, user.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return access, nil
+}
+
+// hidePrivateFields removes the profile fields the viewer is not allowed to see
+func (a *profileAccess) hidePrivateFields(user *data.User) {
+
+	if !a.allows(a.privacy.Email) {
+		user.Email = ""
+	}
+	if !a.allows(a.privacy.BirthDate) {
+		user.BirthDate = time.Time{}
+	}
+	if !a.owner {
+		user.SuspensionReason = ""
+	}
+}
+
+// publicUser is the user shown to the viewers who may not see their email address, the field being left out
+type publicUser struct {
+	*data.User
+	Email string `json:"email,omitempty"`
+}
+
+// view returns the user as the viewer may see them
+func (a *profileAccess) view(user *data.User) any {
+
+	if !a.allows(a.privacy.Email) {
+		return publicUser{User: user}
+	}
+
+	return user
+}
+
+func (app *application) getPrivacyHandler(w http.ResponseWriter, r *http.Request) {
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrUserIDNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.badRequestResponse(w, r, err)
+		}
+		return
+	}
+
+	privacy, err := app.models.Privacy.GetByUserID(id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"privacy": privacy}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updatePrivacyHandler(w http.ResponseWriter, r *http.Request) {
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrUserIDNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.badRequestResponse(w, r, err)
+		}
+		return
+	}
+
+	user, err := app.models.Users.GetByID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	privacy, err := app.models.Privacy.GetByUserID(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	before := *privacy
+
+	var input struct {
+		Email           *string `json:"email"`
+		BirthDate       *string `json:"birth_date"`
+		Friends         *string `json:"friends"`
+		FavoriteThreads *string `json:"favorite_threads"`
+		FollowingTags   *string `json:"following_tags"`
+		Reactions       *string `json:"reactions"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	fields := []struct {
+		input *string
+		value *string
+		key   string
+	}{
+		{input.Email, &privacy.Email, "email"},
+		{input.BirthDate, &privacy.BirthDate, "birth_date"},
+		{input.Friends, &privacy.Friends, "friends"},
+		{input.FavoriteThreads, &privacy.FavoriteThreads, "favorite_threads"},
+		{input.FollowingTags, &privacy.FollowingTags, "following_tags"},
+		{input.Reactions, &privacy.Reactions, "reactions"},
+	}
+
+	var isEmpty = true
+	for _, field := range fields {
+		if field.input == nil {
+			continue
+		}
+		isEmpty = false
+		*field.value = strings.ToLower(strings.TrimSpace(*field.input))
+		v.Check(validator.PermittedValue(*field.value, data.PermittedVisibilities...), field.key, "must be public, friends or private")
+	}
+	if isEmpty {
+		v.AddError("privacy", "at least one field is required")
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Privacy.Upsert(user.ID, privacy)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.audit(r, data.AuditAction.Update, data.AuditEntity.User, user.ID, before, privacy)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"privacy": privacy}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,385 @@
+package main
+
+import (
+	"ForumAPI/internal/data"
+	"ForumAPI/internal/diff"
+	"ForumAPI/internal/stream"
+	"ForumAPI/internal/validator"
+	"errors"
+	"net/http"
+	"slices"
+)
+
+type revisionDiffForm struct {
+	From                int `form:"from"`
+	To                  int `form:"to"`
+	validator.Validator `form:"-"`
+}
+
+// diffVersions checks the versions to compare against the existing ones (listed the latest first):
+// to defaults to the latest version and from to the version preceding to.
+func diffVersions(form *revisionDiffForm, versions []int) {
+
+	if form.To == 0 {
+		form.To = versions[0]
+	}
+	if form.From == 0 {
+		for _, version := range versions {
+			if version < form.To {
+				form.From = version
+				break
+			}
+		}
+	}
+
+	form.Check(form.To > 0 && slices.Contains(versions, form.To), "to", "must be an existing version")
+	form.Check(form.From > 0 && slices.Contains(versions, form.From), "from", "must be an existing version")
+	form.Check(form.From != form.To, "from", "must be different from to")
+}
+
+/* #############################################################################
+/*	POSTS
+/* ############################################################################# */
+
+// getVisiblePost returns the post, unless it is hidden to the user
+func (app *application) getVisiblePost(w http.ResponseWriter, r *http.Request) (*data.Post, bool) {
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return nil, false
+	}
+
+	post, err := app.models.Posts.GetByID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	// hidden posts are only visible to the moderation
+	if post.Status == data.PostStatus.Hidden && !app.contextGetUser(r).IsModerator() {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	return post, true
+}
+
+func (app *application) getPostRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+
+	post, ok := app.getVisiblePost(w, r)
+	if !ok {
+		return
+	}
+
+	revisions, err := app.models.Revisions.GetForPost(post.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"revisions": revisions}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) diffPostRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+
+	post, ok := app.getVisiblePost(w, r)
+	if !ok {
+		return
+	}
+
+	form := newRevisionDiffForm()
+
+	err := app.decodeForm(r, &form)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	revisions, err := app.models.Revisions.GetForPost(post.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	versions := make([]int, len(revisions))
+	contents := make(map[int]string, len(revisions))
+	for i, revision := range revisions {
+		versions[i] = revision.Version
+		contents[revision.Version] = revision.Content
+	}
+
+	diffVersions(form, versions)
+
+	if !form.Valid() {
+		err = app.writeJSON(w, http.StatusBadRequest, envelope{"errors": form.Errors}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	result := envelope{
+		"from":    form.From,
+		"to":      form.To,
+		"content": diff.Words(contents[form.From], contents[form.To]),
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"diff": result}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) restorePostRevisionHandler(w http.ResponseWriter, r *http.Request) {
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	version, err := app.readVersionParam(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	post, err := app.models.Posts.GetByID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	revision, err := app.models.Revisions.GetPostRevision(post.ID, version)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	v := validator.New()
+	v.Check(revision.Content != post.Content, "version", "must differ from the current content")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	before := app.auditSnapshot(post)
+
+	post.Content = revision.Content
+
+	err = app.models.Posts.Update(post, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.audit(r, data.AuditAction.Restore, data.AuditEntity.Post, post.ID, before, post)
+
+	app.hub.Publish(stream.ThreadChannel(post.Thread.ID), stream.EventType.PostUpdated, post)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"post": post}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+/* #############################################################################
+/*	THREADS
+/* ############################################################################# */
+
+// getVisibleThread returns the thread, unless it is hidden to the user
+func (app *application) getVisibleThread(w http.ResponseWriter, r *http.Request) (*data.Thread, bool) {
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return nil, false
+	}
+
+	thread, err := app.models.Threads.GetByID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	// hidden threads are only visible to the moderation
+	if thread.Status == data.ThreadStatus.Hidden && !app.contextGetUser(r).IsModerator() {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	return thread, true
+}
+
+func (app *application) getThreadRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+
+	thread, ok := app.getVisibleThread(w, r)
+	if !ok {
+		return
+	}
+
+	revisions, err := app.models.Revisions.GetForThread(thread.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"revisions": revisions}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) diffThreadRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+
+	thread, ok := app.getVisibleThread(w, r)
+	if !ok {
+		return
+	}
+
+	form := newRevisionDiffForm()
+
+	err := app.decodeForm(r, &form)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	revisions, err := app.models.Revisions.GetForThread(thread.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	versions := make([]int, len(revisions))
+	byVersion := make(map[int]*data.ThreadRevision, len(revisions))
+	for i, revision := range revisions {
+		versions[i] = revision.Version
+		byVersion[revision.Version] = revision
+	}
+
+	diffVersions(form, versions)
+
+	if !form.Valid() {
+		err = app.writeJSON(w, http.StatusBadRequest, envelope{"errors": form.Errors}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	from, to := byVersion[form.From], byVersion[form.To]
+
+	result := envelope{
+		"from":        form.From,
+		"to":          form.To,
+		"title":       diff.Words(from.Title, to.Title),
+		"description": diff.Words(from.Description, to.Description),
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"diff": result}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) restoreThreadRevisionHandler(w http.ResponseWriter, r *http.Request) {
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	version, err := app.readVersionParam(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	thread, err := app.models.Threads.GetByID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	revision, err := app.models.Revisions.GetThreadRevision(thread.ID, version)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	v := validator.New()
+	v.Check(revision.Title != thread.Title || revision.Description != thread.Description, "version", "must differ from the current title and description")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	before := app.auditSnapshot(thread)
+
+	thread.Title = revision.Title
+	thread.Description = revision.Description
+
+	err = app.models.Threads.Update(thread, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateTitle):
+			v.AddError("title", "a thread with this title already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.audit(r, data.AuditAction.Restore, data.AuditEntity.Thread, thread.ID, before, thread)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"thread": thread}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
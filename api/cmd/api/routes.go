@@ -26,6 +26,12 @@ func (app *application) routes() http.Handler {
 		group.HandleFunc("/v1/tokens/public-key", app.getPublicKeyPEM, http.MethodGet)
 	})
 
+	/* #############################################################################
+	/* # FILES (PUBLIC, SO THAT THE BROWSERS CAN LOAD THEM DIRECTLY)
+	/* ############################################################################# */
+
+	router.HandleFunc("/v1/files/...", app.serveFileHandler, http.MethodGet)
+
 	/* #############################################################################
 	/* # BASIC ROUTES (WITH TOKEN HANDLING)
 	/* ############################################################################# */
@@ -60,6 +66,8 @@ func (app *application) routes() http.Handler {
 		group.Use(app.decryptRSA)
 
 		group.HandleFunc("/v1/tokens/authentication", app.createAuthenticationTokenHandler, http.MethodPost)
+		group.HandleFunc("/v1/tokens/authentication/mfa", app.createMFAAuthenticationTokenHandler, http.MethodPost)
+		group.HandleFunc("/v1/tokens/oidc", app.createOIDCAuthenticationTokenHandler, http.MethodPost)
 	})
 
 	// ##################################
@@ -71,12 +79,20 @@ func (app *application) routes() http.Handler {
 		group.HandleFunc("/v1/tokens/revoke/:id", app.revokeTokensHandler, http.MethodPost)
 	})
 
+	/* #############################################################################
+	/* # IDENTITY PROVIDERS (OPENID CONNECT & GITHUB)
+	/* ############################################################################# */
+
+	router.HandleFunc("/v1/oidc/providers", app.getIdentityProvidersHandler, http.MethodGet)
+	router.HandleFunc("/v1/oidc/:provider/authorization", app.getAuthorizationURLHandler, http.MethodGet)
+
 	/* #############################################################################
 	/* # USERS
 	/* ############################################################################# */
 
 	router.HandleFunc("/v1/users/activated", app.activateUserHandler, http.MethodPut)
 	router.HandleFunc("/v1/users/forgot-password", app.forgotPasswordHandler, http.MethodPost)
+	router.HandleFunc("/v1/users/unlocked", app.unlockAccountHandler, http.MethodPut)
 
 	// ##################################
 	// ENCRYPTED ROUTES
@@ -102,9 +118,21 @@ func (app *application) routes() http.Handler {
 		group.HandleFunc("/v1/users/:id/friend", app.friendResponseHandler, http.MethodPut)
 		group.HandleFunc("/v1/users/:id/friend", app.friendDeleteHandler, http.MethodDelete)
 
+		group.HandleFunc("/v1/users/:id/report", app.reportUserHandler, http.MethodPost)
+
 		// CHECK PERMISSIONS FOR USER MANIPULATION
 		group.Use(app.guardUserHandlers)
 		group.HandleFunc("/v1/users/:id", app.deleteUserHandler, http.MethodDelete)
+		group.HandleFunc("/v1/users/:id/avatar", app.updateAvatarHandler, http.MethodPut)
+		group.HandleFunc("/v1/users/:id/privacy", app.getPrivacyHandler, http.MethodGet)
+		group.HandleFunc("/v1/users/:id/privacy", app.updatePrivacyHandler, http.MethodPut)
+		group.HandleFunc("/v1/users/:id/sessions", app.getSessionsHandler, http.MethodGet)
+		group.HandleFunc("/v1/users/:id/sessions/:sid", app.deleteSessionHandler, http.MethodDelete)
+		group.HandleFunc("/v1/users/:id/totp", app.getTOTPHandler, http.MethodGet)
+		group.HandleFunc("/v1/users/:id/totp", app.enrollTOTPHandler, http.MethodPost)
+		group.HandleFunc("/v1/users/:id/totp", app.confirmTOTPHandler, http.MethodPut)
+		group.HandleFunc("/v1/users/:id/totp", app.disableTOTPHandler, http.MethodDelete)
+		group.HandleFunc("/v1/users/:id/totp/recovery-codes", app.regenerateRecoveryCodesHandler, http.MethodPost)
 
 		// ENCRYPTED ROUTE
 		group.Use(app.decryptRSA)
@@ -139,6 +167,10 @@ func (app *application) routes() http.Handler {
 	router.HandleFunc("/v1/threads", app.getThreadsHandler, http.MethodGet)
 
 	router.HandleFunc("/v1/threads/:id", app.getSingleThreadHandler, http.MethodGet)
+	router.HandleFunc("/v1/threads/:id/revisions", app.getThreadRevisionsHandler, http.MethodGet)
+	router.HandleFunc("/v1/threads/:id/revisions/diff", app.diffThreadRevisionsHandler, http.MethodGet)
+
+	router.HandleFunc("/v1/subscriptions/unsubscribe", app.unsubscribeHandler, http.MethodPut)
 
 	// ##################################
 	// PROTECTED ROUTES
@@ -153,6 +185,11 @@ func (app *application) routes() http.Handler {
 
 		group.HandleFunc("/v1/threads/:id/favorite", app.addToFavoritesThreadHandler, http.MethodPost)
 		group.HandleFunc("/v1/threads/:id/favorite", app.removeFromFavoritesThreadHandler, http.MethodDelete)
+
+		group.HandleFunc("/v1/threads/:id/subscription", app.getSubscriptionHandler, http.MethodGet)
+		group.HandleFunc("/v1/threads/:id/subscription", app.updateSubscriptionHandler, http.MethodPut)
+
+		group.HandleFunc("/v1/threads/:id/report", app.reportThreadHandler, http.MethodPost)
 	})
 
 	/* #############################################################################
@@ -185,6 +222,9 @@ func (app *application) routes() http.Handler {
 	router.HandleFunc("/v1/posts", app.getPostsHandler, http.MethodGet)
 
 	router.HandleFunc("/v1/posts/:id", app.getSinglePostHandler, http.MethodGet)
+	router.HandleFunc("/v1/posts/:id/replies", app.getPostRepliesHandler, http.MethodGet)
+	router.HandleFunc("/v1/posts/:id/revisions", app.getPostRevisionsHandler, http.MethodGet)
+	router.HandleFunc("/v1/posts/:id/revisions/diff", app.diffPostRevisionsHandler, http.MethodGet)
 
 	// ##################################
 	// PROTECTED ROUTES
@@ -193,6 +233,7 @@ func (app *application) routes() http.Handler {
 		group.Use(app.requireActivatedUser)
 
 		group.HandleFunc("/v1/posts", app.createPostHandler, http.MethodPost)
+		group.HandleFunc("/v1/posts/preview", app.previewPostHandler, http.MethodPost)
 
 		group.HandleFunc("/v1/posts/:id", app.updatePostHandler, http.MethodPut)
 		group.HandleFunc("/v1/posts/:id", app.deletePostHandler, http.MethodDelete)
@@ -200,6 +241,77 @@ func (app *application) routes() http.Handler {
 		group.HandleFunc("/v1/posts/:id/react", app.reactToPostHandler, http.MethodPost)
 		group.HandleFunc("/v1/posts/:id/react", app.changeReactionPostHandler, http.MethodPatch)
 		group.HandleFunc("/v1/posts/:id/react", app.removeReactionPostHandler, http.MethodDelete)
+
+		group.HandleFunc("/v1/posts/:id/report", app.reportPostHandler, http.MethodPost)
+
+		group.HandleFunc("/v1/posts/:id/attachments", app.uploadAttachmentHandler, http.MethodPost)
+		group.HandleFunc("/v1/attachments/:id", app.deleteAttachmentHandler, http.MethodDelete)
+	})
+
+	/* #############################################################################
+	/* # STREAM (SERVER-SENT EVENTS)
+	/* ############################################################################# */
+
+	router.HandleFunc("/v1/stream", app.streamHandler, http.MethodGet)
+
+	/* #############################################################################
+	/* # NOTIFICATIONS
+	/* ############################################################################# */
+
+	router.Group(func(group *flow.Mux) {
+		group.Use(app.requireActivatedUser)
+
+		group.HandleFunc("/v1/notifications", app.getNotificationsHandler, http.MethodGet)
+		group.HandleFunc("/v1/notifications/read", app.readNotificationsHandler, http.MethodPut)
+	})
+
+	/* #############################################################################
+	/* # DIRECT MESSAGES
+	/* ############################################################################# */
+
+	router.Group(func(group *flow.Mux) {
+		group.Use(app.requireActivatedUser)
+
+		group.HandleFunc("/v1/conversations", app.getConversationsHandler, http.MethodGet)
+		group.HandleFunc("/v1/conversations", app.createConversationHandler, http.MethodPost)
+
+		group.HandleFunc("/v1/conversations/:id/messages", app.getMessagesHandler, http.MethodGet)
+		group.HandleFunc("/v1/conversations/:id/messages", app.createMessageHandler, http.MethodPost)
+	})
+
+	/* #############################################################################
+	/* # MODERATION
+	/* ############################################################################# */
+
+	router.Group(func(group *flow.Mux) {
+		group.Use(app.requireModerator)
+
+		group.HandleFunc("/v1/moderation/queue", app.getModerationQueueHandler, http.MethodGet)
+
+		group.HandleFunc("/v1/moderation/reports/:id", app.getSingleReportHandler, http.MethodGet)
+		group.HandleFunc("/v1/moderation/reports/:id/resolve", app.resolveReportHandler, http.MethodPost)
+
+		group.HandleFunc("/v1/users/:id/suspension", app.suspendUserHandler, http.MethodPut)
+		group.HandleFunc("/v1/users/:id/suspension", app.liftSuspensionHandler, http.MethodDelete)
+
+		group.HandleFunc("/v1/threads/:id/revisions/:version/restore", app.restoreThreadRevisionHandler, http.MethodPost)
+		group.HandleFunc("/v1/posts/:id/revisions/:version/restore", app.restorePostRevisionHandler, http.MethodPost)
+
+		group.HandleFunc("/v1/trash", app.getTrashHandler, http.MethodGet)
+		group.HandleFunc("/v1/categories/:id/restore", app.restoreCategoryHandler, http.MethodPost)
+		group.HandleFunc("/v1/tags/:id/restore", app.restoreTagHandler, http.MethodPost)
+		group.HandleFunc("/v1/threads/:id/restore", app.restoreThreadHandler, http.MethodPost)
+		group.HandleFunc("/v1/posts/:id/restore", app.restorePostHandler, http.MethodPost)
+	})
+
+	/* #############################################################################
+	/* # AUDIT
+	/* ############################################################################# */
+
+	router.Group(func(group *flow.Mux) {
+		group.Use(app.requireAdmin)
+
+		group.HandleFunc("/v1/audit", app.getAuditHandler, http.MethodGet)
 	})
 
 	/* #############################################################################
@@ -207,8 +319,16 @@ func (app *application) routes() http.Handler {
 	/* ############################################################################# */
 
 	router.HandleFunc("/v1/popular", app.getPopularHandler, http.MethodGet)
-	//router.HandleFunc("/v1/recommendations/:id", app.getRecommendations, http.MethodGet)
-	//router.HandleFunc("/v1/search", app.searchHandler, http.MethodGet)
+	router.HandleFunc("/v1/search", app.searchHandler, http.MethodGet)
+
+	// ##################################
+	// PROTECTED ROUTES
+	// ##################################
+	router.Group(func(group *flow.Mux) {
+		group.Use(app.requireActivatedUser)
+
+		group.HandleFunc("/v1/recommendations/:id", app.getRecommendationsHandler, http.MethodGet)
+	})
 
 	return router
 }
@@ -22,6 +22,9 @@ func (app *application) serve() error {
 		ErrorLog:     slog.NewLogLogger(app.logger.Handler(), slog.LevelError),
 	}
 
+	// closing the event streams, otherwise the server would wait for them until the timeout
+	srv.RegisterOnShutdown(app.hub.Close)
+
 	shutdownError := make(chan error)
 
 	go func() {
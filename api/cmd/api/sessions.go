@@ -0,0 +1,192 @@
+package main
+
+import (
+	"ForumAPI/internal/data"
+	"errors"
+	"fmt"
+	"github.com/alexedwards/flow"
+	"github.com/tomasen/realip"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// deviceBrowsers and deviceSystems are looked up in order in the user agents,
+// the more specific names first (e.g. Edge user agents also mention Chrome and Safari)
+var (
+	deviceBrowsers = []struct{ token, name string }{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+	}
+	deviceSystems = []struct{ token, name string }{
+		{"Android", "Android"},
+		{"iPhone", "iPhone"},
+		{"iPad", "iPad"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"Linux", "Linux"},
+	}
+)
+
+// deviceLabel returns a readable name of the device using the user agent, e.g. "Firefox on Linux"
+func deviceLabel(userAgent string) string {
+
+	var browser, system string
+
+	for _, b := range deviceBrowsers {
+		if strings.Contains(userAgent, b.token) {
+			browser = b.name
+			break
+		}
+	}
+	for _, s := range deviceSystems {
+		if strings.Contains(userAgent, s.token) {
+			system = s.name
+			break
+		}
+	}
+
+	switch {
+	case browser != "" && system != "":
+		return fmt.Sprintf("%s on %s", browser, system)
+	case browser != "":
+		return browser
+	case system != "":
+		return system
+	default:
+		return "Unknown device"
+	}
+}
+
+// newSession creates the session of a user logging in.
+// The user agent and IP address are the ones of the request, unless the client forwards the ones of its own user.
+func (app *application) newSession(r *http.Request, user *data.User, userAgent, ip string) (*data.Session, error) {
+
+	if userAgent == "" {
+		userAgent = r.UserAgent()
+	}
+	if ip == "" {
+		ip = realip.FromRequest(r)
+	}
+
+	session := &data.Session{
+		UserID:    user.ID,
+		Device:    deviceLabel(userAgent),
+		UserAgent: truncate(userAgent, 255),
+		IP:        truncate(ip, 45),
+	}
+
+	err := app.models.Sessions.Insert(session)
+	if err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// truncate cuts s to at most n bytes
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+// readSessionIDParam reads the session id in the path, "current" meaning the session of the request's token
+func (app *application) readSessionIDParam(r *http.Request) (int, error) {
+
+	param := flow.Param(r.Context(), "sid")
+	if param == "current" {
+		id := app.contextGetUser(r).SessionID
+		if id == 0 {
+			return 0, data.ErrRecordNotFound
+		}
+		return id, nil
+	}
+
+	id, err := strconv.ParseInt(param, 10, 64)
+	if err != nil || id < 1 {
+		return 0, errors.New("invalid session id parameter")
+	}
+
+	return int(id), nil
+}
+
+func (app *application) getSessionsHandler(w http.ResponseWriter, r *http.Request) {
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrUserIDNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.badRequestResponse(w, r, err)
+		}
+		return
+	}
+
+	sessions, err := app.models.Sessions.GetByUserID(id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	for _, session := range sessions {
+		session.Current = session.ID == user.SessionID
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"sessions": sessions}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteSessionHandler(w http.ResponseWriter, r *http.Request) {
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrUserIDNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.badRequestResponse(w, r, err)
+		}
+		return
+	}
+
+	sessionID, err := app.readSessionIDParam(r)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.badRequestResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.Sessions.Delete(sessionID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	response := envelope{
+		"message": fmt.Sprintf("session %d revoked for user %d", sessionID, id),
+	}
+
+	err = app.writeJSON(w, http.StatusOK, response, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"ForumAPI/internal/data"
+	"ForumAPI/internal/stream"
+	"ForumAPI/internal/validator"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type streamForm struct {
+	Channels            []string `form:"channels[]"`
+	validator.Validator `form:"-"`
+}
+
+// publishReactions sends in the background the updated reaction counts of a post to the subscribers of its thread
+func (app *application) publishReactions(postID int) {
+
+	app.background(func() {
+		post, err := app.models.Posts.GetByID(postID)
+		if err != nil {
+			app.logger.Error(err.Error())
+			return
+		}
+
+		err = app.models.Posts.GetReactions([]*data.Post{post})
+		if err != nil {
+			app.logger.Error(err.Error())
+			return
+		}
+
+		app.hub.Publish(stream.ThreadChannel(post.Thread.ID), stream.EventType.ReactionsUpdated, envelope{"post_id": post.ID, "reactions": post.Reactions})
+	})
+}
+
+// streamChannels translates the requested channels into hub channels:
+// "thread:<id>" for the activity of a visible thread and "notifications" for the user's notifications
+func (app *application) streamChannels(r *http.Request, form *streamForm) ([]string, error) {
+
+	user := app.contextGetUser(r)
+
+	var channels []string
+
+	for _, channel := range form.Channels {
+		switch {
+		case channel == "notifications":
+			if user.IsAnonymous() {
+				form.AddError("channels[]", "notifications require an authenticated user")
+				continue
+			}
+			channels = append(channels, stream.UserChannel(user.ID))
+
+		case strings.HasPrefix(channel, "thread:"):
+			id, err := strconv.Atoi(strings.TrimPrefix(channel, "thread:"))
+			if err != nil || id < 1 {
+				form.AddError("channels[]", fmt.Sprintf("incorrect value %s", channel))
+				continue
+			}
+
+			thread, err := app.models.Threads.GetByID(id)
+			if err != nil {
+				switch {
+				case errors.Is(err, data.ErrRecordNotFound):
+					form.AddError("channels[]", fmt.Sprintf("thread %d not found", id))
+					continue
+				default:
+					return nil, err
+				}
+			}
+			if thread.Status == data.ThreadStatus.Hidden && !user.IsModerator() {
+				form.AddError("channels[]", fmt.Sprintf("thread %d not found", id))
+				continue
+			}
+			channels = append(channels, stream.ThreadChannel(id))
+
+		default:
+			form.AddError("channels[]", fmt.Sprintf("incorrect value %s", channel))
+		}
+	}
+
+	return channels, nil
+}
+
+func (app *application) streamHandler(w http.ResponseWriter, r *http.Request) {
+
+	form := newStreamForm()
+
+	err := app.decodeForm(r, &form)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	form.Check(len(form.Channels) > 0, "channels[]", "must be provided")
+	form.Check(len(form.Channels) <= 20, "channels[]", "must not contain more than 20 channels")
+	form.Check(validator.Unique(form.Channels), "channels[]", "duplicate values")
+
+	channels, err := app.streamChannels(r, form)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !form.Valid() {
+		err = app.writeJSON(w, http.StatusBadRequest, envelope{"errors": form.Errors}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	subscriber, err := app.hub.Subscribe(channels...)
+	if err != nil {
+		switch {
+		case errors.Is(err, stream.ErrHubClosed):
+			app.serverShuttingDownResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	defer app.hub.Unsubscribe(subscriber)
+
+	// the stream is a background task for the graceful shutdown
+	app.wg.Add(1)
+	defer app.wg.Done()
+
+	// the server's write timeout doesn't apply to the stream
+	rc := http.NewResponseController(w)
+	err = rc.SetWriteDeadline(time.Time{})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	_, err = fmt.Fprint(w, "retry: 5000\n\n")
+	if err == nil {
+		err = rc.Flush()
+	}
+
+	heartbeat := time.NewTicker(app.config.stream.heartbeat)
+	defer heartbeat.Stop()
+
+	for err == nil {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case event, ok := <-subscriber.Events():
+			// unsubscribed by the hub (slow consumer or shutdown)
+			if !ok {
+				return
+			}
+
+			js, err := json.Marshal(event)
+			if err != nil {
+				app.logError(r, err)
+				continue
+			}
+
+			_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, js)
+			if err != nil {
+				return
+			}
+
+		case <-heartbeat.C:
+			_, err = fmt.Fprint(w, ": heartbeat\n\n")
+		}
+
+		if err == nil {
+			err = rc.Flush()
+		}
+	}
+}
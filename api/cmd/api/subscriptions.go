@@ -0,0 +1,183 @@
+package main
+
+import (
+	"ForumAPI/internal/data"
+	"ForumAPI/internal/validator"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// sendDigests sends every N duration the daily digests of the subscribed threads which are due
+func (app *application) sendDigests(frequency, timeout time.Duration) {
+	defer func() {
+		if err := recover(); err != nil {
+			app.logger.Error(fmt.Sprintf("%v", err))
+		}
+	}()
+	time.Sleep(timeout)
+	for {
+		until := time.Now()
+
+		digests, err := app.models.Subscriptions.GetDigests(until)
+		if err != nil {
+			app.logger.Error(err.Error())
+		}
+
+		for _, digest := range digests {
+			app.background(func() {
+				err := app.mailer.Send(digest.Email, "thread_digest.tmpl", map[string]any{
+					"username": digest.Username,
+					"threads":  digest.Threads,
+				})
+				if err != nil {
+					app.logger.Error(err.Error())
+					return
+				}
+
+				err = app.models.Subscriptions.MarkDigestSent(digest.UserID, until)
+				if err != nil {
+					app.logger.Error(err.Error())
+				}
+			})
+		}
+
+		time.Sleep(frequency)
+	}
+}
+
+func (app *application) getSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	subscription, err := app.models.Subscriptions.Get(user.ID, id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"subscription": subscription}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	var input struct {
+		Level string `json:"level"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	subscription := &data.Subscription{
+		ThreadID: id,
+		Level:    strings.ToLower(strings.TrimSpace(input.Level)),
+	}
+
+	v := validator.New()
+
+	v.Check(validator.PermittedValue(subscription.Level, data.PermittedSubscriptionLevels...), "level", "must be none, in_app, immediate or daily")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	_, err = app.models.Threads.GetByID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.Subscriptions.Set(user.ID, id, subscription.Level)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"subscription": subscription}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// unsubscribeHandler unsubscribes a user from a thread with the signed token of the link sent by email, without requiring them to log in
+func (app *application) unsubscribeHandler(w http.ResponseWriter, r *http.Request) {
+
+	var input struct {
+		Token string `json:"token"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	userID, threadID, err := data.ParseUnsubscribeToken(input.Token)
+	if err != nil {
+		v.AddError("token", "invalid unsubscribe link")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Subscriptions.Set(userID, threadID, data.SubscriptionLevel.None)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	thread, err := app.models.Threads.GetByID(threadID)
+	if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	message := "successfully unsubscribed from the thread"
+	if thread != nil {
+		message = fmt.Sprintf("successfully unsubscribed from the thread %s", thread.Title)
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": message}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
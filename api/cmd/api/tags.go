@@ -55,7 +55,12 @@ func (app *application) getTagsHandler(w http.ResponseWriter, r *http.Request) {
 
 	tags, metadata, err := app.models.Tags.Get(form.Search, form.Filters)
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
+		switch {
+		case errors.Is(err, data.ErrInvalidCursor):
+			app.badRequestResponse(w, r, err)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
@@ -122,6 +127,8 @@ func (app *application) createTagHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	app.audit(r, data.AuditAction.Create, data.AuditEntity.Tag, tag.ID, nil, tag)
+
 	err = app.writeJSON(w, http.StatusCreated, envelope{"tag": tag}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -224,6 +231,8 @@ func (app *application) updateTagHandler(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
+	before := app.auditSnapshot(tag)
+
 	var input struct {
 		Name          *string `json:"name"`
 		AddThreads    *[]int  `json:"add_threads"`
@@ -269,6 +278,8 @@ func (app *application) updateTagHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	app.audit(r, data.AuditAction.Update, data.AuditEntity.Tag, tag.ID, before, tag)
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"tag": tag}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -300,7 +311,7 @@ func (app *application) deleteTagHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err = app.models.Tags.Delete(id)
+	err = app.models.Tags.Delete(id, user.ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -311,6 +322,8 @@ func (app *application) deleteTagHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	app.audit(r, data.AuditAction.Delete, data.AuditEntity.Tag, id, tag, nil)
+
 	response := envelope{
 		"message": fmt.Sprintf("deleted tag with id %d", id),
 	}
@@ -346,6 +359,8 @@ func (app *application) followTagHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	app.audit(r, data.AuditAction.Follow, data.AuditEntity.Tag, id, nil, nil)
+
 	response := envelope{
 		"message": fmt.Sprintf("tag with id %d successfully added to your following list", id),
 	}
@@ -377,6 +392,8 @@ func (app *application) unfollowTagHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	app.audit(r, data.AuditAction.Unfollow, data.AuditEntity.Tag, id, nil, nil)
+
 	response := envelope{
 		"message": fmt.Sprintf("tag with id %d successfully removed from following list", id),
 	}
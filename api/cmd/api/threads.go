@@ -8,10 +8,20 @@ import (
 	"net/http"
 	"slices"
 	"strconv"
+	"time"
 )
 
 type getThreadsForm struct {
-	Search string `form:"q"`
+	Search               string `form:"q"`
+	AuthorID             int    `form:"author_id"`
+	CategoryID           int    `form:"category_id"`
+	IncludeSubcategories bool   `form:"include_subcategories"`
+	TagIDs               []int  `form:"tag_id[]"`
+	TagMode              string `form:"tag_mode"`
+	CreatedAfter         string `form:"created_after"`
+	CreatedBefore        string `form:"created_before"`
+	Status               string `form:"status"`
+	IsPublic             *bool  `form:"is_public"`
 	data.Filters
 	validator.Validator `form:"-"`
 }
@@ -19,6 +29,8 @@ type getThreadsForm struct {
 type threadByIDForm struct {
 	ID                  int      `form:"-"`
 	Includes            []string `form:"includes[]"`
+	Layout              string   `form:"layout"`
+	Depth               int      `form:"depth"`
 	PermittedFields     []string `form:"-"`
 	validator.Validator `form:"-"`
 }
@@ -43,8 +55,56 @@ func (app *application) getThreadsHandler(w http.ResponseWriter, r *http.Request
 		form.Sort = form.SortSafelist[0]
 	}
 
+	if form.TagMode == "" {
+		form.TagMode = data.TagMatch.Any
+	}
+
 	data.ValidateFilters(&form.Validator, form.Filters)
 
+	form.Check(form.AuthorID >= 0, "author_id", "must be a valid id")
+	form.Check(form.CategoryID >= 0, "category_id", "must be a valid id")
+	form.Check(!form.IncludeSubcategories || form.CategoryID > 0, "include_subcategories", "requires category_id")
+	form.Check(len(form.TagIDs) <= 20, "tag_id[]", "must not contain more than 20 tags")
+	form.Check(validator.Unique(form.TagIDs), "tag_id[]", "duplicate values")
+	for _, id := range form.TagIDs {
+		form.Check(id > 0, "tag_id[]", fmt.Sprintf("incorrect value %d", id))
+	}
+	form.Check(validator.PermittedValue(form.TagMode, data.TagMatch.Any, data.TagMatch.All), "tag_mode", "invalid tag_mode value")
+
+	// the hidden threads are only listed for the moderation
+	isModerator := app.contextGetUser(r).IsModerator()
+	if form.Status != "" {
+		form.Check(validator.PermittedValue(form.Status, data.ThreadStatus.Active, data.ThreadStatus.Archived, data.ThreadStatus.Hidden), "status", "invalid status value")
+		form.Check(form.Status != data.ThreadStatus.Hidden || isModerator, "status", "invalid status value")
+	}
+
+	threadFilters := data.ThreadFilters{
+		AuthorID:             form.AuthorID,
+		CategoryID:           form.CategoryID,
+		IncludeSubcategories: form.IncludeSubcategories,
+		TagIDs:               form.TagIDs,
+		AllTags:              form.TagMode == data.TagMatch.All,
+		Status:               form.Status,
+		IsPublic:             form.IsPublic,
+		IncludeHidden:        isModerator,
+	}
+
+	if form.CreatedAfter != "" {
+		threadFilters.CreatedAfter, err = time.Parse("2006-01-02", form.CreatedAfter)
+		if err != nil {
+			form.AddError("created_after", "must be a valid date in the format YYYY-MM-DD")
+		}
+	}
+	if form.CreatedBefore != "" {
+		threadFilters.CreatedBefore, err = time.Parse("2006-01-02", form.CreatedBefore)
+		if err != nil {
+			form.AddError("created_before", "must be a valid date in the format YYYY-MM-DD")
+		}
+	}
+	if !threadFilters.CreatedAfter.IsZero() && !threadFilters.CreatedBefore.IsZero() {
+		form.Check(threadFilters.CreatedAfter.Before(threadFilters.CreatedBefore), "created_before", "must be after created_after")
+	}
+
 	if !form.Valid() {
 		err = app.writeJSON(w, http.StatusBadRequest, envelope{"errors": form.Errors}, nil)
 		if err != nil {
@@ -53,9 +113,14 @@ func (app *application) getThreadsHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	threads, metadata, err := app.models.Threads.Get(form.Search, form.Filters)
+	threads, metadata, err := app.models.Threads.Get(form.Search, threadFilters, form.Filters)
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
+		switch {
+		case errors.Is(err, data.ErrInvalidCursor):
+			app.badRequestResponse(w, r, err)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
@@ -118,12 +183,23 @@ func (app *application) createThreadHandler(w http.ResponseWriter, r *http.Reque
 		case errors.Is(err, data.ErrDuplicateName):
 			v.AddError("name", "a thread with this name already exists")
 			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("category_id", "must refer to an existing category")
+			app.failedValidationResponse(w, r, v.Errors)
 		default:
 			app.serverErrorResponse(w, r, err)
 		}
 		return
 	}
 
+	app.audit(r, data.AuditAction.Create, data.AuditEntity.Thread, thread.ID, nil, thread)
+
+	// the author is notified of the posts of their own thread
+	err = app.models.Subscriptions.Subscribe(user.ID, thread.ID)
+	if err != nil {
+		app.logger.Error(err.Error())
+	}
+
 	err = app.writeJSON(w, http.StatusCreated, envelope{"thread": thread}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -146,10 +222,20 @@ func (app *application) getSingleThreadHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	if form.Layout == "" {
+		form.Layout = "flat"
+	}
+	if form.Depth == 0 {
+		form.Depth = 5
+	}
+
 	form.Check(validator.Unique(form.Includes), "includes[]", "duplicate values")
 	for _, field := range form.Includes {
 		form.Check(validator.PermittedValue(field, form.PermittedFields...), "includes[]", fmt.Sprintf("incorrect value %s", field))
 	}
+	form.Check(validator.PermittedValue(form.Layout, "flat", "tree"), "layout", fmt.Sprintf("incorrect value %s", form.Layout))
+	form.Check(form.Depth > 0, "depth", "must be greater than zero")
+	form.Check(form.Depth <= 10, "depth", "must be a maximum of 10")
 
 	if !form.Valid() {
 		err = app.writeJSON(w, http.StatusBadRequest, envelope{"errors": form.Errors}, nil)
@@ -173,8 +259,18 @@ func (app *application) getSingleThreadHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	// hidden threads are only visible to the moderation
+	if thread.Status == data.ThreadStatus.Hidden && !app.contextGetUser(r).IsModerator() {
+		app.notFoundResponse(w, r)
+		return
+	}
+
 	if slices.Contains(form.Includes, "posts") {
-		thread.Posts, err = app.models.Posts.GetByThread(thread.ID)
+		if form.Layout == "tree" {
+			thread.Posts, err = app.models.Posts.GetTreeByThread(thread.ID, form.Depth)
+		} else {
+			thread.Posts, err = app.models.Posts.GetByThread(thread.ID)
+		}
 		if err != nil {
 			switch {
 			case errors.Is(err, data.ErrRecordNotFound):
@@ -188,14 +284,31 @@ func (app *application) getSingleThreadHandler(w http.ResponseWriter, r *http.Re
 				return
 			}
 		}
-		err = app.models.Posts.GetReactions(thread.Posts)
-		if err != nil {
+		if len(thread.Posts) > 0 {
+			err = app.models.Posts.GetReactions(thread.Posts)
+			if err != nil {
 
-			// DEBUG
-			app.logger.Debug("app.models.Posts.GetReactions(thread.Posts)")
+				// DEBUG
+				app.logger.Debug("app.models.Posts.GetReactions(thread.Posts)")
 
-			app.serverErrorResponse(w, r, err)
-			return
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+
+			err = app.models.Posts.GetMentions(thread.Posts)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+
+			err = app.models.Posts.GetAttachments(thread.Posts)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+		}
+		if form.Layout == "tree" {
+			thread.Posts = data.NestPosts(thread.Posts)
 		}
 
 		// DEBUG
@@ -266,6 +379,8 @@ func (app *application) updateThreadHandler(w http.ResponseWriter, r *http.Reque
 		}
 	}
 
+	before := app.auditSnapshot(thread)
+
 	var input struct {
 		Title       *string `json:"title"`
 		Description *string `json:"description"`
@@ -299,7 +414,7 @@ func (app *application) updateThreadHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	err = app.models.Threads.Update(thread)
+	err = app.models.Threads.Update(thread, user.ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrDuplicateTitle):
@@ -313,6 +428,8 @@ func (app *application) updateThreadHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	app.audit(r, data.AuditAction.Update, data.AuditEntity.Thread, thread.ID, before, thread)
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"thread": thread}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -344,7 +461,7 @@ func (app *application) deleteThreadHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	err = app.models.Threads.Delete(id)
+	err = app.models.Threads.Delete(id, user.ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -355,6 +472,8 @@ func (app *application) deleteThreadHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	app.audit(r, data.AuditAction.Delete, data.AuditEntity.Thread, id, thread, nil)
+
 	response := envelope{
 		"message": fmt.Sprintf("deleted thread with id %d", id),
 	}
@@ -390,6 +509,14 @@ func (app *application) addToFavoritesThreadHandler(w http.ResponseWriter, r *ht
 		return
 	}
 
+	app.audit(r, data.AuditAction.Favorite, data.AuditEntity.Thread, id, nil, nil)
+
+	// the favorite threads keep notifying their new posts, unless the user already chose another level
+	err = app.models.Subscriptions.Subscribe(user.ID, id)
+	if err != nil {
+		app.logger.Error(err.Error())
+	}
+
 	response := envelope{
 		"message": fmt.Sprintf("thread with id %d successfully added to favorites", id),
 	}
@@ -421,6 +548,8 @@ func (app *application) removeFromFavoritesThreadHandler(w http.ResponseWriter,
 		return
 	}
 
+	app.audit(r, data.AuditAction.Unfavorite, data.AuditEntity.Thread, id, nil, nil)
+
 	response := envelope{
 		"message": fmt.Sprintf("thread with id %d successfully removed from favorites", id),
 	}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"ForumAPI/internal/data"
+	"ForumAPI/internal/validator"
+	"errors"
+	"fmt"
+	"github.com/tomasen/realip"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+// unlockTokenTTL is how long the unlock link sent with the lockout email is valid
+const unlockTokenTTL = 24 * time.Hour
+
+// loginSubjects returns the keys the failed logins are counted under: the email address (whether an account exists or not)
+// and the IP address of the user. The IP address in the body is only trusted when forwarded by a trusted proxy
+// (the web server, all the web users coming from it), anyone else could change it at each attempt.
+func (app *application) loginSubjects(r *http.Request, email, ip string) (string, string) {
+	if ip == "" || !app.isTrustedProxy(r) {
+		ip = realip.FromRequest(r)
+	}
+	return strings.ToLower(strings.TrimSpace(email)), ip
+}
+
+// isTrustedProxy tells whether the request comes directly from one of the trusted proxies
+func (app *application) isTrustedProxy(r *http.Request) bool {
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+
+	for _, prefix := range app.config.login.trustedProxies {
+		if prefix.Contains(addr.Unmap()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// loginRetryAfter returns how long the login must wait because of the previous failures of the account or the IP address
+func (app *application) loginRetryAfter(email, ip string) (time.Duration, error) {
+
+	cfg := app.config.login
+	now := time.Now()
+
+	account, err := app.models.LoginThrottles.Get(data.ThrottleKind.Account, email, cfg.window)
+	if err != nil {
+		return 0, err
+	}
+
+	address, err := app.models.LoginThrottles.Get(data.ThrottleKind.IP, ip, cfg.window)
+	if err != nil {
+		return 0, err
+	}
+
+	return max(
+		account.RetryAfter(now, cfg.freeFailures, cfg.backoff, cfg.maxBackoff),
+		address.RetryAfter(now, cfg.ipFreeFailures, cfg.backoff, cfg.maxBackoff),
+	), nil
+}
+
+// loginFailed counts the failed login of the account and IP address, and locks them when they failed too many times.
+// The owner of a locked account (nil when no account has the email address) gets an email with an unlock link.
+func (app *application) loginFailed(email, ip string, user *data.User) error {
+
+	cfg := app.config.login
+	until := time.Now().Add(cfg.lockout)
+
+	account, err := app.models.LoginThrottles.RecordFailure(data.ThrottleKind.Account, email, cfg.window)
+	if err != nil {
+		return err
+	}
+
+	if account.Failures >= cfg.maxFailures && !account.IsLocked(time.Now()) {
+		err = app.models.LoginThrottles.Lock(account, until)
+		if err != nil {
+			return err
+		}
+
+		app.logger.Warn("account locked after too many failed logins", slog.String("email", email), slog.String("ip", ip), slog.Int("failures", account.Failures))
+
+		if user != nil {
+			err = app.sendUnlockEmail(user, until)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	address, err := app.models.LoginThrottles.RecordFailure(data.ThrottleKind.IP, ip, cfg.window)
+	if err != nil {
+		return err
+	}
+
+	if address.Failures >= cfg.ipMaxFailures && !address.IsLocked(time.Now()) {
+		err = app.models.LoginThrottles.Lock(address, until)
+		if err != nil {
+			return err
+		}
+
+		app.logger.Warn("IP address locked after too many failed logins", slog.String("ip", ip), slog.Int("failures", address.Failures))
+	}
+
+	return nil
+}
+
+// sendUnlockEmail warns the user that their account is locked and sends them a link to unlock it
+func (app *application) sendUnlockEmail(user *data.User, until time.Time) error {
+
+	err := app.models.Tokens.DeleteAllForUser(data.TokenScope.Unlock, user.ID)
+	if err != nil {
+		return err
+	}
+
+	token, err := app.models.Tokens.New(user.ID, unlockTokenTTL, data.TokenScope.Unlock)
+	if err != nil {
+		return err
+	}
+
+	app.background(func() {
+
+		mailData := map[string]any{
+			"username": user.Name,
+			"token":    token.Plaintext,
+			"until":    until.Format(time.RFC1123),
+		}
+
+		err := app.mailer.Send(user.Email, "account_locked.tmpl", mailData)
+		if err != nil {
+			app.logger.Error(err.Error())
+		}
+	})
+
+	return nil
+}
+
+// unlockAccountHandler lifts the lockout of an account with the link sent to its owner
+func (app *application) unlockAccountHandler(w http.ResponseWriter, r *http.Request) {
+
+	var input struct {
+		Token string `json:"token"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if data.ValidateTokenPlaintext(v, input.Token); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, err := app.models.Users.GetForToken(data.TokenScope.Unlock, input.Token)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("token", "invalid or expired unlock token")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.LoginThrottles.Reset(data.ThrottleKind.Account, strings.ToLower(user.Email))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.Tokens.DeleteAllForUser(data.TokenScope.Unlock, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	response := envelope{
+		"message": fmt.Sprintf("account of %s unlocked", user.Name),
+	}
+
+	err = app.writeJSON(w, http.StatusOK, response, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestIsTrustedProxy(t *testing.T) {
+
+	app := &application{}
+	app.config.login.trustedProxies = []netip.Prefix{
+		netip.MustParsePrefix("127.0.0.0/8"),
+		netip.MustParsePrefix("::1/128"),
+		netip.MustParsePrefix("10.0.0.0/24"),
+	}
+
+	tests := []struct {
+		name        string
+		remoteAddr  string
+		wantTrusted bool
+	}{
+		{name: "Loopback", remoteAddr: "127.0.0.1:52000", wantTrusted: true},
+		{name: "IPv6 loopback", remoteAddr: "[::1]:52000", wantTrusted: true},
+		{name: "IPv4-mapped IPv6", remoteAddr: "[::ffff:10.0.0.5]:52000", wantTrusted: true},
+		{name: "Trusted range", remoteAddr: "10.0.0.254:52000", wantTrusted: true},
+		{name: "Outside the range", remoteAddr: "10.0.1.1:52000"},
+		{name: "Public address", remoteAddr: "203.0.113.7:52000"},
+		{name: "Without port", remoteAddr: "127.0.0.1", wantTrusted: true},
+		{name: "Invalid", remoteAddr: "localhost:52000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/v1/tokens/authentication", nil)
+			r.RemoteAddr = tt.remoteAddr
+
+			if got := app.isTrustedProxy(r); got != tt.wantTrusted {
+				t.Errorf("isTrustedProxy() = %v, want %v", got, tt.wantTrusted)
+			}
+		})
+	}
+}
+
+func TestLoginSubjects(t *testing.T) {
+
+	app := &application{}
+	app.config.login.trustedProxies = []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		ip         string
+		wantIP     string
+	}{
+		{name: "Forwarded by a trusted proxy", remoteAddr: "127.0.0.1:52000", ip: "198.51.100.4", wantIP: "198.51.100.4"},
+		{name: "Not forwarded by a trusted proxy", remoteAddr: "127.0.0.1:52000", wantIP: "127.0.0.1"},
+		{name: "Given by an untrusted client", remoteAddr: "203.0.113.7:52000", ip: "198.51.100.4", wantIP: "203.0.113.7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/v1/tokens/authentication", nil)
+			r.RemoteAddr = tt.remoteAddr
+
+			email, ip := app.loginSubjects(r, "  Jane@Example.com ", tt.ip)
+			if email != "jane@example.com" {
+				t.Errorf("got email %q; want %q", email, "jane@example.com")
+			}
+			if ip != tt.wantIP {
+				t.Errorf("got IP address %q; want %q", ip, tt.wantIP)
+			}
+		})
+	}
+}
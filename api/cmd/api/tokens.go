@@ -5,6 +5,8 @@ import (
 	"ForumAPI/internal/validator"
 	"errors"
 	"fmt"
+	"github.com/tomasen/realip"
+	"log/slog"
 	"net/http"
 	"time"
 )
@@ -21,26 +23,50 @@ func (app *application) cleanExpiredTokens(frequency, timeout time.Duration) {
 		if err != nil {
 			app.logger.Error(err.Error())
 		}
+		err = app.models.Sessions.DeleteEmpty()
+		if err != nil {
+			app.logger.Error(err.Error())
+		}
+		err = app.models.LoginThrottles.DeleteExpired(app.config.login.window)
+		if err != nil {
+			app.logger.Error(err.Error())
+		}
 		time.Sleep(frequency)
 	}
 }
 
-func (app *application) newAuthenticationToken(user *data.User) (envelope, error) {
+// refreshReuseGracePeriod is the delay during which a refresh token used again is considered as sent twice by its
+// legitimate client (e.g. concurrent requests) rather than replayed by someone who stole it
+const refreshReuseGracePeriod = 10 * time.Second
+
+// newAuthenticationToken replaces the tokens of the session, leaving the other sessions (devices) of the user logged in.
+// parent is the refresh token exchanged for the new ones (nil when logging in, starting a new rotation family).
+// The used refresh tokens are kept until they expire to detect their reuse.
+func (app *application) newAuthenticationToken(user *data.User, session *data.Session, parent *data.Token) (envelope, error) {
 
-	// deleting all user's current tokens
-	err := app.models.Tokens.DeleteAllForUser("*", user.ID)
+	// starting a new rotation family
+	if parent == nil || parent.Family == "" {
+		family, err := data.NewTokenFamily()
+		if err != nil {
+			return nil, err
+		}
+		parent = &data.Token{Family: family}
+	}
+
+	// deleting the session's current authentication tokens
+	err := app.models.Tokens.DeleteAllForSession(data.TokenScope.Authentication, session.ID)
 	if err != nil {
 		return nil, err
 	}
 
 	// generating new authentication token
-	authToken, err := app.models.Tokens.New(user.ID, 24*time.Hour, data.TokenScope.Authentication)
+	authToken, err := app.models.Tokens.NewForSession(user.ID, session.ID, 24*time.Hour, data.TokenScope.Authentication, parent)
 	if err != nil {
 		return nil, err
 	}
 
 	// generating new refresh token
-	refreshToken, err := app.models.Tokens.New(user.ID, 48*time.Hour, data.TokenScope.Refresh)
+	refreshToken, err := app.models.Tokens.NewForSession(user.ID, session.ID, 48*time.Hour, data.TokenScope.Refresh, parent)
 	if err != nil {
 		return nil, err
 	}
@@ -134,8 +160,10 @@ func (app *application) getPublicKeyPEM(w http.ResponseWriter, r *http.Request)
 func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter, r *http.Request) {
 
 	var input struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
+		Email     string `json:"email"`
+		Password  string `json:"password"`
+		UserAgent string `json:"user_agent"`
+		IP        string `json:"ip"`
 	}
 
 	err := app.readJSON(w, r, &input)
@@ -154,10 +182,33 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 		return
 	}
 
+	email, ip := app.loginSubjects(r, input.Email, input.IP)
+
+	// slowing down the password guessing on the account or from the IP address
+	retryAfter, err := app.loginRetryAfter(email, ip)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if retryAfter > 0 {
+		app.tooManyLoginAttemptsResponse(w, r, retryAfter)
+		return
+	}
+
 	user, err := app.models.Users.GetByEmail(input.Email)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
+			// answering as slowly as for a wrong password, not to tell which email addresses have an account
+			data.DummyPasswordMatches(input.Password)
+
+			err = app.loginFailed(email, ip, nil)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+
 			app.invalidCredentialsResponse(w, r)
 		default:
 			app.serverErrorResponse(w, r, err)
@@ -172,11 +223,61 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 	}
 
 	if !match {
+		err = app.loginFailed(email, ip, user)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
 		app.invalidCredentialsResponse(w, r)
 		return
 	}
 
-	token, err := app.newAuthenticationToken(user)
+	if user.IsBlocked() {
+		app.suspendedAccountResponse(w, r, user)
+		return
+	}
+
+	userTOTP, err := app.models.TOTP.Get(user.ID)
+	if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// the users with two-factor authentication get a short-lived token to send along with their code
+	if userTOTP.IsEnabled() {
+		err = app.models.Tokens.DeleteAllForUser(data.TokenScope.MFAPending, user.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		mfaToken, err := app.models.Tokens.New(user.ID, mfaPendingTTL, data.TokenScope.MFAPending)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		err = app.writeJSON(w, http.StatusAccepted, envelope{"mfa_token": mfaToken}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.LoginThrottles.Reset(data.ThrottleKind.Account, email)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	session, err := app.newSession(r, user, input.UserAgent, input.IP)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	token, err := app.newAuthenticationToken(user, session, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -188,6 +289,155 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 	}
 }
 
+// createMFAAuthenticationTokenHandler is the second step of the login of the users with two-factor authentication,
+// exchanging the token obtained with their password and a code of their app (or a recovery code) for the authentication tokens
+func (app *application) createMFAAuthenticationTokenHandler(w http.ResponseWriter, r *http.Request) {
+
+	var input struct {
+		Token     string `json:"token"`
+		Code      string `json:"code"`
+		UserAgent string `json:"user_agent"`
+		IP        string `json:"ip"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	data.ValidateTokenPlaintext(v, input.Token)
+	v.Check(input.Code != "", "code", "must be provided")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, err := app.models.Users.GetForToken(data.TokenScope.MFAPending, input.Token)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("token", "invalid or expired two-factor token")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if user.IsBlocked() {
+		app.suspendedAccountResponse(w, r, user)
+		return
+	}
+
+	userTOTP, err := app.models.TOTP.Get(user.ID)
+	if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// the two-factor authentication was disabled in the meantime
+	if !userTOTP.IsEnabled() {
+		v.AddError("token", "invalid or expired two-factor token")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// the codes are guessed as slowly as the passwords
+	email, ip := app.loginSubjects(r, user.Email, input.IP)
+
+	retryAfter, err := app.loginRetryAfter(email, ip)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if retryAfter > 0 {
+		app.tooManyLoginAttemptsResponse(w, r, retryAfter)
+		return
+	}
+
+	valid, err := app.checkSecondFactor(userTOTP, input.Code)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !valid {
+		err = app.loginFailed(email, ip, user)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		v.AddError("code", "invalid code")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.LoginThrottles.Reset(data.ThrottleKind.Account, email)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.Tokens.DeleteAllForUser(data.TokenScope.MFAPending, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	session, err := app.newSession(r, user, input.UserAgent, input.IP)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	token, err := app.newAuthenticationToken(user, session, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, token, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// revokeTokenFamily logs out the session of a refresh token which was used twice and records the security event,
+// since either the user or the one who stole the token holds a token of the family
+func (app *application) revokeTokenFamily(r *http.Request, user *data.User, token *data.Token) error {
+
+	if token.SessionID != 0 {
+		err := app.models.Sessions.Delete(token.SessionID, user.ID)
+		if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+			return err
+		}
+	}
+
+	if token.Family != "" {
+		err := app.models.Tokens.DeleteFamily(token.Family)
+		if err != nil {
+			return err
+		}
+	}
+
+	app.logger.Warn("refresh token reused, token family revoked", slog.Int("user_id", user.ID), slog.Int("session_id", token.SessionID), slog.String("ip", realip.FromRequest(r)))
+
+	r = app.contextSetUser(r, user)
+	app.audit(r, data.AuditAction.TokenReuse, data.AuditEntity.User, user.ID, nil, envelope{
+		"session_id": token.SessionID,
+		"family":     token.Family,
+		"used_at":    token.UsedAt,
+	})
+
+	return nil
+}
+
 func (app *application) refreshAuthenticationTokenHandler(w http.ResponseWriter, r *http.Request) {
 
 	var input struct {
@@ -206,7 +456,24 @@ func (app *application) refreshAuthenticationTokenHandler(w http.ResponseWriter,
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
-			v.AddError("token", "invalid or expired activation token")
+			v.AddError("token", "invalid or expired refresh token")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if user.IsBlocked() {
+		app.suspendedAccountResponse(w, r, user)
+		return
+	}
+
+	refreshToken, err := app.models.Tokens.GetRefresh(input.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("token", "invalid or expired refresh token")
 			app.failedValidationResponse(w, r, v.Errors)
 		default:
 			app.serverErrorResponse(w, r, err)
@@ -214,7 +481,45 @@ func (app *application) refreshAuthenticationTokenHandler(w http.ResponseWriter,
 		return
 	}
 
-	token, err := app.newAuthenticationToken(user)
+	// each refresh token can be exchanged only once
+	unused, err := app.models.Tokens.MarkUsed(refreshToken)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !unused {
+		if refreshToken.UsedAt != nil && time.Since(*refreshToken.UsedAt) > refreshReuseGracePeriod {
+			err = app.revokeTokenFamily(r, user, refreshToken)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+		}
+
+		v.AddError("token", "refresh token already used")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	session := &data.Session{ID: refreshToken.SessionID}
+
+	// the tokens created before the sessions existed get a session of their own
+	if session.ID == 0 {
+		err = app.models.Tokens.DeleteUnboundForUser(user.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		session, err = app.newSession(r, user, "", "")
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	token, err := app.newAuthenticationToken(user, session, refreshToken)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -230,7 +535,13 @@ func (app *application) revokeTokensHandler(w http.ResponseWriter, r *http.Reque
 
 	user := app.contextGetUser(r)
 
-	err := app.models.Tokens.DeleteAllForUser("*", user.ID)
+	err := app.models.Sessions.DeleteAllForUser(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.Tokens.DeleteAllForUser("*", user.ID)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -0,0 +1,390 @@
+package main
+
+import (
+	"ForumAPI/internal/data"
+	"ForumAPI/internal/totp"
+	"ForumAPI/internal/validator"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	// totpIssuer is the name the authenticator apps show next to the codes
+	totpIssuer = "Threadive"
+	// totpQRCodeSize is the width in pixels of the enrolment QR code
+	totpQRCodeSize = 256
+	// mfaPendingTTL is how long the user has to type the code after giving the right password
+	mfaPendingTTL = 5 * time.Minute
+)
+
+// checkSecondFactor consumes the code of the user's authenticator app or one of their recovery codes,
+// each code being accepted only once
+func (app *application) checkSecondFactor(userTOTP *data.TOTP, code string) (bool, error) {
+
+	step, ok := totp.Validate(userTOTP.Secret, code, time.Now())
+	if ok {
+		return app.models.TOTP.UseStep(userTOTP.UserID, step)
+	}
+
+	return app.models.TOTP.UseRecoveryCode(userTOTP.UserID, code)
+}
+
+// verifySecondFactor checks the code the user gives to change their two-factor settings,
+// throttled like the login so that the codes can't be guessed with a stolen access token
+func (app *application) verifySecondFactor(w http.ResponseWriter, r *http.Request, user *data.User, userTOTP *data.TOTP, code string) bool {
+
+	email, ip := app.loginSubjects(r, user.Email, "")
+
+	retryAfter, err := app.loginRetryAfter(email, ip)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return false
+	}
+
+	if retryAfter > 0 {
+		app.tooManyLoginAttemptsResponse(w, r, retryAfter)
+		return false
+	}
+
+	valid, err := app.checkSecondFactor(userTOTP, code)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return false
+	}
+
+	if !valid {
+		err = app.loginFailed(email, ip, user)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return false
+		}
+
+		v := validator.New()
+		v.AddError("code", "invalid code")
+		app.failedValidationResponse(w, r, v.Errors)
+		return false
+	}
+
+	err = app.models.LoginThrottles.Reset(data.ThrottleKind.Account, email)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return false
+	}
+
+	return true
+}
+
+// readSelfIDParam reads the id in the path, only accepting the user's own id
+// (an admin may manage the users' accounts, but not enrol them)
+func (app *application) readSelfIDParam(w http.ResponseWriter, r *http.Request) (*data.User, bool) {
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return nil, false
+	}
+
+	user := app.contextGetUser(r)
+
+	if user.ID != id {
+		app.notPermittedResponse(w, r)
+		return nil, false
+	}
+
+	return user, true
+}
+
+func (app *application) getTOTPHandler(w http.ResponseWriter, r *http.Request) {
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	userTOTP, err := app.models.TOTP.Get(id)
+	if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	response := envelope{
+		"enabled": userTOTP.IsEnabled(),
+	}
+
+	if userTOTP.IsEnabled() {
+		count, err := app.models.TOTP.CountRecoveryCodes(id)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		response["confirmed_at"] = userTOTP.ConfirmedAt
+		response["recovery_codes_left"] = count
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"totp": response}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// enrollTOTPHandler generates a new secret for the user, to be scanned in an authenticator app and confirmed with a first code
+func (app *application) enrollTOTPHandler(w http.ResponseWriter, r *http.Request) {
+
+	user, ok := app.readSelfIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	userTOTP, err := app.models.TOTP.Get(user.ID)
+	if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if userTOTP.IsEnabled() {
+		v := validator.New()
+		v.AddError("totp", "two-factor authentication already enabled")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	uri := totp.URI(totpIssuer, user.Email, secret)
+
+	qrCode, err := totp.QRCode(uri, totpQRCodeSize)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.TOTP.Enroll(user.ID, secret)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	response := envelope{
+		"totp": envelope{
+			"secret":  secret,
+			"uri":     uri,
+			"qr_code": base64.StdEncoding.EncodeToString(qrCode),
+		},
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, response, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// confirmTOTPHandler enables the two-factor authentication with the first code of the app and returns the recovery codes
+func (app *application) confirmTOTPHandler(w http.ResponseWriter, r *http.Request) {
+
+	user, ok := app.readSelfIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Code string `json:"code"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	v.Check(input.Code != "", "code", "must be provided")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	userTOTP, err := app.models.TOTP.Get(user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if userTOTP.IsEnabled() {
+		v.AddError("totp", "two-factor authentication already enabled")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	step, ok := totp.Validate(userTOTP.Secret, input.Code, time.Now())
+	if !ok {
+		v.AddError("code", "invalid code")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.TOTP.Confirm(user.ID, step)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	codes, err := app.models.TOTP.NewRecoveryCodes(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.audit(r, data.AuditAction.EnableTOTP, data.AuditEntity.User, user.ID, nil, nil)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"recovery_codes": codes}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// regenerateRecoveryCodesHandler replaces the recovery codes of the user, proving they still hold the second factor
+func (app *application) regenerateRecoveryCodesHandler(w http.ResponseWriter, r *http.Request) {
+
+	user, ok := app.readSelfIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Code string `json:"code"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	v.Check(input.Code != "", "code", "must be provided")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	userTOTP, err := app.models.TOTP.Get(user.ID)
+	if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !userTOTP.IsEnabled() {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	if !app.verifySecondFactor(w, r, user, userTOTP, input.Code) {
+		return
+	}
+
+	codes, err := app.models.TOTP.NewRecoveryCodes(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"recovery_codes": codes}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// disableTOTPHandler turns the two-factor authentication off.
+// The users must prove they hold the second factor, an admin doesn't (to recover the account of a user who lost it).
+func (app *application) disableTOTPHandler(w http.ResponseWriter, r *http.Request) {
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	var input struct {
+		Code string `json:"code"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	userTOTP, err := app.models.TOTP.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if user.ID == id && userTOTP.IsEnabled() {
+		v := validator.New()
+
+		v.Check(input.Code != "", "code", "must be provided")
+
+		if !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		if !app.verifySecondFactor(w, r, user, userTOTP, input.Code) {
+			return
+		}
+	}
+
+	err = app.models.TOTP.Delete(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if userTOTP.IsEnabled() {
+		app.audit(r, data.AuditAction.DisableTOTP, data.AuditEntity.User, id, nil, nil)
+	}
+
+	response := envelope{
+		"message": fmt.Sprintf("two-factor authentication disabled for user %d", id),
+	}
+
+	err = app.writeJSON(w, http.StatusOK, response, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
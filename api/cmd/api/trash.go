@@ -0,0 +1,142 @@
+package main
+
+import (
+	"ForumAPI/internal/data"
+	"ForumAPI/internal/validator"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type trashForm struct {
+	Type string `form:"type"`
+	data.Filters
+	validator.Validator `form:"-"`
+}
+
+func (app *application) purgeTrash(frequency, timeout time.Duration) {
+	defer func() {
+		if err := recover(); err != nil {
+			app.logger.Error(fmt.Sprintf("%v", err))
+		}
+	}()
+	time.Sleep(timeout)
+	for {
+		err := app.models.Trash.Purge(app.config.trash.retention)
+		if err != nil {
+			app.logger.Error(err.Error())
+		}
+		time.Sleep(frequency)
+	}
+}
+
+func (app *application) getTrashHandler(w http.ResponseWriter, r *http.Request) {
+
+	form := newTrashForm()
+
+	err := app.decodeForm(r, &form)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if form.Page == 0 {
+		form.Page = 1
+	}
+	if form.PageSize == 0 {
+		form.PageSize = 50
+	}
+	if form.Sort == "" {
+		form.Sort = form.SortSafelist[0]
+	}
+
+	data.ValidateFilters(&form.Validator, form.Filters)
+
+	if form.Type != "" {
+		form.Check(validator.PermittedValue(form.Type, data.PermittedTrashTypes...), "type", fmt.Sprintf("incorrect value %s", form.Type))
+	}
+
+	if !form.Valid() {
+		err = app.writeJSON(w, http.StatusBadRequest, envelope{"errors": form.Errors}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	items, metadata, err := app.models.Trash.Get(form.Type, form.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"_metadata": metadata, "trash": items}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) restoreCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	app.restoreFromTrash(w, r, data.TrashType.Category, data.AuditEntity.Category)
+}
+
+func (app *application) restoreTagHandler(w http.ResponseWriter, r *http.Request) {
+	app.restoreFromTrash(w, r, data.TrashType.Tag, data.AuditEntity.Tag)
+}
+
+func (app *application) restoreThreadHandler(w http.ResponseWriter, r *http.Request) {
+	app.restoreFromTrash(w, r, data.TrashType.Thread, data.AuditEntity.Thread)
+}
+
+func (app *application) restorePostHandler(w http.ResponseWriter, r *http.Request) {
+	app.restoreFromTrash(w, r, data.TrashType.Post, data.AuditEntity.Post)
+}
+
+func (app *application) restoreFromTrash(w http.ResponseWriter, r *http.Request, itemType, entityType string) {
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	err = app.models.Trash.Restore(itemType, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrParentDeleted):
+			v := validator.New()
+			v.AddError("id", fmt.Sprintf("the %s belongs to an item which is still in the trash", itemType))
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var item any
+
+	switch itemType {
+	case data.TrashType.Category:
+		item, err = app.models.Categories.GetByID(id)
+	case data.TrashType.Tag:
+		item, err = app.models.Tags.GetByID(id)
+	case data.TrashType.Thread:
+		item, err = app.models.Threads.GetByID(id)
+	case data.TrashType.Post:
+		item, err = app.models.Posts.GetByID(id)
+	}
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.audit(r, data.AuditAction.Restore, entityType, id, nil, item)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{itemType: item}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
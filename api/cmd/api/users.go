@@ -11,6 +11,16 @@ import (
 	"time"
 )
 
+type getUsersForm struct {
+	Search        string `form:"q"`
+	Role          string `form:"role"`
+	Status        string `form:"status"`
+	CreatedAfter  string `form:"created_after"`
+	CreatedBefore string `form:"created_before"`
+	data.Filters
+	validator.Validator `form:"-"`
+}
+
 type userByIDForm struct {
 	ID                  int      `form:"-"`
 	Includes            []string `form:"includes[]"`
@@ -33,6 +43,21 @@ func (app *application) cleanExpiredUnactivatedUsers(frequency, timeout time.Dur
 	}
 }
 
+func (app *application) liftExpiredSuspensions(frequency time.Duration) {
+	defer func() {
+		if err := recover(); err != nil {
+			app.logger.Error(fmt.Sprintf("%v", err))
+		}
+	}()
+	for {
+		err := app.models.Users.LiftExpiredSuspensions()
+		if err != nil {
+			app.logger.Error(err.Error())
+		}
+		time.Sleep(frequency)
+	}
+}
+
 func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Request) {
 
 	var input struct {
@@ -81,6 +106,8 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	app.audit(r, data.AuditAction.Create, data.AuditEntity.User, user.ID, nil, user)
+
 	token, err := app.models.Tokens.New(user.ID, 3*24*time.Hour, data.TokenScope.Activation)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -147,6 +174,8 @@ func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	before := app.auditSnapshot(user)
+
 	err = app.models.Users.Activate(user)
 	if err != nil {
 		switch {
@@ -164,6 +193,8 @@ func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	app.audit(r, data.AuditAction.Activate, data.AuditEntity.User, user.ID, before, user)
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"user": user}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -172,9 +203,102 @@ func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 
 func (app *application) getUsersHandler(w http.ResponseWriter, r *http.Request) {
 
-	// TODO -> for administration purposes
+	form := newGetUsersForm()
+
+	err := app.decodeForm(r, &form)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if form.Page == 0 {
+		form.Page = 1
+	}
+	if form.PageSize == 0 {
+		form.PageSize = 20
+	}
+	if form.Sort == "" {
+		form.Sort = form.SortSafelist[0]
+	}
+
+	data.ValidateFilters(&form.Validator, form.Filters)
+
+	// email addresses are only searchable and disclosed for administrators
+	isAdmin := app.contextGetUser(r).Role == data.UserRole.Admin
+
+	userFilters := data.UserFilters{
+		SearchEmail: isAdmin,
+	}
+
+	if form.Role != "" {
+		form.Check(validator.PermittedValue(form.Role, data.UserRole.Admin, data.UserRole.Moderator, data.UserRole.Normal), "role", "invalid role value")
+		userFilters.Role = form.Role
+	}
+	if form.Status != "" {
+		form.Check(validator.PermittedValue(form.Status, data.UserStatus.Activated, data.UserStatus.ToConfirm, data.UserStatus.Blocked), "status", "invalid status value")
+		userFilters.Status = form.Status
+	}
+	if form.CreatedAfter != "" {
+		userFilters.CreatedAfter, err = time.Parse("2006-01-02", form.CreatedAfter)
+		if err != nil {
+			form.AddError("created_after", "must be a valid date in the format YYYY-MM-DD")
+		}
+	}
+	if form.CreatedBefore != "" {
+		userFilters.CreatedBefore, err = time.Parse("2006-01-02", form.CreatedBefore)
+		if err != nil {
+			form.AddError("created_before", "must be a valid date in the format YYYY-MM-DD")
+		}
+	}
+	if !userFilters.CreatedAfter.IsZero() && !userFilters.CreatedBefore.IsZero() {
+		form.Check(userFilters.CreatedAfter.Before(userFilters.CreatedBefore), "created_before", "must be after created_after")
+	}
+
+	if !form.Valid() {
+		err = app.writeJSON(w, http.StatusBadRequest, envelope{"errors": form.Errors}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	users, metadata, err := app.models.Users.Get(form.Search, userFilters, form.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !isAdmin {
+
+		// in the lists, the birth dates are only shown when public (the friendships are not checked)
+		ids := make([]int, len(users))
+		for i, user := range users {
+			ids[i] = user.ID
+		}
+		birthDates, err := app.models.Privacy.GetBirthDateVisibilities(ids)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		viewerID := app.contextGetUser(r).ID
+		publicUsers := make([]publicUser, len(users))
+		for i, user := range users {
+			user.Email = ""
+			if user.ID != viewerID && birthDates[user.ID] != data.Visibility.Public {
+				user.BirthDate = time.Time{}
+			}
+			publicUsers[i] = publicUser{User: user}
+		}
 
-	err := app.writeJSON(w, http.StatusOK, envelope{"users": "get_users"}, nil)
+		err = app.writeJSON(w, http.StatusOK, envelope{"_metadata": metadata, "users": publicUsers}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"_metadata": metadata, "users": users}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -217,7 +341,16 @@ func (app *application) getSingleUserHandler(w http.ResponseWriter, r *http.Requ
 		app.serverErrorResponse(w, r, err)
 		return
 	}
-	if slices.Contains(form.Includes, "following_tags") {
+
+	// the privacy settings of the user decide what the viewer may see
+	access, err := app.profileAccessFor(app.contextGetUser(r), user)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	access.hidePrivateFields(user)
+
+	if slices.Contains(form.Includes, "following_tags") && access.allows(access.privacy.FollowingTags) {
 		user.FollowingTags, err = app.models.Tags.GetByFollowingUserID(user.ID)
 		if err != nil {
 			if errors.Is(err, data.ErrRecordNotFound) {
@@ -228,7 +361,7 @@ func (app *application) getSingleUserHandler(w http.ResponseWriter, r *http.Requ
 			return
 		}
 	}
-	if slices.Contains(form.Includes, "favorite_threads") {
+	if slices.Contains(form.Includes, "favorite_threads") && access.allows(access.privacy.FavoriteThreads) {
 		user.FavoriteThreads, err = app.models.Threads.GetFavoriteThreadsByUserID(user.ID)
 		if err != nil {
 			if errors.Is(err, data.ErrRecordNotFound) {
@@ -283,7 +416,7 @@ func (app *application) getSingleUserHandler(w http.ResponseWriter, r *http.Requ
 			return
 		}
 	}
-	if slices.Contains(form.Includes, "reactions") {
+	if slices.Contains(form.Includes, "reactions") && access.allows(access.privacy.Reactions) {
 		err = app.models.Posts.GetReactionsByUser(user)
 		if err != nil {
 			if errors.Is(err, data.ErrRecordNotFound) {
@@ -295,7 +428,7 @@ func (app *application) getSingleUserHandler(w http.ResponseWriter, r *http.Requ
 		}
 	}
 	if slices.Contains(form.Includes, "friends") {
-		err = app.getFriendsByUser(user)
+		err = app.getFriendsByUser(user, access)
 		if err != nil {
 			if errors.Is(err, data.ErrRecordNotFound) {
 				app.notFoundResponse(w, r)
@@ -306,7 +439,7 @@ func (app *application) getSingleUserHandler(w http.ResponseWriter, r *http.Requ
 		}
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"user": user}, nil)
+	err = app.writeJSON(w, http.StatusOK, envelope{"user": access.view(user)}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -343,6 +476,8 @@ func (app *application) updateUserHandler(w http.ResponseWriter, r *http.Request
 		}
 	}
 
+	before := app.auditSnapshot(user)
+
 	var input struct {
 		Username             *string `json:"username"`
 		Email                *string `json:"email"`
@@ -418,6 +553,8 @@ func (app *application) updateUserHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	app.audit(r, data.AuditAction.Update, data.AuditEntity.User, user.ID, before, user)
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"user": "updated_user"}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -547,6 +684,8 @@ func (app *application) resetPasswordHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	app.audit(r, data.AuditAction.ResetPassword, data.AuditEntity.User, user.ID, nil, nil)
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"message": "your password has been updated successfully"}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -561,6 +700,27 @@ func (app *application) deleteUserHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	user, err := app.models.Users.GetByID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// the content is reassigned to the deleted user, so the original authorship is kept in the audit log
+	content, err := app.models.Users.GetAuthoredContent(id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	before := app.auditSnapshot(user)
+	before["authored_content"] = content
+
 	err = app.models.Tokens.DeleteAllForUser("*", id)
 	if err != nil {
 		switch {
@@ -583,6 +743,8 @@ func (app *application) deleteUserHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	app.audit(r, data.AuditAction.Delete, data.AuditEntity.User, id, before, nil)
+
 	response := envelope{
 		"message": fmt.Sprintf("user with id %d deleted", id),
 	}
@@ -592,3 +754,151 @@ func (app *application) deleteUserHandler(w http.ResponseWriter, r *http.Request
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+func (app *application) suspendUserHandler(w http.ResponseWriter, r *http.Request) {
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var input struct {
+		Reason *string    `json:"reason"`
+		Until  *time.Time `json:"until"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if input.Reason == nil {
+		v.AddError("reason", "must be provided")
+	} else {
+		v.StringCheck(*input.Reason, 5, 500, true, "reason")
+	}
+	if input.Until != nil {
+		v.Check(input.Until.After(time.Now()), "until", "must be in the future")
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, err := app.models.Users.GetByID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// only an administrator may suspend a member of the moderation, and nobody can suspend their own account
+	moderator := app.contextGetUser(r)
+	if user.ID == moderator.ID || (user.IsModerator() && moderator.Role != data.UserRole.Admin) {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	v.Check(user.IsActivated() || user.Status == data.UserStatus.Blocked, "user", "must be an activated user")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	before := app.auditSnapshot(user)
+
+	err = app.models.Users.Suspend(user.ID, *input.Reason, input.Until)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	user.Status = data.UserStatus.Blocked
+	user.SuspensionReason = *input.Reason
+	user.SuspendedUntil = input.Until
+
+	app.audit(r, data.AuditAction.Suspend, data.AuditEntity.User, user.ID, before, user)
+
+	// logging the user out of every device
+	err = app.models.Tokens.DeleteAllForUser("*", user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	response := envelope{
+		"message": fmt.Sprintf("suspended user with id %d", user.ID),
+		"suspension": envelope{
+			"reason": *input.Reason,
+			"until":  input.Until,
+		},
+	}
+
+	err = app.writeJSON(w, http.StatusOK, response, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) liftSuspensionHandler(w http.ResponseWriter, r *http.Request) {
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user, err := app.models.Users.GetByID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// only an administrator may lift the suspension of a member of the moderation, and nobody can lift their own
+	moderator := app.contextGetUser(r)
+	if user.ID == moderator.ID || (user.IsModerator() && moderator.Role != data.UserRole.Admin) {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	err = app.models.Users.LiftSuspension(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.audit(r, data.AuditAction.LiftSuspension, data.AuditEntity.User, id, envelope{"status": data.UserStatus.Blocked}, envelope{"status": data.UserStatus.Activated})
+
+	response := envelope{
+		"message": fmt.Sprintf("lifted the suspension of user with id %d", id),
+	}
+
+	err = app.writeJSON(w, http.StatusOK, response, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
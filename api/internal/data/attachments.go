@@ -0,0 +1,311 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// filesURL is the public URL under which the stored files are served
+var filesURL = "http://localhost:4000/v1/files"
+
+// SetFilesURL sets the public URL under which the stored files are served
+func SetFilesURL(url string) {
+	filesURL = strings.TrimSuffix(url, "/")
+}
+
+// FileURL returns the public URL of the file stored under the key
+func FileURL(key string) string {
+	return filesURL + "/" + key
+}
+
+// FileKey returns the key of the stored file served at the URL, if it is one of them
+func FileKey(url string) (string, bool) {
+
+	key, found := strings.CutPrefix(url, filesURL+"/")
+	if !found || key == "" {
+		return "", false
+	}
+
+	return key, true
+}
+
+// Attachment is a file uploaded to a post, images coming with a thumbnail
+type Attachment struct {
+	ID            int       `json:"id"`
+	PostID        int       `json:"post_id,omitempty"`
+	UploaderID    int       `json:"uploader_id,omitempty"`
+	Filename      string    `json:"filename"`
+	ContentType   string    `json:"content_type"`
+	Size          int64     `json:"size"`
+	URL           string    `json:"url"`
+	ThumbnailURL  string    `json:"thumbnail_url,omitempty"`
+	Width         int       `json:"width,omitempty"`
+	Height        int       `json:"height,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	StorageKey    string    `json:"-"`
+	ThumbnailKey  string    `json:"-"`
+	ThumbnailSize int64     `json:"-"`
+}
+
+// setURLs sets the public URLs of the attachment's files from their keys
+func (a *Attachment) setURLs() {
+
+	a.URL = FileURL(a.StorageKey)
+	if a.ThumbnailKey != "" {
+		a.ThumbnailURL = FileURL(a.ThumbnailKey)
+	}
+}
+
+type AttachmentModel struct {
+	DB *sql.DB
+}
+
+// attachmentColumns are the columns scanned by scanAttachment, for the attachments aliased a
+const attachmentColumns = `a.Id_attachments, COALESCE(a.Id_posts, 0), COALESCE(a.Id_users, 0), a.Filename, a.Content_type, a.Size, a.Storage_key, COALESCE(a.Thumbnail_key, ''), a.Thumbnail_size, COALESCE(a.Width, 0), COALESCE(a.Height, 0), a.Created_at`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAttachment(row scanner, a *Attachment) error {
+
+	err := row.Scan(
+		&a.ID,
+		&a.PostID,
+		&a.UploaderID,
+		&a.Filename,
+		&a.ContentType,
+		&a.Size,
+		&a.StorageKey,
+		&a.ThumbnailKey,
+		&a.ThumbnailSize,
+		&a.Width,
+		&a.Height,
+		&a.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	a.setURLs()
+
+	return nil
+}
+
+func (m AttachmentModel) Insert(a *Attachment) error {
+
+	query := `
+		INSERT INTO attachments (Id_posts, Id_users, Filename, Content_type, Size, Storage_key, Thumbnail_key, Thumbnail_size, Width, Height)
+		VALUES (?, ?, ?, ?, ?, ?, NULLIF(?, ''), ?, NULLIF(?, 0), NULLIF(?, 0));`
+
+	args := []any{a.PostID, a.UploaderID, a.Filename, a.ContentType, a.Size, a.StorageKey, a.ThumbnailKey, a.ThumbnailSize, a.Width, a.Height}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	res, err := m.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	a.ID = int(id)
+	a.CreatedAt = time.Now()
+	a.setURLs()
+
+	return nil
+}
+
+// GetByID returns the attachment along with the id of the author of its post (0 if it has none anymore)
+func (m AttachmentModel) GetByID(id int) (*Attachment, int, error) {
+
+	query := fmt.Sprintf(`
+		SELECT %s, COALESCE(p.Id_author, 0)
+		FROM attachments a
+		INNER JOIN posts p ON a.Id_posts = p.Id_posts
+		WHERE a.Id_attachments = ? AND p.Deleted_at IS NULL;`, attachmentColumns)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var a Attachment
+	var authorID int
+
+	row := m.DB.QueryRowContext(ctx, query, id)
+
+	err := row.Scan(
+		&a.ID,
+		&a.PostID,
+		&a.UploaderID,
+		&a.Filename,
+		&a.ContentType,
+		&a.Size,
+		&a.StorageKey,
+		&a.ThumbnailKey,
+		&a.ThumbnailSize,
+		&a.Width,
+		&a.Height,
+		&a.CreatedAt,
+		&authorID,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, 0, ErrRecordNotFound
+		default:
+			return nil, 0, err
+		}
+	}
+
+	a.setURLs()
+
+	return &a, authorID, nil
+}
+
+// UsedQuota returns the number of bytes stored for the attachments uploaded by the user, thumbnails included
+func (m AttachmentModel) UsedQuota(userID int) (int64, error) {
+
+	query := `
+		SELECT COALESCE(SUM(Size + Thumbnail_size), 0)
+		FROM attachments
+		WHERE Id_users = ?;`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var used int64
+	err := m.DB.QueryRowContext(ctx, query, userID).Scan(&used)
+
+	return used, err
+}
+
+// Detach unlinks the attachment from its post, leaving its files to be removed along with the other orphans
+func (m AttachmentModel) Detach(id int) error {
+
+	query := `
+		UPDATE attachments
+		SET Id_posts = NULL
+		WHERE Id_attachments = ?;`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	res, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetOrphans returns up to limit attachments which do not belong to any post anymore (detached or whose post was purged)
+func (m AttachmentModel) GetOrphans(limit int) ([]*Attachment, error) {
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM attachments a
+		WHERE a.Id_posts IS NULL
+		ORDER BY a.Id_attachments
+		LIMIT ?;`, attachmentColumns)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attachments []*Attachment
+
+	for rows.Next() {
+		var a Attachment
+		if err = scanAttachment(rows, &a); err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, &a)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
+
+// Delete removes the record of an orphan attachment, once its files are removed from the storage
+func (m AttachmentModel) Delete(id int) error {
+
+	query := `
+		DELETE FROM attachments
+		WHERE Id_attachments = ? AND Id_posts IS NULL;`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, id)
+
+	return err
+}
+
+// GetAttachments sets the attachments of the posts whose content is shown
+func (m PostModel) GetAttachments(posts []*Post) error {
+
+	var ids []any
+	byID := make(map[int]*Post, len(posts))
+
+	for _, post := range posts {
+		post.Attachments = nil
+		if post.Content == "" {
+			continue
+		}
+		ids = append(ids, post.ID)
+		byID[post.ID] = post
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM attachments a
+		WHERE a.Id_posts IN (%s)
+		ORDER BY a.Id_posts, a.Id_attachments;`, attachmentColumns, placeholders(len(ids)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, ids...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a Attachment
+		if err = scanAttachment(rows, &a); err != nil {
+			return err
+		}
+		byID[a.PostID].Attachments = append(byID[a.PostID].Attachments, &a)
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,280 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+type AuditEntry struct {
+	ID    int `json:"id"`
+	Actor struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	} `json:"actor"`
+	Client struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	} `json:"client"`
+	Action     string                 `json:"action"`
+	EntityType string                 `json:"entity_type"`
+	EntityID   int                    `json:"entity_id"`
+	Changes    map[string]AuditChange `json:"changes,omitempty"`
+	IP         string                 `json:"ip"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
+
+// AuditChange holds the value of a field before and after an action
+type AuditChange struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// AuditFilters is the set of structured filters available when reading the audit log
+type AuditFilters struct {
+	ActorID    int
+	EntityType string
+	EntityID   int
+	From       time.Time
+	To         time.Time
+}
+
+type auditAction struct {
+	Create         string
+	Update         string
+	Delete         string
+	Activate       string
+	ResetPassword  string
+	React          string
+	Unreact        string
+	Favorite       string
+	Unfavorite     string
+	Follow         string
+	Unfollow       string
+	FriendRequest  string
+	FriendResponse string
+	Unfriend       string
+	Resolve        string
+	Suspend        string
+	LiftSuspension string
+	Restore        string
+	TokenReuse     string
+	EnableTOTP     string
+	DisableTOTP    string
+	LinkIdentity   string
+}
+
+type auditEntity struct {
+	Category   string
+	Tag        string
+	Thread     string
+	Post       string
+	User       string
+	Report     string
+	Attachment string
+}
+
+var (
+	AuditAction = auditAction{
+		Create:         "create",
+		Update:         "update",
+		Delete:         "delete",
+		Activate:       "activate",
+		ResetPassword:  "reset_password",
+		React:          "react",
+		Unreact:        "unreact",
+		Favorite:       "favorite",
+		Unfavorite:     "unfavorite",
+		Follow:         "follow",
+		Unfollow:       "unfollow",
+		FriendRequest:  "friend_request",
+		FriendResponse: "friend_response",
+		Unfriend:       "unfriend",
+		Resolve:        "resolve",
+		Suspend:        "suspend",
+		LiftSuspension: "lift_suspension",
+		Restore:        "restore",
+		TokenReuse:     "token_reuse",
+		EnableTOTP:     "enable_totp",
+		DisableTOTP:    "disable_totp",
+		LinkIdentity:   "link_identity",
+	}
+	AuditEntity = auditEntity{
+		Category:   "category",
+		Tag:        "tag",
+		Thread:     "thread",
+		Post:       "post",
+		User:       "user",
+		Report:     "report",
+		Attachment: "attachment",
+	}
+	PermittedAuditEntities = []string{AuditEntity.Category, AuditEntity.Tag, AuditEntity.Thread, AuditEntity.Post, AuditEntity.User, AuditEntity.Report, AuditEntity.Attachment}
+)
+
+// AuditSnapshot returns the JSON representation of an entity as a map of its fields,
+// so that it can be compared later even if the entity itself is modified in the meantime
+func AuditSnapshot(entity any) (map[string]any, error) {
+
+	snapshot := make(map[string]any)
+
+	if entity == nil || (reflect.ValueOf(entity).Kind() == reflect.Pointer && reflect.ValueOf(entity).IsNil()) {
+		return snapshot, nil
+	}
+
+	js, err := json.Marshal(entity)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(js, &snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("cannot snapshot %T: %w", entity, err)
+	}
+
+	return snapshot, nil
+}
+
+// AuditDiff returns the fields that differ between two snapshots
+func AuditDiff(before, after map[string]any) map[string]AuditChange {
+
+	changes := make(map[string]AuditChange)
+
+	for field, value := range before {
+		if !reflect.DeepEqual(value, after[field]) {
+			changes[field] = AuditChange{Before: value, After: after[field]}
+		}
+	}
+	for field, value := range after {
+		if _, found := before[field]; !found {
+			changes[field] = AuditChange{Before: nil, After: value}
+		}
+	}
+
+	return changes
+}
+
+type AuditModel struct {
+	DB *sql.DB
+}
+
+func (m AuditModel) Insert(entry *AuditEntry) error {
+
+	changes, err := json.Marshal(entry.Changes)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO audit_log (Id_actor, Actor_name, Id_client, Client_name, Action, Entity_type, Id_entity, Changes, Ip)
+		VALUES (NULLIF(?, 0), NULLIF(?, ''), NULLIF(?, 0), NULLIF(?, ''), ?, ?, ?, ?, ?);`
+
+	args := []any{entry.Actor.ID, entry.Actor.Name, entry.Client.ID, entry.Client.Name, entry.Action, entry.EntityType, entry.EntityID, changes, entry.IP}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	res, err := m.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	entry.ID = int(id)
+
+	return nil
+}
+
+func (m AuditModel) Get(auditFilters AuditFilters, filters Filters) ([]*AuditEntry, Metadata, error) {
+
+	var conditions string
+	var args []any
+
+	if auditFilters.ActorID != 0 {
+		conditions += " AND Id_actor = ?"
+		args = append(args, auditFilters.ActorID)
+	}
+	if auditFilters.EntityType != "" {
+		conditions += " AND Entity_type = ?"
+		args = append(args, auditFilters.EntityType)
+	}
+	if auditFilters.EntityID != 0 {
+		conditions += " AND Id_entity = ?"
+		args = append(args, auditFilters.EntityID)
+	}
+	if !auditFilters.From.IsZero() {
+		conditions += " AND Created_at >= ?"
+		args = append(args, auditFilters.From)
+	}
+	if !auditFilters.To.IsZero() {
+		conditions += " AND Created_at < ?"
+		args = append(args, auditFilters.To)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), Id_audit_log, COALESCE(Id_actor, 0), COALESCE(Actor_name, ''), COALESCE(Id_client, 0), COALESCE(Client_name, ''), Action, Entity_type, Id_entity, Changes, Ip, Created_at
+		FROM audit_log
+		WHERE 1 = 1%s
+		ORDER BY %s %s, Id_audit_log DESC
+		LIMIT ? OFFSET ?;`, conditions, filters.sortColumn(), filters.sortDirection())
+
+	args = append(args, filters.limit(), filters.offset())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	var totalRecords int
+	var entries []*AuditEntry
+
+	for rows.Next() {
+		var entry AuditEntry
+		var changes []byte
+
+		err = rows.Scan(
+			&totalRecords,
+			&entry.ID,
+			&entry.Actor.ID,
+			&entry.Actor.Name,
+			&entry.Client.ID,
+			&entry.Client.Name,
+			&entry.Action,
+			&entry.EntityType,
+			&entry.EntityID,
+			&changes,
+			&entry.IP,
+			&entry.CreatedAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		if len(changes) > 0 {
+			err = json.Unmarshal(changes, &entry.Changes)
+			if err != nil {
+				return nil, Metadata{}, err
+			}
+		}
+
+		entries = append(entries, &entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return entries, metadata, nil
+}
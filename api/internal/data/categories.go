@@ -99,7 +99,7 @@ func (m CategoryModel) Insert(category *Category) error {
 		SELECT c.Created_at, pc.Name, c.Version
 		FROM categories c
 		LEFT JOIN categories pc ON c.Id_parent_categories = pc.Id_categories
-		WHERE c.Id_categories = ?;`
+		WHERE c.Id_categories = ? AND pc.Deleted_at IS NULL;`
 
 	var parentCategoryName sql.NullString
 
@@ -132,16 +132,23 @@ func (m CategoryModel) Get(search string, filters Filters) ([]*Category, Metadat
 		search = "%"
 	}
 
+	keyset, keysetArgs, err := filters.keyset("c", "c.Id_categories")
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
 	query := fmt.Sprintf(`
-		SELECT count(*) OVER(), c.Id_categories, c.Name, c.Id_author, u.Username, c.Id_parent_categories, pc.Name, c.Created_at, c.Updated_at, c.Version
+		SELECT %s, c.Id_categories, c.Name, c.Id_author, u.Username, c.Id_parent_categories, pc.Name, c.Created_at, c.Updated_at, c.Version, %s
 		FROM categories c
 		INNER JOIN users u ON u.Id_users = c.Id_author
 		LEFT OUTER JOIN categories pc ON pc.Id_categories = c.Id_parent_categories
-		WHERE c.Name LIKE ?
-		ORDER BY %s %s, Id_Categories ASC
-		LIMIT ? OFFSET ?;`, filters.sortColumn(), filters.sortDirection())
+		WHERE c.Name LIKE ? AND c.Deleted_at IS NULL%s
+		ORDER BY %s %s, Id_Categories %s
+		LIMIT ? OFFSET ?;`, filters.countColumn(), filters.sortKey("c"), keyset, filters.sortKey("c"), filters.sortDirection(), filters.idDirection())
 
-	args := []any{search, filters.limit(), filters.offset()}
+	args := []any{search}
+	args = append(args, keysetArgs...)
+	args = append(args, filters.limit(), filters.offset())
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -155,12 +162,14 @@ func (m CategoryModel) Get(search string, filters Filters) ([]*Category, Metadat
 
 	var totalRecords int
 	var categories []*Category
+	var cursors []Cursor
 
 	for rows.Next() {
 
 		var parentID sql.NullInt64
 		var parentName sql.NullString
 		var category Category
+		var sortKey any
 
 		err = rows.Scan(
 			&totalRecords,
@@ -173,6 +182,7 @@ func (m CategoryModel) Get(search string, filters Filters) ([]*Category, Metadat
 			&category.CreatedAt,
 			&category.UpdatedAt,
 			&category.Version,
+			&sortKey,
 		)
 		if err != nil {
 			return nil, Metadata{}, err
@@ -186,13 +196,14 @@ func (m CategoryModel) Get(search string, filters Filters) ([]*Category, Metadat
 		}
 
 		categories = append(categories, &category)
+		cursors = append(cursors, newCursor(filters.Sort, sortKey, category.ID))
 	}
 
 	if err = rows.Err(); err != nil {
 		return nil, Metadata{}, err
 	}
 
-	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	categories, metadata := paginate(categories, cursors, totalRecords, filters)
 
 	return categories, metadata, nil
 }
@@ -204,7 +215,7 @@ func (m CategoryModel) GetByID(id int) (*Category, error) {
 		FROM categories c
 		INNER JOIN users u ON u.Id_users = c.Id_author
 		LEFT OUTER JOIN categories pc ON pc.Id_categories = c.Id_parent_categories
-		WHERE c.Id_categories = ?;`
+		WHERE c.Id_categories = ? AND c.Deleted_at IS NULL;`
 
 	var category Category
 
@@ -251,7 +262,7 @@ func (m CategoryModel) GetByParentID(id int) ([]Category, error) {
 		SELECT c.Id_categories, c.Name, c.Id_author, u.Username, c.Created_at, c.Updated_at
 		FROM categories c
 		INNER JOIN users u ON u.Id_users = c.Id_author
-		WHERE c.Id_parent_categories = ?;`
+		WHERE c.Id_parent_categories = ? AND c.Deleted_at IS NULL;`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -293,7 +304,7 @@ func (m CategoryModel) GetOwnedCategoriesByUserID(id int) ([]Category, error) {
 	query := `
 		SELECT Id_categories, Name, Created_at, Updated_at
 		FROM categories
-		WHERE Id_author = ?;`
+		WHERE Id_author = ? AND Deleted_at IS NULL;`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -335,7 +346,7 @@ func (m CategoryModel) Update(category *Category) error {
 	query := `
 		UPDATE categories 
 		SET Name = ?, Id_author= ?, Id_parent_categories = ?, Version = Version + 1
-		WHERE Id_categories = ? AND Version = ?;`
+		WHERE Id_categories = ? AND Version = ? AND Deleted_at IS NULL;`
 
 	args := []any{category.Name, category.Author.ID, category.ParentCategory.ID, category.ID, category.Version}
 
@@ -392,16 +403,38 @@ func (m CategoryModel) Update(category *Category) error {
 	return nil
 }
 
-func (m CategoryModel) Delete(id int) error {
+// Delete moves the category to the trash, provided it has no threads nor subcategories left
+func (m CategoryModel) Delete(id, userID int) error {
 
 	query := `
-		DELETE FROM categories
-		WHERE Id_categories = ?;`
+		SELECT EXISTS (SELECT 1 FROM threads WHERE Id_categories = ? AND Deleted_at IS NULL)
+			OR EXISTS (SELECT 1 FROM categories WHERE Id_parent_categories = ? AND Deleted_at IS NULL);`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	result, err := m.DB.ExecContext(ctx, query, id)
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var notEmpty bool
+
+	err = tx.QueryRowContext(ctx, query, id, id).Scan(&notEmpty)
+	if err != nil {
+		return err
+	}
+	if notEmpty {
+		return ErrNotEmpty
+	}
+
+	query = `
+		UPDATE categories
+		SET Deleted_at = NOW(), Deleted_by = ?
+		WHERE Id_categories = ? AND Deleted_at IS NULL;`
+
+	result, err := tx.ExecContext(ctx, query, userID, id)
 	if err != nil {
 		return err
 	}
@@ -415,5 +448,5 @@ func (m CategoryModel) Delete(id int) error {
 		return ErrRecordNotFound
 	}
 
-	return nil
+	return tx.Commit()
 }
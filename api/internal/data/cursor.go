@@ -0,0 +1,102 @@
+package data
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// cursorKey signs the pagination cursors
+var cursorKey = newSigningKey()
+
+func SetCursorKey(key string) {
+	cursorKey.set(key)
+}
+
+// Cursor is the position of an item in a list sorted by Sort: the item's sort key and its id
+type Cursor struct {
+	Sort  string  `json:"s"`
+	Value *string `json:"v"`
+	ID    int     `json:"i"`
+}
+
+// newCursor creates the cursor from the sort key scanned from the database
+func newCursor(sort string, value any, id int) Cursor {
+
+	cursor := Cursor{
+		Sort: sort,
+		ID:   id,
+	}
+
+	var key string
+	switch value := value.(type) {
+	case nil:
+		return cursor
+	case time.Time:
+		key = value.Format("2006-01-02 15:04:05.999999")
+	case []byte:
+		key = string(value)
+	case string:
+		key = value
+	case int64:
+		key = strconv.FormatInt(value, 10)
+	case bool:
+		key = "0"
+		if value {
+			key = "1"
+		}
+	default:
+		key = fmt.Sprint(value)
+	}
+	cursor.Value = &key
+
+	return cursor
+}
+
+// Encode returns the opaque and signed representation of the cursor
+func (c Cursor) Encode() string {
+
+	payload, err := json.Marshal(c)
+	if err != nil {
+		panic(err)
+	}
+
+	return fmt.Sprintf("%s.%s", base64.RawURLEncoding.EncodeToString(payload), base64.RawURLEncoding.EncodeToString(cursorKey.sign(payload)))
+}
+
+// DecodeCursor checks the cursor's signature and returns the cursor
+func DecodeCursor(encoded string) (Cursor, error) {
+
+	var cursor Cursor
+
+	payloadPart, signaturePart, ok := strings.Cut(encoded, ".")
+	if !ok {
+		return cursor, ErrInvalidCursor
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(payloadPart)
+	if err != nil {
+		return cursor, ErrInvalidCursor
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(signaturePart)
+	if err != nil {
+		return cursor, ErrInvalidCursor
+	}
+
+	if !cursorKey.verify(payload, signature) {
+		return cursor, ErrInvalidCursor
+	}
+
+	err = json.Unmarshal(payload, &cursor)
+	if err != nil {
+		return cursor, ErrInvalidCursor
+	}
+
+	return cursor, nil
+}
@@ -0,0 +1,110 @@
+package data
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewCursor(t *testing.T) {
+
+	createdAt := time.Date(2024, 5, 25, 20, 7, 34, 120000000, time.UTC)
+
+	tests := []struct {
+		name      string
+		value     any
+		wantValue *string
+	}{
+		{name: "NULL", value: nil},
+		{name: "Time", value: createdAt, wantValue: ptr("2024-05-25 20:07:34.12")},
+		{name: "Bytes", value: []byte("golang"), wantValue: ptr("golang")},
+		{name: "String", value: "golang", wantValue: ptr("golang")},
+		{name: "Integer", value: int64(42), wantValue: ptr("42")},
+		{name: "Boolean", value: true, wantValue: ptr("1")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor := newCursor("Created_at", tt.value, 7)
+
+			if cursor.Sort != "Created_at" || cursor.ID != 7 {
+				t.Errorf("got sort %q and id %d; want Created_at and 7", cursor.Sort, cursor.ID)
+			}
+			switch {
+			case tt.wantValue == nil && cursor.Value != nil:
+				t.Errorf("got value %q; want NULL", *cursor.Value)
+			case tt.wantValue != nil && (cursor.Value == nil || *cursor.Value != *tt.wantValue):
+				t.Errorf("got value %v; want %q", cursor.Value, *tt.wantValue)
+			}
+		})
+	}
+}
+
+func TestDecodeCursor(t *testing.T) {
+
+	SetCursorKey("secret")
+
+	cursor := Cursor{Sort: "-Created_at", Value: ptr("2024-05-25 20:07:34"), ID: 7}
+	encoded := cursor.Encode()
+
+	t.Run("Round trip", func(t *testing.T) {
+		decoded, err := DecodeCursor(encoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded.Sort != cursor.Sort || decoded.ID != cursor.ID || decoded.Value == nil || *decoded.Value != *cursor.Value {
+			t.Errorf("got %+v; want %+v", decoded, cursor)
+		}
+	})
+
+	t.Run("NULL value", func(t *testing.T) {
+		decoded, err := DecodeCursor(Cursor{Sort: "Updated_at", ID: 3}.Encode())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded.Value != nil {
+			t.Errorf("got value %q; want NULL", *decoded.Value)
+		}
+	})
+
+	payload, signature, _ := strings.Cut(encoded, ".")
+	forged := base64.RawURLEncoding.EncodeToString([]byte(`{"s":"-Created_at","v":"2024-05-25 20:07:34","i":8}`))
+	wrongType := base64.RawURLEncoding.EncodeToString([]byte(`{"s":"-Created_at","v":42,"i":7}`))
+
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{name: "Tampered payload", encoded: forged + "." + signature},
+		{name: "Tampered signature", encoded: payload + "." + strings.Repeat("A", len(signature))},
+		{name: "Sort value of another type", encoded: wrongType + "." + base64.RawURLEncoding.EncodeToString(cursorKey.sign([]byte(`{"s":"-Created_at","v":42,"i":7}`)))},
+		{name: "No signature", encoded: payload},
+		{name: "Invalid encoding", encoded: "!." + signature},
+		{name: "Empty", encoded: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecodeCursor(tt.encoded)
+			if !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("got %v; want %v", err, ErrInvalidCursor)
+			}
+		})
+	}
+
+	t.Run("Other key", func(t *testing.T) {
+		SetCursorKey("other")
+		defer SetCursorKey("secret")
+
+		_, err := DecodeCursor(encoded)
+		if !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("got %v; want %v", err, ErrInvalidCursor)
+		}
+	})
+}
+
+func ptr(s string) *string {
+	return &s
+}
@@ -2,9 +2,22 @@ package data
 
 import (
 	"ForumAPI/internal/validator"
+	"fmt"
+	"slices"
 	"strings"
 )
 
+type tagMatch struct {
+	Any string
+	All string
+}
+
+// TagMatch tells whether the items must have any or all of the filtered tags
+var TagMatch = tagMatch{
+	Any: "any",
+	All: "all",
+}
+
 // Filters is the set of filter related information
 type Filters struct {
 
@@ -19,6 +32,22 @@ type Filters struct {
 
 	// SortSafelist is the list of all possible fields for sorting (not in the query string)
 	SortSafelist []string `form:"-"`
+
+	// Cursor enables the cursor pagination from the first item (instead of Page)
+	Cursor bool `form:"cursor"`
+
+	// After is the cursor of the item after which the next items are wanted (next_cursor)
+	After string `form:"after"`
+
+	// Before is the cursor of the item before which the previous items are wanted (prev_cursor)
+	Before string `form:"before"`
+
+	// SkipCount disables the count of all the records (total_records and last_page).
+	// With a cursor, total_records only counts the items from the cursor on, in its direction.
+	SkipCount bool `form:"skip_count"`
+
+	// Cursorable tells whether the list supports the cursor pagination (not in the query string)
+	Cursorable bool `form:"-"`
 }
 
 func (f Filters) sortColumn() string {
@@ -31,22 +60,103 @@ func (f Filters) sortColumn() string {
 	panic("unsafe sort parameter: " + f.Sort)
 }
 
+// sortDirection returns the sort direction, reversed when going backwards with a cursor
 func (f Filters) sortDirection() string {
-	if strings.HasPrefix(f.Sort, "-") {
+	if strings.HasPrefix(f.Sort, "-") != f.isBackward() {
+		return "DESC"
+	}
+
+	return "ASC"
+}
+
+// idDirection returns the direction of the id used as a tiebreaker
+func (f Filters) idDirection() string {
+	if f.isBackward() {
 		return "DESC"
 	}
 
 	return "ASC"
 }
 
+func (f Filters) isCursor() bool {
+	return f.Cursor || f.After != "" || f.Before != ""
+}
+
+func (f Filters) isBackward() bool {
+	return f.Before != ""
+}
+
 func (f Filters) limit() int {
+
+	// fetching one more item to know whether there is a next page
+	if f.isCursor() {
+		return f.PageSize + 1
+	}
+
 	return f.PageSize
 }
 
 func (f Filters) offset() int {
+	if f.isCursor() {
+		return 0
+	}
+
 	return (f.Page - 1) * f.PageSize
 }
 
+// countColumn returns the select expression of the total count, unless the client asked to skip it
+func (f Filters) countColumn() string {
+	if f.SkipCount {
+		return "0"
+	}
+
+	return "count(*) OVER()"
+}
+
+// sortKey returns the sort column qualified with the table alias, to be selected for the cursors
+func (f Filters) sortKey(alias string) string {
+	return fmt.Sprintf("%s.%s", alias, f.sortColumn())
+}
+
+// keyset returns the condition selecting the items after (or before) the cursor and its arguments,
+// or ErrInvalidCursor if the cursor doesn't belong to the list's sort.
+// MySQL puts the NULL values first in ascending order and last in descending order.
+func (f Filters) keyset(alias, idColumn string) (string, []any, error) {
+
+	encoded := f.After
+	if f.isBackward() {
+		encoded = f.Before
+	}
+	if encoded == "" {
+		return "", nil, nil
+	}
+
+	cursor, err := DecodeCursor(encoded)
+	if err != nil {
+		return "", nil, err
+	}
+	if cursor.Sort != f.Sort {
+		return "", nil, ErrInvalidCursor
+	}
+
+	column := f.sortKey(alias)
+	idOperator := ">"
+	if f.isBackward() {
+		idOperator = "<"
+	}
+
+	switch {
+	case cursor.Value == nil && f.sortDirection() == "ASC":
+		return fmt.Sprintf(" AND (%s IS NOT NULL OR %s %s ?)", column, idColumn, idOperator), []any{cursor.ID}, nil
+	case cursor.Value == nil:
+		return fmt.Sprintf(" AND (%s IS NULL AND %s %s ?)", column, idColumn, idOperator), []any{cursor.ID}, nil
+	case f.sortDirection() == "ASC":
+		return fmt.Sprintf(" AND (%[1]s > ? OR (%[1]s = ? AND %[2]s %[3]s ?))", column, idColumn, idOperator), []any{*cursor.Value, *cursor.Value, cursor.ID}, nil
+	default:
+		return fmt.Sprintf(" AND (%[1]s < ? OR %[1]s IS NULL OR (%[1]s = ? AND %[2]s %[3]s ?))", column, idColumn, idOperator), []any{*cursor.Value, *cursor.Value, cursor.ID}, nil
+	}
+}
+
 func ValidateFilters(v *validator.Validator, f Filters) {
 	v.Check(f.Page > 0, "page", "must be greater than zero")
 	v.Check(f.Page <= 10_000_000, "page", "must be a maximum of 10 million")
@@ -54,14 +164,32 @@ func ValidateFilters(v *validator.Validator, f Filters) {
 	v.Check(f.PageSize <= 100, "page_size", "must be a maximum of 100")
 
 	v.Check(validator.PermittedValue(f.Sort, f.SortSafelist...), "sort", "invalid sort value")
+
+	if !f.Cursorable {
+		v.Check(!f.isCursor(), "cursor", "cursor pagination not available for this list")
+		return
+	}
+
+	v.Check(f.After == "" || f.Before == "", "cursor", "must not use after and before together")
+
+	for key, encoded := range map[string]string{"after": f.After, "before": f.Before} {
+		if encoded == "" {
+			continue
+		}
+		cursor, err := DecodeCursor(encoded)
+		v.Check(err == nil, key, "invalid cursor")
+		v.Check(err != nil || cursor.Sort == f.Sort, key, "cursor created with another sort value")
+	}
 }
 
 type Metadata struct {
-	CurrentPage  int `json:"current_page,omitempty"`
-	PageSize     int `json:"page_size,omitempty"`
-	FirstPage    int `json:"first_page,omitempty"`
-	LastPage     int `json:"last_page,omitempty"`
-	TotalRecords int `json:"total_records,omitempty"`
+	CurrentPage  int    `json:"current_page,omitempty"`
+	PageSize     int    `json:"page_size,omitempty"`
+	FirstPage    int    `json:"first_page,omitempty"`
+	LastPage     int    `json:"last_page,omitempty"`
+	TotalRecords int    `json:"total_records,omitempty"` // from the cursor on in cursor mode
+	NextCursor   string `json:"next_cursor,omitempty"`
+	PrevCursor   string `json:"prev_cursor,omitempty"`
 }
 
 func calculateMetadata(totalRecords, page, pageSize int) Metadata {
@@ -77,3 +205,91 @@ func calculateMetadata(totalRecords, page, pageSize int) Metadata {
 		TotalRecords: totalRecords,
 	}
 }
+
+// paginate returns the page of items and its metadata, the cursors being built from the items' sort keys.
+// In cursor mode, it drops the extra item fetched to know whether there is a next page
+// and puts back in order the items fetched backwards.
+func paginate[T any](items []T, cursors []Cursor, totalRecords int, filters Filters) ([]T, Metadata) {
+
+	if !filters.isCursor() {
+		if filters.SkipCount {
+			if len(items) == 0 {
+				return items, Metadata{}
+			}
+			return items, Metadata{
+				CurrentPage: filters.Page,
+				PageSize:    filters.PageSize,
+				FirstPage:   1,
+			}
+		}
+		return items, calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	}
+
+	hasMore := len(items) > filters.PageSize
+	if hasMore {
+		items = items[:filters.PageSize]
+		cursors = cursors[:filters.PageSize]
+	}
+
+	if filters.isBackward() {
+		slices.Reverse(items)
+		slices.Reverse(cursors)
+	}
+
+	metadata := Metadata{
+		PageSize:     filters.PageSize,
+		TotalRecords: totalRecords,
+	}
+
+	if len(items) == 0 {
+		return items, metadata
+	}
+
+	// going forward, there are previous items if we came from a cursor;
+	// going backwards, there are always next items (the ones we came from)
+	if (!filters.isBackward() && hasMore) || filters.isBackward() {
+		metadata.NextCursor = cursors[len(cursors)-1].Encode()
+	}
+	if (filters.isBackward() && hasMore) || filters.After != "" {
+		metadata.PrevCursor = cursors[0].Encode()
+	}
+
+	return items, metadata
+}
+
+// categoryCondition returns the condition restricting the category column to the category,
+// or to the category and all its subcategories (at any depth) when descendants is set
+func categoryCondition(column string, categoryID int, descendants bool) (string, []any) {
+
+	if !descendants {
+		return fmt.Sprintf(" AND %s = ?", column), []any{categoryID}
+	}
+
+	return fmt.Sprintf(` AND %s IN (
+			WITH RECURSIVE subcategories (Id) AS (
+				SELECT ?
+				UNION ALL
+				SELECT c.Id_categories FROM categories c INNER JOIN subcategories s ON c.Id_parent_categories = s.Id
+			)
+			SELECT Id FROM subcategories)`, column), []any{categoryID}
+}
+
+// tagsCondition returns the condition keeping the threads (identified by the thread column)
+// having any of the tags, or all of them when all is set
+func tagsCondition(threadColumn string, tagIDs []int, all bool) (string, []any) {
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(tagIDs)), ", ")
+
+	args := make([]any, 0, len(tagIDs)+1)
+	for _, id := range tagIDs {
+		args = append(args, id)
+	}
+
+	if !all {
+		return fmt.Sprintf(" AND EXISTS (SELECT 1 FROM threads_tags tt WHERE tt.Id_threads = %s AND tt.Id_tags IN (%s))", threadColumn, placeholders), args
+	}
+
+	args = append(args, len(tagIDs))
+
+	return fmt.Sprintf(" AND (SELECT COUNT(DISTINCT tt.Id_tags) FROM threads_tags tt WHERE tt.Id_threads = %s AND tt.Id_tags IN (%s)) = ?", threadColumn, placeholders), args
+}
@@ -0,0 +1,240 @@
+package data
+
+import (
+	"ForumAPI/internal/validator"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+var testSortSafelist = []string{"Created_at", "-Created_at"}
+
+func TestFiltersKeyset(t *testing.T) {
+
+	SetCursorKey("secret")
+
+	value := ptr("2024-05-25 20:07:34")
+
+	tests := []struct {
+		name          string
+		filters       Filters
+		wantCondition string
+		wantArgs      []any
+		wantOrder     string
+	}{
+		{
+			name:      "First page",
+			filters:   Filters{Sort: "Created_at", Cursor: true},
+			wantOrder: "ASC ASC",
+		},
+		{
+			name:          "Forward ascending",
+			filters:       Filters{Sort: "Created_at", After: Cursor{Sort: "Created_at", Value: value, ID: 7}.Encode()},
+			wantCondition: " AND (t.Created_at > ? OR (t.Created_at = ? AND t.Id_threads > ?))",
+			wantArgs:      []any{*value, *value, 7},
+			wantOrder:     "ASC ASC",
+		},
+		{
+			name:          "Forward descending",
+			filters:       Filters{Sort: "-Created_at", After: Cursor{Sort: "-Created_at", Value: value, ID: 7}.Encode()},
+			wantCondition: " AND (t.Created_at < ? OR t.Created_at IS NULL OR (t.Created_at = ? AND t.Id_threads > ?))",
+			wantArgs:      []any{*value, *value, 7},
+			wantOrder:     "DESC ASC",
+		},
+		{
+			name:          "Backward ascending",
+			filters:       Filters{Sort: "Created_at", Before: Cursor{Sort: "Created_at", Value: value, ID: 7}.Encode()},
+			wantCondition: " AND (t.Created_at < ? OR t.Created_at IS NULL OR (t.Created_at = ? AND t.Id_threads < ?))",
+			wantArgs:      []any{*value, *value, 7},
+			wantOrder:     "DESC DESC",
+		},
+		{
+			name:          "Backward descending",
+			filters:       Filters{Sort: "-Created_at", Before: Cursor{Sort: "-Created_at", Value: value, ID: 7}.Encode()},
+			wantCondition: " AND (t.Created_at > ? OR (t.Created_at = ? AND t.Id_threads < ?))",
+			wantArgs:      []any{*value, *value, 7},
+			wantOrder:     "ASC DESC",
+		},
+		{
+			// the NULL values come first in ascending order: the non-NULL ones and the next NULL ones follow
+			name:          "Forward ascending from NULL",
+			filters:       Filters{Sort: "Created_at", After: Cursor{Sort: "Created_at", ID: 7}.Encode()},
+			wantCondition: " AND (t.Created_at IS NOT NULL OR t.Id_threads > ?)",
+			wantArgs:      []any{7},
+			wantOrder:     "ASC ASC",
+		},
+		{
+			// the NULL values come last in descending order: only the next NULL ones follow
+			name:          "Forward descending from NULL",
+			filters:       Filters{Sort: "-Created_at", After: Cursor{Sort: "-Created_at", ID: 7}.Encode()},
+			wantCondition: " AND (t.Created_at IS NULL AND t.Id_threads > ?)",
+			wantArgs:      []any{7},
+			wantOrder:     "DESC ASC",
+		},
+		{
+			name:          "Backward ascending from NULL",
+			filters:       Filters{Sort: "Created_at", Before: Cursor{Sort: "Created_at", ID: 7}.Encode()},
+			wantCondition: " AND (t.Created_at IS NULL AND t.Id_threads < ?)",
+			wantArgs:      []any{7},
+			wantOrder:     "DESC DESC",
+		},
+		{
+			name:          "Backward descending from NULL",
+			filters:       Filters{Sort: "-Created_at", Before: Cursor{Sort: "-Created_at", ID: 7}.Encode()},
+			wantCondition: " AND (t.Created_at IS NOT NULL OR t.Id_threads < ?)",
+			wantArgs:      []any{7},
+			wantOrder:     "ASC DESC",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.filters.SortSafelist = testSortSafelist
+
+			condition, args, err := tt.filters.keyset("t", "t.Id_threads")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if condition != tt.wantCondition {
+				t.Errorf("got condition %q; want %q", condition, tt.wantCondition)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("got arguments %v; want %v", args, tt.wantArgs)
+			}
+			if order := tt.filters.sortDirection() + " " + tt.filters.idDirection(); order != tt.wantOrder {
+				t.Errorf("got order %q; want %q", order, tt.wantOrder)
+			}
+		})
+	}
+
+	invalid := []struct {
+		name    string
+		filters Filters
+	}{
+		{name: "Tampered cursor", filters: Filters{Sort: "Created_at", After: "eyJzIjoiQ3JlYXRlZF9hdCIsInYiOm51bGwsImkiOjh9.c2lnbmF0dXJl"}},
+		{name: "Cursor of another sort", filters: Filters{Sort: "Created_at", After: Cursor{Sort: "-Created_at", Value: value, ID: 7}.Encode()}},
+	}
+
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.filters.SortSafelist = testSortSafelist
+
+			_, _, err := tt.filters.keyset("t", "t.Id_threads")
+			if !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("got %v; want %v", err, ErrInvalidCursor)
+			}
+
+			v := validator.New()
+			tt.filters.Page, tt.filters.PageSize, tt.filters.Cursorable = 1, 20, true
+			ValidateFilters(v, tt.filters)
+			if _, ok := v.Errors["after"]; !ok {
+				t.Errorf("got errors %v; want an error on after", v.Errors)
+			}
+		})
+	}
+}
+
+func TestPaginate(t *testing.T) {
+
+	SetCursorKey("secret")
+
+	// the items as fetched from the database, with their ids as sort keys
+	fetch := func(ids ...int) ([]int, []Cursor) {
+		var cursors []Cursor
+		for _, id := range ids {
+			cursors = append(cursors, newCursor("Created_at", int64(id), id))
+		}
+		return ids, cursors
+	}
+	cursorID := func(encoded string) int {
+		if encoded == "" {
+			return 0
+		}
+		cursor, err := DecodeCursor(encoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cursor.ID
+	}
+
+	after := Cursor{Sort: "Created_at", Value: ptr("2"), ID: 2}.Encode()
+	before := Cursor{Sort: "Created_at", Value: ptr("6"), ID: 6}.Encode()
+
+	tests := []struct {
+		name      string
+		filters   Filters
+		fetched   []int
+		wantItems []int
+		wantNext  int
+		wantPrev  int
+	}{
+		{
+			name:      "First page",
+			filters:   Filters{Cursor: true},
+			fetched:   []int{1, 2, 3},
+			wantItems: []int{1, 2},
+			wantNext:  2,
+		},
+		{
+			name:      "Only page",
+			filters:   Filters{Cursor: true},
+			fetched:   []int{1, 2},
+			wantItems: []int{1, 2},
+		},
+		{
+			name:      "Forward with more",
+			filters:   Filters{After: after},
+			fetched:   []int{3, 4, 5},
+			wantItems: []int{3, 4},
+			wantNext:  4,
+			wantPrev:  3,
+		},
+		{
+			name:      "Forward last page",
+			filters:   Filters{After: after},
+			fetched:   []int{3},
+			wantItems: []int{3},
+			wantPrev:  3,
+		},
+		{
+			// fetched in reverse order, put back in order
+			name:      "Backward with more",
+			filters:   Filters{Before: before},
+			fetched:   []int{5, 4, 3},
+			wantItems: []int{4, 5},
+			wantNext:  5,
+			wantPrev:  4,
+		},
+		{
+			name:      "Backward first page",
+			filters:   Filters{Before: before},
+			fetched:   []int{5},
+			wantItems: []int{5},
+			wantNext:  5,
+		},
+		{
+			name:    "Empty",
+			filters: Filters{After: after},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.filters.Sort, tt.filters.SortSafelist, tt.filters.PageSize = "Created_at", testSortSafelist, 2
+
+			items, cursors := fetch(tt.fetched...)
+			items, metadata := paginate(items, cursors, 10, tt.filters)
+
+			if !slices.Equal(items, tt.wantItems) {
+				t.Errorf("got items %v; want %v", items, tt.wantItems)
+			}
+			if next := cursorID(metadata.NextCursor); next != tt.wantNext {
+				t.Errorf("got next cursor on %d; want %d", next, tt.wantNext)
+			}
+			if prev := cursorID(metadata.PrevCursor); prev != tt.wantPrev {
+				t.Errorf("got previous cursor on %d; want %d", prev, tt.wantPrev)
+			}
+		})
+	}
+}
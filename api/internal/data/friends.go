@@ -192,3 +192,24 @@ func (m UserModel) GetFriendsByUserID(id int) (sentFriends, receivedFriends []Fr
 
 	return sentFriends, receivedFriends, nil
 }
+
+// AreFriends checks whether the two users accepted each other as friends (in either direction)
+func (m UserModel) AreFriends(id1, id2 int) (bool, error) {
+
+	query := `
+		SELECT EXISTS(
+		    SELECT 1 FROM friends
+		    WHERE ((Id_users_from = ? AND Id_users_to = ?) OR (Id_users_from = ? AND Id_users_to = ?)) AND Status = ?);`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var friends bool
+
+	err := m.DB.QueryRowContext(ctx, query, id1, id2, id2, id1, FriendStatus.Accepted).Scan(&friends)
+	if err != nil {
+		return false, err
+	}
+
+	return friends, nil
+}
@@ -0,0 +1,83 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/go-sql-driver/mysql"
+	"time"
+)
+
+// Identity links a user to their account at an external identity provider (OpenID Connect or GitHub)
+type Identity struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"-"`
+	Provider  string    `json:"provider"`
+	Subject   string    `json:"-"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type IdentityModel struct {
+	DB *sql.DB
+}
+
+// GetUserID returns the id of the user linked to the provider's subject
+func (m IdentityModel) GetUserID(provider, subject string) (int, error) {
+
+	query := `
+		SELECT Id_users
+		FROM user_identities
+		WHERE Provider = ? AND Subject = ?;`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var userID int
+
+	err := m.DB.QueryRowContext(ctx, query, provider, subject).Scan(&userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, ErrRecordNotFound
+		default:
+			return 0, err
+		}
+	}
+
+	return userID, nil
+}
+
+// Insert links the provider's subject to the user (a subject being linked to one user only)
+func (m IdentityModel) Insert(identity *Identity) error {
+
+	query := `
+		INSERT INTO user_identities (Id_users, Provider, Subject, Email)
+		VALUES (?, ?, ?, ?);`
+
+	args := []any{identity.UserID, identity.Provider, identity.Subject, identity.Email}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	res, err := m.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		var mySQLError *mysql.MySQLError
+		switch {
+		case errors.As(err, &mySQLError) && mySQLError.Number == 1062:
+			return ErrDuplicateEntry
+		default:
+			return err
+		}
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	identity.ID = int(id)
+	identity.CreatedAt = time.Now()
+
+	return nil
+}
@@ -0,0 +1,224 @@
+package data
+
+import (
+	"ForumAPI/internal/markdown"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+type mentionType struct {
+	User string
+	Tag  string
+}
+
+var MentionType = mentionType{
+	User: "user",
+	Tag:  "tag",
+}
+
+// Mention is an @username or #tagname of a post's content resolved to the user or tag it refers to
+//
+// Offset and Length locate the reference (prefix included) in the content, in bytes.
+type Mention struct {
+	Type   string `json:"type"`
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	Offset int    `json:"offset"`
+	Length int    `json:"length"`
+}
+
+// MentionedUserIDs returns the ids of the users mentioned in the post, without duplicates
+func (post *Post) MentionedUserIDs() []int {
+
+	var ids []int
+	for _, mention := range post.Mentions {
+		if mention.Type == MentionType.User && !slices.Contains(ids, mention.ID) {
+			ids = append(ids, mention.ID)
+		}
+	}
+
+	return ids
+}
+
+// resolveNames returns the ids and exact names of the rows whose name is among the given ones, indexed by lowercase name
+func resolveNames(ctx context.Context, tx *sql.Tx, query string, names []string) (map[string]Mention, error) {
+
+	resolved := make(map[string]Mention)
+
+	if len(names) == 0 {
+		return resolved, nil
+	}
+
+	args := make([]any, len(names))
+	for i, name := range names {
+		args[i] = name
+	}
+
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(query, placeholders(len(names))), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var mention Mention
+		if err = rows.Scan(&mention.ID, &mention.Name); err != nil {
+			return nil, err
+		}
+		resolved[strings.ToLower(mention.Name)] = mention
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return resolved, nil
+}
+
+// insertMentions resolves the references of the post's content against the users and the tags,
+// records them and sets the post's mentions. The references to unknown users or tags are ignored.
+func insertMentions(ctx context.Context, tx *sql.Tx, post *Post) error {
+
+	post.Mentions = nil
+
+	references := markdown.References(post.Content)
+	if len(references) == 0 {
+		return nil
+	}
+
+	var usernames, tagNames []string
+	for _, reference := range references {
+		switch reference.Prefix {
+		case '@':
+			usernames = append(usernames, reference.Name)
+		case '#':
+			tagNames = append(tagNames, reference.Name)
+		}
+	}
+
+	users, err := resolveNames(ctx, tx, `
+		SELECT Id_users, Username
+		FROM users
+		WHERE Username IN (%s);`, usernames)
+	if err != nil {
+		return err
+	}
+
+	tags, err := resolveNames(ctx, tx, `
+		SELECT Id_tags, Name
+		FROM tags
+		WHERE Name IN (%s) AND Deleted_at IS NULL;`, tagNames)
+	if err != nil {
+		return err
+	}
+
+	var values []string
+	var args []any
+
+	for _, reference := range references {
+		var mention Mention
+		var ok bool
+		var userID, tagID any
+
+		switch reference.Prefix {
+		case '@':
+			mention, ok = users[strings.ToLower(reference.Name)]
+			mention.Type = MentionType.User
+			userID = mention.ID
+		case '#':
+			mention, ok = tags[strings.ToLower(reference.Name)]
+			mention.Type = MentionType.Tag
+			tagID = mention.ID
+		}
+		if !ok {
+			continue
+		}
+
+		mention.Offset = reference.Offset
+		mention.Length = reference.Length
+		post.Mentions = append(post.Mentions, mention)
+
+		values = append(values, "(?, ?, ?, ?, ?)")
+		args = append(args, post.ID, userID, tagID, mention.Offset, mention.Length)
+	}
+
+	if len(values) == 0 {
+		return nil
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO post_mentions (Id_posts, Id_users, Id_tags, Position, Length)
+		VALUES %s;`, strings.Join(values, ", "))
+
+	_, err = tx.ExecContext(ctx, query, args...)
+
+	return err
+}
+
+// GetMentions sets the mentions of the posts, leaving out the users and tags which do not exist anymore
+// and the posts whose content is not shown
+func (m PostModel) GetMentions(posts []*Post) error {
+
+	if len(posts) == 0 {
+		return nil
+	}
+
+	var ids []any
+	byID := make(map[int]*Post, len(posts))
+
+	for _, post := range posts {
+		post.Mentions = nil
+		if post.Content == "" {
+			continue
+		}
+		ids = append(ids, post.ID)
+		byID[post.ID] = post
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	query := fmt.Sprintf(`
+		SELECT pm.Id_posts, IF(pm.Id_users IS NULL, ?, ?), COALESCE(u.Id_users, tg.Id_tags), COALESCE(u.Username, tg.Name), pm.Position, pm.Length
+		FROM post_mentions pm
+		LEFT JOIN users u ON pm.Id_users = u.Id_users
+		LEFT JOIN tags tg ON pm.Id_tags = tg.Id_tags AND tg.Deleted_at IS NULL
+		WHERE pm.Id_posts IN (%s) AND (u.Id_users IS NOT NULL OR tg.Id_tags IS NOT NULL)
+		ORDER BY pm.Id_posts, pm.Position;`, placeholders(len(ids)))
+
+	args := append([]any{MentionType.Tag, MentionType.User}, ids...)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil
+		default:
+			return err
+		}
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var postID int
+		var mention Mention
+
+		err = rows.Scan(&postID, &mention.Type, &mention.ID, &mention.Name, &mention.Offset, &mention.Length)
+		if err != nil {
+			return err
+		}
+
+		byID[postID].Mentions = append(byID[postID].Mentions, mention)
+	}
+
+	return rows.Err()
+}
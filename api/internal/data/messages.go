@@ -0,0 +1,347 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"time"
+)
+
+type Conversation struct {
+	ID      int    `json:"id"`
+	UserIDs [2]int `json:"-"`
+	With    struct {
+		ID     int    `json:"id"`
+		Name   string `json:"name"`
+		Avatar string `json:"avatar,omitempty"`
+	} `json:"with"`
+	LastMessage *Message  `json:"last_message,omitempty"`
+	Unread      int       `json:"unread"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type Message struct {
+	ID             int       `json:"id"`
+	ConversationID int       `json:"conversation_id"`
+	AuthorID       int       `json:"author_id"`
+	Content        string    `json:"content"`
+	IsRead         bool      `json:"is_read"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// HasParticipant checks whether the user takes part in the conversation
+func (c *Conversation) HasParticipant(userID int) bool {
+	return c.UserIDs[0] == userID || c.UserIDs[1] == userID
+}
+
+// Recipient returns the id of the other participant of the conversation
+func (c *Conversation) Recipient(userID int) int {
+	if c.UserIDs[0] == userID {
+		return c.UserIDs[1]
+	}
+	return c.UserIDs[0]
+}
+
+type MessageModel struct {
+	DB *sql.DB
+}
+
+// InsertConversation creates the conversation between the two users of conversation.UserIDs.
+// The ids are stored in ascending order so that a pair of users only has one conversation.
+func (m MessageModel) InsertConversation(conversation *Conversation) error {
+
+	if conversation.UserIDs[0] > conversation.UserIDs[1] {
+		conversation.UserIDs[0], conversation.UserIDs[1] = conversation.UserIDs[1], conversation.UserIDs[0]
+	}
+
+	query := `
+		INSERT INTO conversations (Id_users_1, Id_users_2)
+		VALUES (?, ?);`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	res, err := m.DB.ExecContext(ctx, query, conversation.UserIDs[0], conversation.UserIDs[1])
+	if err != nil {
+		var mySQLError *mysql.MySQLError
+		switch {
+		case errors.As(err, &mySQLError) && mySQLError.Number == 1062:
+			return ErrDuplicateEntry
+		default:
+			return err
+		}
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	conversation.ID = int(id)
+	conversation.CreatedAt = time.Now()
+	conversation.UpdatedAt = conversation.CreatedAt
+
+	return nil
+}
+
+// GetConversation returns the conversation with the given id, seen by the user userID
+func (m MessageModel) GetConversation(id, userID int) (*Conversation, error) {
+
+	query := `
+		SELECT c.Id_conversations, c.Id_users_1, c.Id_users_2, c.Created_at, c.Updated_at, u.Id_users, u.Username, u.Avatar_path
+		FROM conversations c
+		INNER JOIN users u ON u.Id_users = IF(c.Id_users_1 = ?, c.Id_users_2, c.Id_users_1)
+		WHERE c.Id_conversations = ?;`
+
+	return m.getConversation(query, userID, id)
+}
+
+// GetConversationBetween returns the conversation between the user userID and the user otherID
+func (m MessageModel) GetConversationBetween(userID, otherID int) (*Conversation, error) {
+
+	query := `
+		SELECT c.Id_conversations, c.Id_users_1, c.Id_users_2, c.Created_at, c.Updated_at, u.Id_users, u.Username, u.Avatar_path
+		FROM conversations c
+		INNER JOIN users u ON u.Id_users = IF(c.Id_users_1 = ?, c.Id_users_2, c.Id_users_1)
+		WHERE c.Id_users_1 = LEAST(?, ?) AND c.Id_users_2 = GREATEST(?, ?);`
+
+	return m.getConversation(query, userID, userID, otherID, userID, otherID)
+}
+
+func (m MessageModel) getConversation(query string, args ...any) (*Conversation, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var conversation Conversation
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(
+		&conversation.ID,
+		&conversation.UserIDs[0],
+		&conversation.UserIDs[1],
+		&conversation.CreatedAt,
+		&conversation.UpdatedAt,
+		&conversation.With.ID,
+		&conversation.With.Name,
+		&conversation.With.Avatar,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &conversation, nil
+}
+
+// GetConversations returns the conversations of the user with their last message and unread count.
+// Conversations with blocked users are excluded.
+func (m MessageModel) GetConversations(userID int, filters Filters) ([]*Conversation, Metadata, error) {
+
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), c.Id_conversations, c.Id_users_1, c.Id_users_2, c.Created_at, c.Updated_at, u.Id_users, u.Username, u.Avatar_path,
+		       m.Id_messages, m.Id_author, m.Content, m.Is_read, m.Created_at,
+		       (SELECT count(*) FROM messages um WHERE um.Id_conversations = c.Id_conversations AND um.Id_author <> ? AND um.Is_read = false)
+		FROM conversations c
+		INNER JOIN users u ON u.Id_users = IF(c.Id_users_1 = ?, c.Id_users_2, c.Id_users_1)
+		LEFT JOIN messages m ON m.Id_messages = (SELECT MAX(lm.Id_messages) FROM messages lm WHERE lm.Id_conversations = c.Id_conversations)
+		WHERE (c.Id_users_1 = ? OR c.Id_users_2 = ?) AND u.Status <> ?
+		ORDER BY %s %s, c.Id_conversations DESC
+		LIMIT ? OFFSET ?;`, filters.sortColumn(), filters.sortDirection())
+
+	args := []any{userID, userID, userID, userID, UserStatus.Blocked, filters.limit(), filters.offset()}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	var totalRecords int
+	var conversations []*Conversation
+
+	for rows.Next() {
+		var conversation Conversation
+		var messageID, authorID sql.NullInt64
+		var content sql.NullString
+		var isRead sql.NullBool
+		var createdAt sql.NullTime
+
+		err = rows.Scan(
+			&totalRecords,
+			&conversation.ID,
+			&conversation.UserIDs[0],
+			&conversation.UserIDs[1],
+			&conversation.CreatedAt,
+			&conversation.UpdatedAt,
+			&conversation.With.ID,
+			&conversation.With.Name,
+			&conversation.With.Avatar,
+			&messageID,
+			&authorID,
+			&content,
+			&isRead,
+			&createdAt,
+			&conversation.Unread,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		if messageID.Valid {
+			conversation.LastMessage = &Message{
+				ID:             int(messageID.Int64),
+				ConversationID: conversation.ID,
+				AuthorID:       int(authorID.Int64),
+				Content:        content.String,
+				IsRead:         isRead.Bool,
+				CreatedAt:      createdAt.Time,
+			}
+		}
+
+		conversations = append(conversations, &conversation)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return conversations, metadata, nil
+}
+
+// Insert adds the message to its conversation and updates the conversation's last activity
+func (m MessageModel) Insert(message *Message) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO messages (Id_conversations, Id_author, Content)
+		VALUES (?, ?, ?);`
+
+	res, err := tx.ExecContext(ctx, query, message.ConversationID, message.AuthorID, message.Content)
+	if err != nil {
+		return err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	query = `
+		UPDATE conversations
+		SET Updated_at = CURRENT_TIMESTAMP
+		WHERE Id_conversations = ?;`
+
+	_, err = tx.ExecContext(ctx, query, message.ConversationID)
+	if err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	message.ID = int(id)
+	message.CreatedAt = time.Now()
+
+	return nil
+}
+
+// Get returns at most limit messages of the conversation, newest first, older than the message before (if not 0).
+// The returned cursor is the id to use as before to get the next messages, or 0 if there are none left.
+func (m MessageModel) Get(conversationID, before, limit int) ([]*Message, int, error) {
+
+	var beforeCondition string
+	args := []any{conversationID}
+
+	if before > 0 {
+		beforeCondition = " AND Id_messages < ?"
+		args = append(args, before)
+	}
+
+	// fetching one more message to know if there is a next page
+	args = append(args, limit+1)
+
+	query := fmt.Sprintf(`
+		SELECT Id_messages, Id_conversations, COALESCE(Id_author, 0), Content, Is_read, Created_at
+		FROM messages
+		WHERE Id_conversations = ?%s
+		ORDER BY Id_messages DESC
+		LIMIT ?;`, beforeCondition)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var messages []*Message
+
+	for rows.Next() {
+		var message Message
+
+		err = rows.Scan(
+			&message.ID,
+			&message.ConversationID,
+			&message.AuthorID,
+			&message.Content,
+			&message.IsRead,
+			&message.CreatedAt,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		messages = append(messages, &message)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	var cursor int
+	if len(messages) > limit {
+		messages = messages[:limit]
+		cursor = messages[limit-1].ID
+	}
+
+	return messages, cursor, nil
+}
+
+// MarkAsRead marks the messages the user received in the conversation as read
+func (m MessageModel) MarkAsRead(conversationID, userID int) error {
+
+	query := `
+		UPDATE messages
+		SET Is_read = true
+		WHERE Id_conversations = ? AND Id_author <> ? AND Is_read = false;`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, conversationID, userID)
+
+	return err
+}
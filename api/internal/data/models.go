@@ -15,24 +15,56 @@ var (
 	ErrDuplicateTitle    = errors.New("duplicate thread title")
 	ErrDuplicateToken    = errors.New("duplicate token")
 	ErrDuplicateEntry    = errors.New("duplicate entry")
+	ErrNotEmpty          = errors.New("category not empty")
+	ErrParentDeleted     = errors.New("parent deleted")
 )
 
 type Models struct {
-	Categories CategoryModel
-	Threads    ThreadModel
-	Tags       TagModel
-	Posts      PostModel
-	Tokens     TokenModel
-	Users      UserModel
+	Attachments     AttachmentModel
+	Audit           AuditModel
+	Categories      CategoryModel
+	Threads         ThreadModel
+	Tags            TagModel
+	Identities      IdentityModel
+	LoginThrottles  LoginThrottleModel
+	Messages        MessageModel
+	Notifications   NotificationModel
+	Posts           PostModel
+	Privacy         PrivacyModel
+	Recommendations RecommendationModel
+	Reports         ReportModel
+	Revisions       RevisionModel
+	Search          SearchModel
+	Sessions        SessionModel
+	Subscriptions   SubscriptionModel
+	Tokens          TokenModel
+	TOTP            TOTPModel
+	Trash           TrashModel
+	Users           UserModel
 }
 
 func NewModels(db *sql.DB) Models {
 	return Models{
-		Categories: CategoryModel{DB: db},
-		Threads:    ThreadModel{DB: db},
-		Tags:       TagModel{DB: db},
-		Posts:      PostModel{DB: db},
-		Tokens:     TokenModel{DB: db},
-		Users:      UserModel{DB: db},
+		Attachments:     AttachmentModel{DB: db},
+		Audit:           AuditModel{DB: db},
+		Categories:      CategoryModel{DB: db},
+		Threads:         ThreadModel{DB: db},
+		Tags:            TagModel{DB: db},
+		Identities:      IdentityModel{DB: db},
+		LoginThrottles:  LoginThrottleModel{DB: db},
+		Messages:        MessageModel{DB: db},
+		Notifications:   NotificationModel{DB: db},
+		Posts:           PostModel{DB: db},
+		Privacy:         PrivacyModel{DB: db},
+		Recommendations: RecommendationModel{DB: db},
+		Reports:         ReportModel{DB: db},
+		Revisions:       RevisionModel{DB: db},
+		Search:          SearchModel{DB: db},
+		Sessions:        SessionModel{DB: db},
+		Subscriptions:   SubscriptionModel{DB: db},
+		Tokens:          TokenModel{DB: db},
+		TOTP:            TOTPModel{DB: db},
+		Trash:           TrashModel{DB: db},
+		Users:           UserModel{DB: db},
 	}
 }
@@ -0,0 +1,276 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type Notification struct {
+	ID     int    `json:"id"`
+	UserID int    `json:"-"`
+	Type   string `json:"type"`
+	Actor  struct {
+		ID     int    `json:"id"`
+		Name   string `json:"name"`
+		Avatar string `json:"avatar,omitempty"`
+	} `json:"actor"`
+	Thread struct {
+		ID    int    `json:"id,omitempty"`
+		Title string `json:"title,omitempty"`
+	} `json:"thread,omitempty"`
+	PostID    int       `json:"post_id,omitempty"`
+	IsRead    bool      `json:"is_read"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type notificationType struct {
+	Reply          string
+	ThreadPost     string
+	Reaction       string
+	Mention        string
+	FriendRequest  string
+	FriendAccepted string
+}
+
+var NotificationType = notificationType{
+	Reply:          "reply",
+	ThreadPost:     "thread_post",
+	Reaction:       "reaction",
+	Mention:        "mention",
+	FriendRequest:  "friend_request",
+	FriendAccepted: "friend_accepted",
+}
+
+type NotificationModel struct {
+	DB *sql.DB
+}
+
+func (m NotificationModel) Insert(notification *Notification) error {
+
+	query := `
+		INSERT INTO notifications (Id_users, Id_actor, Type, Id_threads, Id_posts)
+		VALUES (?, ?, ?, NULLIF(?, 0), NULLIF(?, 0));`
+
+	args := []any{notification.UserID, notification.Actor.ID, notification.Type, notification.Thread.ID, notification.PostID}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	res, err := m.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	notification.ID = int(id)
+
+	return nil
+}
+
+// InsertForSubscribers notifies every user subscribed to the notification's thread,
+// except the actor and the users in excluded (already notified otherwise).
+// It returns the notifications created.
+func (m NotificationModel) InsertForSubscribers(notification *Notification, excluded ...int) ([]*Notification, error) {
+
+	excluded = append(excluded, notification.Actor.ID)
+
+	query := fmt.Sprintf(`
+		SELECT Id_users
+		FROM thread_subscriptions
+		WHERE Id_threads = ? AND Level <> ? AND Id_users NOT IN (?%s);`, strings.Repeat(", ?", len(excluded)-1))
+
+	args := []any{notification.Thread.ID, SubscriptionLevel.None}
+	for _, id := range excluded {
+		args = append(args, id)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var recipients []int
+	for rows.Next() {
+		var id int
+		err = rows.Scan(&id)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		recipients = append(recipients, id)
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	query = `
+		INSERT INTO notifications (Id_users, Id_actor, Type, Id_threads, Id_posts)
+		VALUES (?, ?, ?, ?, NULLIF(?, 0));`
+
+	var notifications []*Notification
+
+	for _, recipient := range recipients {
+		res, err := tx.ExecContext(ctx, query, recipient, notification.Actor.ID, notification.Type, notification.Thread.ID, notification.PostID)
+		if err != nil {
+			return nil, err
+		}
+
+		id, err := res.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+
+		created := *notification
+		created.ID = int(id)
+		created.UserID = recipient
+		created.CreatedAt = time.Now()
+
+		notifications = append(notifications, &created)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return notifications, nil
+}
+
+func (m NotificationModel) Get(userID int, unreadOnly bool, filters Filters) ([]*Notification, Metadata, error) {
+
+	var unreadCondition string
+	if unreadOnly {
+		unreadCondition = " AND n.Is_read = false"
+	}
+
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), n.Id_notifications, n.Type, COALESCE(n.Id_actor, 0), COALESCE(u.Username, ''), COALESCE(u.Avatar_path, ''), COALESCE(n.Id_threads, 0), COALESCE(t.Title, ''), COALESCE(n.Id_posts, 0), n.Is_read, n.Created_at
+		FROM notifications n
+		LEFT JOIN users u ON n.Id_actor = u.Id_users
+		LEFT JOIN threads t ON n.Id_threads = t.Id_threads
+		LEFT JOIN posts p ON n.Id_posts = p.Id_posts
+		WHERE n.Id_users = ? AND t.Deleted_at IS NULL AND p.Deleted_at IS NULL%s
+		ORDER BY %s %s, n.Id_notifications DESC
+		LIMIT ? OFFSET ?;`, unreadCondition, filters.sortColumn(), filters.sortDirection())
+
+	args := []any{userID, filters.limit(), filters.offset()}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	var totalRecords int
+	var notifications []*Notification
+
+	for rows.Next() {
+		var notification Notification
+
+		err = rows.Scan(
+			&totalRecords,
+			&notification.ID,
+			&notification.Type,
+			&notification.Actor.ID,
+			&notification.Actor.Name,
+			&notification.Actor.Avatar,
+			&notification.Thread.ID,
+			&notification.Thread.Title,
+			&notification.PostID,
+			&notification.IsRead,
+			&notification.CreatedAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		notification.UserID = userID
+
+		notifications = append(notifications, &notification)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return notifications, metadata, nil
+}
+
+func (m NotificationModel) CountUnread(userID int) (int, error) {
+
+	query := `
+		SELECT count(*)
+		FROM notifications n
+		LEFT JOIN threads t ON n.Id_threads = t.Id_threads
+		LEFT JOIN posts p ON n.Id_posts = p.Id_posts
+		WHERE n.Id_users = ? AND n.Is_read = false AND t.Deleted_at IS NULL AND p.Deleted_at IS NULL;`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var unread int
+
+	err := m.DB.QueryRowContext(ctx, query, userID).Scan(&unread)
+	if err != nil {
+		return 0, err
+	}
+
+	return unread, nil
+}
+
+// MarkAsRead marks the user's notifications with the given ids as read, or all of them when ids is empty.
+// It returns the number of notifications updated.
+func (m NotificationModel) MarkAsRead(userID int, ids []int) (int, error) {
+
+	var idCondition string
+	args := []any{userID}
+
+	if len(ids) > 0 {
+		idCondition = fmt.Sprintf(" AND Id_notifications IN (?%s)", strings.Repeat(", ?", len(ids)-1))
+		for _, id := range ids {
+			args = append(args, id)
+		}
+	}
+
+	query := fmt.Sprintf(`
+		UPDATE notifications
+		SET Is_read = true
+		WHERE Id_users = ? AND Is_read = false%s;`, idCondition)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	res, err := m.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(rowsAffected), nil
+}
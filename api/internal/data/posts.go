@@ -1,6 +1,7 @@
 package data
 
 import (
+	"ForumAPI/internal/markdown"
 	"ForumAPI/internal/validator"
 	"context"
 	"database/sql"
@@ -15,27 +16,103 @@ import (
 type Post struct {
 	ID           int            `json:"id"`
 	Content      string         `json:"content"`
+	ContentHTML  string         `json:"content_html,omitempty"`
+	Mentions     []Mention      `json:"mentions,omitempty"`
+	Attachments  []*Attachment  `json:"attachments,omitempty"`
 	CreatedAt    time.Time      `json:"created_at"`
 	UpdatedAt    time.Time      `json:"updated_at"`
 	Author       User           `json:"author"`
 	IDParentPost int            `json:"id_parent_post,omitempty"`
 	Thread       Thread         `json:"thread"`
+	Status       string         `json:"status,omitempty"`
 	Reactions    map[string]int `json:"reactions,omitempty"`
 	Popularity   int            `json:"popularity,omitempty"`
 	Version      int            `json:"version,omitempty"`
+	Depth        int            `json:"depth,omitempty"`
+	ReplyCount   int            `json:"reply_count,omitempty"`
+	Replies      []*Post        `json:"replies,omitempty"`
 }
 
 func (post *Post) Validate(v *validator.Validator) {
-	v.StringCheck(post.Content, 2, 1_020, true, "content")
+	v.StringCheck(post.Content, 2, 10_000, true, "content")
 	v.StringCheck(post.Author.Name, 2, 70, true, "author.name")
 	v.StringCheck(post.Thread.Title, 2, 125, true, "thread.title")
 	v.Check(post.Thread.ID != 0, "post.thread.id", "must be provided")
 }
 
+// PostFilters is the set of structured filters available when listing posts
+//
+// CategoryID and TagIDs apply to the posts' threads.
+type PostFilters struct {
+	AuthorID             int
+	ThreadID             int
+	CategoryID           int
+	IncludeSubcategories bool
+	TagIDs               []int
+	AllTags              bool
+	CreatedAfter         time.Time
+	CreatedBefore        time.Time
+}
+
+// conditions returns the SQL conditions (and their arguments) restricting the post aliased p and its thread aliased t
+func (f PostFilters) conditions() (string, []any) {
+
+	var conditions string
+	var args []any
+
+	if f.AuthorID > 0 {
+		conditions += " AND p.Id_author = ?"
+		args = append(args, f.AuthorID)
+	}
+	if f.ThreadID > 0 {
+		conditions += " AND p.Id_threads = ?"
+		args = append(args, f.ThreadID)
+	}
+	if f.CategoryID > 0 {
+		condition, conditionArgs := categoryCondition("t.Id_categories", f.CategoryID, f.IncludeSubcategories)
+		conditions += condition
+		args = append(args, conditionArgs...)
+	}
+	if len(f.TagIDs) > 0 {
+		condition, conditionArgs := tagsCondition("p.Id_threads", f.TagIDs, f.AllTags)
+		conditions += condition
+		args = append(args, conditionArgs...)
+	}
+	if !f.CreatedAfter.IsZero() {
+		conditions += " AND p.Created_at >= ?"
+		args = append(args, f.CreatedAfter)
+	}
+	if !f.CreatedBefore.IsZero() {
+		conditions += " AND p.Created_at < ?"
+		args = append(args, f.CreatedBefore)
+	}
+
+	return conditions, args
+}
+
+// renderContent renders the Markdown content of the post as sanitized HTML
+func (post *Post) renderContent() error {
+
+	var err error
+	post.ContentHTML, err = markdown.Render(post.Content)
+
+	return err
+}
+
 type PostModel struct {
 	DB *sql.DB
 }
 
+type postStatus struct {
+	Active string
+	Hidden string
+}
+
+var PostStatus = postStatus{
+	Active: "active",
+	Hidden: "hidden",
+}
+
 func (m PostModel) Insert(post *Post) error {
 
 	args := []any{post.Content, post.Author.ID, post.Thread.ID}
@@ -84,12 +161,14 @@ func (m PostModel) Insert(post *Post) error {
 		return ErrRecordNotFound
 	}
 
+	// the thread must not be in the trash
 	query = `
-		SELECT Created_at, Version
-		FROM posts
-		WHERE Id_posts = ?;`
+		SELECT p.Created_at, p.Version, t.Title
+		FROM posts p
+		INNER JOIN threads t ON p.Id_threads = t.Id_threads
+		WHERE p.Id_posts = ? AND t.Deleted_at IS NULL;`
 
-	err = tx.QueryRowContext(ctx, query, post.ID).Scan(&post.CreatedAt, &post.Version)
+	err = tx.QueryRowContext(ctx, query, post.ID).Scan(&post.CreatedAt, &post.Version, &post.Thread.Title)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -99,6 +178,15 @@ func (m PostModel) Insert(post *Post) error {
 		}
 	}
 
+	err = insertMentions(ctx, tx, post)
+	if err != nil {
+		return err
+	}
+
+	if err = post.renderContent(); err != nil {
+		return err
+	}
+
 	if err = tx.Commit(); err != nil {
 		return err
 	}
@@ -106,7 +194,7 @@ func (m PostModel) Insert(post *Post) error {
 	return nil
 }
 
-func (m PostModel) Get(search string, filters Filters) ([]*Post, Metadata, error) {
+func (m PostModel) Get(search string, postFilters PostFilters, filters Filters) ([]*Post, Metadata, error) {
 
 	if search != "" {
 		search = fmt.Sprintf("%%%s%%", search)
@@ -114,16 +202,25 @@ func (m PostModel) Get(search string, filters Filters) ([]*Post, Metadata, error
 		search = "%"
 	}
 
+	conditions, conditionArgs := postFilters.conditions()
+	keyset, keysetArgs, err := filters.keyset("p", "p.Id_posts")
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
 	query := fmt.Sprintf(`
-		SELECT count(*) OVER(), p.Id_posts, p.Content, p.Created_at, p.Updated_at, p.Id_author, u.Username, u.Avatar_path, p.Id_threads, t.Title
+		SELECT %s, p.Id_posts, p.Content, p.Created_at, p.Updated_at, p.Id_author, u.Username, u.Avatar_path, p.Id_threads, t.Title, %s
 		FROM posts p
 		INNER JOIN users u ON p.Id_author = u.Id_users
 		INNER JOIN threads t ON p.Id_threads = t.Id_threads
-		WHERE p.Content LIKE ?
-		ORDER BY %s %s, Id_posts ASC
-		LIMIT ? OFFSET ?;`, filters.sortColumn(), filters.sortDirection())
+		WHERE p.Content LIKE ? AND p.Status <> ? AND p.Deleted_at IS NULL%s%s
+		ORDER BY %s %s, Id_posts %s
+		LIMIT ? OFFSET ?;`, filters.countColumn(), filters.sortKey("p"), conditions, keyset, filters.sortKey("p"), filters.sortDirection(), filters.idDirection())
 
-	args := []any{search, filters.limit(), filters.offset()}
+	args := []any{search, PostStatus.Hidden}
+	args = append(args, conditionArgs...)
+	args = append(args, keysetArgs...)
+	args = append(args, filters.limit(), filters.offset())
 
 	var posts []*Post
 
@@ -141,9 +238,11 @@ func (m PostModel) Get(search string, filters Filters) ([]*Post, Metadata, error
 	}
 
 	var totalRecords int
+	var cursors []Cursor
 
 	for rows.Next() {
 		var post Post
+		var sortKey any
 
 		err := rows.Scan(
 			&totalRecords,
@@ -156,19 +255,25 @@ func (m PostModel) Get(search string, filters Filters) ([]*Post, Metadata, error
 			&post.Author.Avatar,
 			&post.Thread.ID,
 			&post.Thread.Title,
+			&sortKey,
 		)
 		if err != nil {
 			return nil, Metadata{}, err
 		}
 
+		if err = post.renderContent(); err != nil {
+			return nil, Metadata{}, err
+		}
+
 		posts = append(posts, &post)
+		cursors = append(cursors, newCursor(filters.Sort, sortKey, post.ID))
 	}
 
 	if err = rows.Err(); err != nil {
 		return nil, Metadata{}, err
 	}
 
-	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	posts, metadata := paginate(posts, cursors, totalRecords, filters)
 
 	return posts, metadata, nil
 }
@@ -176,11 +281,11 @@ func (m PostModel) Get(search string, filters Filters) ([]*Post, Metadata, error
 func (m PostModel) GetByID(id int) (*Post, error) {
 
 	query := `
-		SELECT p.Id_posts, p.Content, p.Created_at, p.Updated_at, p.Id_author, u.Username, u.Avatar_path, p.Id_parent_posts, p.Id_threads, t.Title, p.Version
+		SELECT p.Id_posts, p.Content, p.Created_at, p.Updated_at, p.Id_author, u.Username, u.Avatar_path, p.Id_parent_posts, p.Id_threads, t.Title, p.Status, p.Version
 		FROM posts p
 		INNER JOIN users u ON p.Id_author = u.Id_users
 		INNER JOIN threads t ON p.Id_threads = t.Id_threads
-		WHERE p.Id_posts = ?;`
+		WHERE p.Id_posts = ? AND p.Deleted_at IS NULL;`
 
 	var post Post
 	var parentPost sql.NullInt64
@@ -199,6 +304,7 @@ func (m PostModel) GetByID(id int) (*Post, error) {
 		&parentPost,
 		&post.Thread.ID,
 		&post.Thread.Title,
+		&post.Status,
 		&post.Version,
 	)
 
@@ -215,6 +321,18 @@ func (m PostModel) GetByID(id int) (*Post, error) {
 		post.IDParentPost = int(parentPost.Int64)
 	}
 
+	if err = post.renderContent(); err != nil {
+		return nil, err
+	}
+
+	if err = m.GetMentions([]*Post{&post}); err != nil {
+		return nil, err
+	}
+
+	if err = m.GetAttachments([]*Post{&post}); err != nil {
+		return nil, err
+	}
+
 	return &post, nil
 }
 
@@ -224,12 +342,13 @@ func (m PostModel) GetByAuthorID(id int) ([]Post, error) {
 		SELECT p.Id_posts, p.Content, p.Created_at, p.Updated_at, p.Id_threads, t.Title, p.Version
 		FROM posts p
 		INNER JOIN threads t on p.Id_threads = t.Id_threads
-		WHERE p.Id_author = ?;`
+		WHERE p.Id_author = ? AND p.Deleted_at IS NULL AND p.Status <> ? AND t.Status <> ?
+		ORDER BY p.Created_at DESC;`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	rows, err := m.DB.QueryContext(ctx, query, id)
+	rows, err := m.DB.QueryContext(ctx, query, id, PostStatus.Hidden, ThreadStatus.Hidden)
 
 	if err != nil {
 		switch {
@@ -255,6 +374,9 @@ func (m PostModel) GetByAuthorID(id int) ([]Post, error) {
 			&post.Version); err != nil {
 			log.Fatal(err)
 		}
+		if err := post.renderContent(); err != nil {
+			return nil, err
+		}
 		posts = append(posts, post)
 	}
 	rerr := rows.Close()
@@ -274,12 +396,12 @@ func (m PostModel) GetByThread(id int) ([]*Post, error) {
 		SELECT p.Id_posts, p.Content, p.Created_at, p.Updated_at, p.Id_author, u.Username, u.Avatar_path, p.Version
 		FROM posts p
 		INNER JOIN users u on p.Id_author = u.Id_users
-		WHERE p.Id_threads = ?;`
+		WHERE p.Id_threads = ? AND p.Status <> ? AND p.Deleted_at IS NULL;`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	rows, err := m.DB.QueryContext(ctx, query, id)
+	rows, err := m.DB.QueryContext(ctx, query, id, PostStatus.Hidden)
 
 	if err != nil {
 		switch {
@@ -306,6 +428,9 @@ func (m PostModel) GetByThread(id int) ([]*Post, error) {
 			&post.Version); err != nil {
 			log.Fatal(err)
 		}
+		if err := post.renderContent(); err != nil {
+			return nil, err
+		}
 		posts = append(posts, &post)
 	}
 
@@ -321,14 +446,152 @@ func (m PostModel) GetByThread(id int) ([]*Post, error) {
 	return posts, nil
 }
 
-func (m PostModel) Update(post Post) error {
+// GetTreeByThread returns the posts of the thread with the given id down to the given number of levels,
+// ordered by depth (use NestPosts to get them as a tree)
+func (m PostModel) GetTreeByThread(id, levels int) ([]*Post, error) {
+	return m.getTree("Id_threads = ? AND Id_parent_posts IS NULL", id, 0, levels)
+}
+
+// GetReplies returns the replies to the post with the given id down to the given number of levels,
+// ordered by depth (use NestPosts to get them as a tree)
+func (m PostModel) GetReplies(id, levels int) ([]*Post, error) {
 
+	// the depth of the replies is relative to the root of the thread
 	query := `
-		UPDATE posts 
-		SET Content = ?, Id_author= ?, Id_parent_posts = ?, Version = Version + 1
-		WHERE Id_posts = ? AND Version = ?;`
+		WITH RECURSIVE ancestors AS (
+			SELECT Id_parent_posts
+			FROM posts
+			WHERE Id_posts = ? AND Deleted_at IS NULL
+			UNION ALL
+			SELECT p.Id_parent_posts
+			FROM posts p
+			INNER JOIN ancestors a ON p.Id_posts = a.Id_parent_posts
+		)
+		SELECT COUNT(*)
+		FROM ancestors;`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var depth int
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(&depth)
+	if err != nil {
+		return nil, err
+	}
+	if depth == 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	return m.getTree("Id_parent_posts = ?", id, depth, levels)
+}
+
+// getTree walks down the replies from the posts matching the root condition, which get the given depth
+//
+// Hidden posts keep their place in the tree (so that their replies stay reachable) but lose their content.
+func (m PostModel) getTree(rootCondition string, rootArg any, depth, levels int) ([]*Post, error) {
+
+	query := fmt.Sprintf(`
+		WITH RECURSIVE tree AS (
+			SELECT Id_posts, CAST(? AS UNSIGNED) AS Depth
+			FROM posts
+			WHERE %s AND Deleted_at IS NULL
+			UNION ALL
+			SELECT p.Id_posts, tree.Depth + 1
+			FROM posts p
+			INNER JOIN tree ON p.Id_parent_posts = tree.Id_posts
+			WHERE tree.Depth < ? AND p.Deleted_at IS NULL
+		)
+		SELECT p.Id_posts, IF(p.Status = ?, '', p.Content), p.Created_at, p.Updated_at, p.Id_author, u.Username, u.Avatar_path, p.Id_parent_posts, p.Id_threads, p.Status, p.Version, tree.Depth, (SELECT COUNT(*)
+																															FROM posts r
+																															WHERE r.Id_parent_posts = p.Id_posts AND r.Deleted_at IS NULL) AS Reply_count
+		FROM tree
+		INNER JOIN posts p ON tree.Id_posts = p.Id_posts
+		INNER JOIN users u ON p.Id_author = u.Id_users
+		ORDER BY tree.Depth ASC, p.Id_posts ASC;`, rootCondition)
+
+	args := []any{depth, rootArg, depth + levels - 1, PostStatus.Hidden}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var posts []*Post
+
+	for rows.Next() {
+		var post Post
+		var parentPost sql.NullInt64
+
+		err = rows.Scan(
+			&post.ID,
+			&post.Content,
+			&post.CreatedAt,
+			&post.UpdatedAt,
+			&post.Author.ID,
+			&post.Author.Name,
+			&post.Author.Avatar,
+			&parentPost,
+			&post.Thread.ID,
+			&post.Status,
+			&post.Version,
+			&post.Depth,
+			&post.ReplyCount,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if parentPost.Valid {
+			post.IDParentPost = int(parentPost.Int64)
+		}
+
+		if err = post.renderContent(); err != nil {
+			return nil, err
+		}
 
-	args := []any{post.Content, post.Author.ID, post.IDParentPost, post.ID, post.Version}
+		posts = append(posts, &post)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+// NestPosts attaches each post to the replies of its parent and returns the posts whose parent is not in the list
+func NestPosts(posts []*Post) []*Post {
+
+	byID := make(map[int]*Post, len(posts))
+	for _, post := range posts {
+		byID[post.ID] = post
+	}
+
+	var roots []*Post
+	for _, post := range posts {
+		if parent, ok := byID[post.IDParentPost]; ok {
+			parent.Replies = append(parent.Replies, post)
+		} else {
+			roots = append(roots, post)
+		}
+	}
+
+	return roots
+}
+
+// Update saves the post and, when its content changed, records the new content as a revision written by the editor
+// and replaces the mentions of the post
+func (m PostModel) Update(post *Post, editorID int) error {
+
+	var parentPost any
+	if post.IDParentPost != 0 {
+		parentPost = post.IDParentPost
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -339,6 +602,38 @@ func (m PostModel) Update(post Post) error {
 	}
 	defer tx.Rollback()
 
+	query := `
+		SELECT Content
+		FROM posts
+		WHERE Id_posts = ? AND Version = ? AND Deleted_at IS NULL
+		FOR UPDATE;`
+
+	var previousContent string
+	err = tx.QueryRowContext(ctx, query, post.ID, post.Version).Scan(&previousContent)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	contentChanged := previousContent != post.Content
+	if contentChanged {
+		err = insertOriginalPostRevision(ctx, tx, post.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	query = `
+		UPDATE posts 
+		SET Content = ?, Id_author= ?, Id_parent_posts = ?, Id_threads = ?, Version = Version + 1
+		WHERE Id_posts = ? AND Version = ?;`
+
+	args := []any{post.Content, post.Author.ID, parentPost, post.Thread.ID, post.ID, post.Version}
+
 	rs, err := tx.ExecContext(ctx, query, args...)
 	if err != nil {
 		return err
@@ -352,11 +647,11 @@ func (m PostModel) Update(post Post) error {
 	}
 
 	query = `
-		SELECT Created_at, Version
+		SELECT Created_at, Updated_at, Version
 		FROM posts
 		WHERE Id_posts = ?;`
 
-	err = tx.QueryRowContext(ctx, query, post.ID).Scan(&post.CreatedAt, &post.Version)
+	err = tx.QueryRowContext(ctx, query, post.ID).Scan(&post.CreatedAt, &post.UpdatedAt, &post.Version)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -366,6 +661,29 @@ func (m PostModel) Update(post Post) error {
 		}
 	}
 
+	if contentChanged {
+		err = insertPostRevision(ctx, tx, post, editorID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			DELETE FROM post_mentions
+			WHERE Id_posts = ?;`, post.ID)
+		if err != nil {
+			return err
+		}
+
+		err = insertMentions(ctx, tx, post)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err = post.renderContent(); err != nil {
+		return err
+	}
+
 	if err = tx.Commit(); err != nil {
 		return err
 	}
@@ -373,30 +691,52 @@ func (m PostModel) Update(post Post) error {
 	return nil
 }
 
-func (m PostModel) Delete(id int) error {
+// Delete moves the post to the trash along with its replies, which are restored with it
+func (m PostModel) Delete(id, userID int) error {
 
 	query := `
-		DELETE FROM posts
-		WHERE Id_posts = ?;`
+		WITH RECURSIVE subtree AS (
+			SELECT Id_posts
+			FROM posts
+			WHERE Id_posts = ? AND Deleted_at IS NULL
+			UNION ALL
+			SELECT p.Id_posts
+			FROM posts p
+			INNER JOIN subtree s ON p.Id_parent_posts = s.Id_posts
+			WHERE p.Deleted_at IS NULL
+		)
+		SELECT Id_posts
+		FROM subtree;`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	result, err := m.DB.ExecContext(ctx, query, id)
+	tx, err := m.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := result.RowsAffected()
+	ids, err := queryIDs(ctx, tx, query, id)
 	if err != nil {
 		return err
 	}
-
-	if rowsAffected == 0 {
+	if len(ids) == 0 {
 		return ErrRecordNotFound
 	}
 
-	return nil
+	// a single statement gives the whole subtree the same deletion date
+	query = fmt.Sprintf(`
+		UPDATE posts
+		SET Deleted_at = NOW(), Deleted_by = ?
+		WHERE Id_posts IN (%s);`, placeholders(len(ids)))
+
+	_, err = tx.ExecContext(ctx, query, append([]any{userID}, ids...)...)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 func (m PostModel) GetReactions(posts []*Post) error {
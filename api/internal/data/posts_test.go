@@ -41,20 +41,14 @@ func TestPostModel_GetByID(t *testing.T) {
 				Content:   "Hello everyone, here is a beginner's course for the Go programming language!",
 				CreatedAt: postCreatedAt,
 				UpdatedAt: postUpdatedAt,
-				Author: struct {
-					ID   int    `json:"id"`
-					Name string `json:"name"`
-				}{
+				Author: User{
 					ID:   1,
 					Name: "Thorgan",
 				},
 				IDParentPost: 0,
-				Thread: struct {
-					ID    int    `json:"id"`
-					Title string `json:"title"`
-				}{
-					1,
-					"Go programming language",
+				Thread: Thread{
+					ID:    1,
+					Title: "Go programming language",
 				},
 				Version: 1,
 			},
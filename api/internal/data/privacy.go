@@ -0,0 +1,158 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	Visibility = &visibility{
+		Public:  "public",
+		Friends: "friends",
+		Private: "private",
+	}
+	PermittedVisibilities = []string{Visibility.Public, Visibility.Friends, Visibility.Private}
+)
+
+type visibility struct {
+	Public  string
+	Friends string
+	Private string
+}
+
+// Privacy is the visibility of the profile fields and activity of a user for the other users
+type Privacy struct {
+	Email           string `json:"email"`
+	BirthDate       string `json:"birth_date"`
+	Friends         string `json:"friends"`
+	FavoriteThreads string `json:"favorite_threads"`
+	FollowingTags   string `json:"following_tags"`
+	Reactions       string `json:"reactions"`
+}
+
+// DefaultPrivacy returns the privacy settings of the users who never changed them
+func DefaultPrivacy() *Privacy {
+	return &Privacy{
+		Email:           Visibility.Private,
+		BirthDate:       Visibility.Private,
+		Friends:         Visibility.Public,
+		FavoriteThreads: Visibility.Private,
+		FollowingTags:   Visibility.Public,
+		Reactions:       Visibility.Private,
+	}
+}
+
+// Visible reports whether a field with the visibility can be seen by a viewer,
+// owner meaning the viewer is the user themselves (or an administrator)
+func Visible(visibility string, owner, friend bool) bool {
+	switch visibility {
+	case Visibility.Public:
+		return true
+	case Visibility.Friends:
+		return owner || friend
+	default:
+		return owner
+	}
+}
+
+type PrivacyModel struct {
+	DB *sql.DB
+}
+
+func (m PrivacyModel) GetByUserID(id int) (*Privacy, error) {
+
+	query := `
+		SELECT Email, Birth_date, Friends, Favorite_threads, Following_tags, Reactions
+		FROM user_privacy
+		WHERE Id_users = ?;`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var privacy Privacy
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&privacy.Email,
+		&privacy.BirthDate,
+		&privacy.Friends,
+		&privacy.FavoriteThreads,
+		&privacy.FollowingTags,
+		&privacy.Reactions,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return DefaultPrivacy(), nil
+		default:
+			return nil, err
+		}
+	}
+
+	return &privacy, nil
+}
+
+// GetBirthDateVisibilities returns the visibility of the birth date of the users, by user id
+func (m PrivacyModel) GetBirthDateVisibilities(ids []int) (map[int]string, error) {
+
+	visibilities := make(map[int]string, len(ids))
+	args := make([]any, 0, len(ids))
+	for _, id := range ids {
+		visibilities[id] = DefaultPrivacy().BirthDate
+		args = append(args, id)
+	}
+
+	if len(ids) == 0 {
+		return visibilities, nil
+	}
+
+	query := fmt.Sprintf(`
+		SELECT Id_users, Birth_date
+		FROM user_privacy
+		WHERE Id_users IN (%s);`, placeholders(len(ids)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var birthDate string
+		if err = rows.Scan(&id, &birthDate); err != nil {
+			return nil, err
+		}
+		visibilities[id] = birthDate
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return visibilities, nil
+}
+
+// Upsert sets the privacy settings of the user, creating them if they were still the default ones
+func (m PrivacyModel) Upsert(id int, privacy *Privacy) error {
+
+	query := `
+		INSERT INTO user_privacy (Id_users, Email, Birth_date, Friends, Favorite_threads, Following_tags, Reactions)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE Email = VALUES(Email), Birth_date = VALUES(Birth_date), Friends = VALUES(Friends),
+		    Favorite_threads = VALUES(Favorite_threads), Following_tags = VALUES(Following_tags), Reactions = VALUES(Reactions);`
+
+	args := []any{id, privacy.Email, privacy.BirthDate, privacy.Friends, privacy.FavoriteThreads, privacy.FollowingTags, privacy.Reactions}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, args...)
+
+	return err
+}
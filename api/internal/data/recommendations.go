@@ -0,0 +1,147 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Recommendation is a thread recommended to a user, along with the main reason it was picked for them
+type Recommendation struct {
+	Thread
+	Score  int    `json:"score"`
+	Reason string `json:"reason"`
+}
+
+type RecommendationModel struct {
+	DB *sql.DB
+}
+
+// Get returns the threads ranked for the user with the given id
+//
+// Each signal found in the user's activity adds its weight to the score of a thread:
+// followed tags (3), threads favourited by users sharing the same favourites (2),
+// threads by authors the user reacted to (2), friends' activity (2) and category affinity (1).
+// The reason given for a thread is the one of its heaviest signal.
+// Threads authored or already favourited by the user are left out, as are hidden threads.
+func (m RecommendationModel) Get(userID int, filters Filters) ([]*Recommendation, Metadata, error) {
+
+	query := fmt.Sprintf(`
+		WITH signals (Id_threads, Weight, Reason) AS (
+			SELECT DISTINCT tt.Id_threads, 3, CONCAT('because you follow #', tg.Name)
+			FROM tags_users tu
+			INNER JOIN threads_tags tt ON tt.Id_tags = tu.Id_tags
+			INNER JOIN tags tg ON tg.Id_tags = tu.Id_tags
+			WHERE tu.Id_users = ? AND tg.Deleted_at IS NULL
+			UNION ALL
+			SELECT DISTINCT other.Id_threads, 2, CONCAT('because people who like "', ft.Title, '" also like it')
+			FROM threads_users mine
+			INNER JOIN threads ft ON ft.Id_threads = mine.Id_threads
+			INNER JOIN threads_users peer ON peer.Id_threads = mine.Id_threads AND peer.Id_users <> mine.Id_users
+			INNER JOIN threads_users other ON other.Id_users = peer.Id_users AND other.Id_threads <> mine.Id_threads
+			WHERE mine.Id_users = ? AND ft.Deleted_at IS NULL
+			UNION ALL
+			SELECT DISTINCT t.Id_threads, 2, CONCAT('because you reacted to posts by ', u.Username)
+			FROM posts_users pu
+			INNER JOIN posts p ON p.Id_posts = pu.Id_posts
+			INNER JOIN users u ON u.Id_users = p.Id_author
+			INNER JOIN threads t ON t.Id_author = p.Id_author
+			WHERE pu.Id_users = ? AND p.Id_author <> pu.Id_users AND p.Deleted_at IS NULL
+			UNION ALL
+			SELECT DISTINCT activity.Id_threads, 2, CONCAT('because your friend ', u.Username, ' is active in it')
+			FROM friends f
+			INNER JOIN users u ON u.Id_users = IF(f.Id_users_from = ?, f.Id_users_to, f.Id_users_from)
+			INNER JOIN (
+				SELECT Id_threads, Id_author AS Id_users FROM threads
+				UNION
+				SELECT Id_threads, Id_author FROM posts
+				UNION
+				SELECT Id_threads, Id_users FROM threads_users
+			) AS activity ON activity.Id_users = u.Id_users
+			WHERE (f.Id_users_from = ? OR f.Id_users_to = ?) AND f.Status = ?
+			UNION ALL
+			SELECT DISTINCT t.Id_threads, 1, CONCAT('because you like the ', c.Name, ' category')
+			FROM threads t
+			INNER JOIN categories c ON c.Id_categories = t.Id_categories
+			WHERE t.Id_categories IN (
+				SELECT ft.Id_categories
+				FROM threads_users tu
+				INNER JOIN threads ft ON ft.Id_threads = tu.Id_threads
+				WHERE tu.Id_users = ?
+				UNION
+				SELECT pt.Id_categories
+				FROM posts p
+				INNER JOIN threads pt ON pt.Id_threads = p.Id_threads
+				WHERE p.Id_author = ?
+			)
+		), ranked AS (
+			SELECT Id_threads, SUM(Weight) OVER (PARTITION BY Id_threads) AS Score, Reason, ROW_NUMBER() OVER (PARTITION BY Id_threads ORDER BY Weight DESC, Reason ASC) AS Reason_rank
+			FROM signals
+		)
+		SELECT count(*) OVER(), t.Id_threads, t.Title, t.Description, t.Is_public, t.Created_at AS Created_at, t.Updated_at, t.Id_author, u.Username, t.Id_categories, c.Name, t.Status, r.Score AS Score, r.Reason
+		FROM ranked r
+		INNER JOIN threads t ON r.Id_threads = t.Id_threads
+		INNER JOIN users u ON t.Id_author = u.Id_users
+		INNER JOIN categories c ON t.Id_categories = c.Id_categories
+		WHERE r.Reason_rank = 1 AND t.Id_author <> ? AND t.Status <> ? AND t.Deleted_at IS NULL
+		AND NOT EXISTS (SELECT 1 FROM threads_users tu WHERE tu.Id_threads = t.Id_threads AND tu.Id_users = ?)
+		ORDER BY %s %s, t.Id_threads ASC
+		LIMIT ? OFFSET ?;`, filters.sortColumn(), filters.sortDirection())
+
+	args := []any{
+		userID,
+		userID,
+		userID,
+		userID, userID, userID, FriendStatus.Accepted,
+		userID, userID,
+		userID, ThreadStatus.Hidden, userID,
+		filters.limit(), filters.offset(),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	var totalRecords int
+	var recommendations []*Recommendation
+
+	for rows.Next() {
+		var recommendation Recommendation
+
+		err = rows.Scan(
+			&totalRecords,
+			&recommendation.ID,
+			&recommendation.Title,
+			&recommendation.Description,
+			&recommendation.IsPublic,
+			&recommendation.CreatedAt,
+			&recommendation.UpdatedAt,
+			&recommendation.Author.ID,
+			&recommendation.Author.Name,
+			&recommendation.Category.ID,
+			&recommendation.Category.Name,
+			&recommendation.Status,
+			&recommendation.Score,
+			&recommendation.Reason,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		recommendations = append(recommendations, &recommendation)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return recommendations, metadata, nil
+}
@@ -0,0 +1,429 @@
+package data
+
+import (
+	"ForumAPI/internal/validator"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+var ErrDuplicateReport = errors.New("duplicate pending report")
+
+type Report struct {
+	ID         int        `json:"id"`
+	TargetType string     `json:"target_type"`
+	TargetID   int        `json:"target_id"`
+	Reason     string     `json:"reason"`
+	Status     string     `json:"status"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	Reporter   struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	} `json:"reporter"`
+	Actions []ModerationAction `json:"actions,omitempty"`
+	Version int                `json:"version,omitempty"`
+}
+
+type ModerationAction struct {
+	ID        int       `json:"id"`
+	Action    string    `json:"action"`
+	Note      string    `json:"note"`
+	CreatedAt time.Time `json:"created_at"`
+	Moderator struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	} `json:"moderator"`
+}
+
+type reportTarget struct {
+	Post   string
+	Thread string
+	User   string
+}
+
+type reportStatus struct {
+	Pending   string
+	Resolved  string
+	Dismissed string
+}
+
+type moderationActionType struct {
+	Dismiss       string
+	HidePost      string
+	ArchiveThread string
+	HideThread    string
+	BlockUser     string
+}
+
+var (
+	ReportTarget = reportTarget{
+		Post:   "post",
+		Thread: "thread",
+		User:   "user",
+	}
+	ReportStatus = reportStatus{
+		Pending:   "pending",
+		Resolved:  "resolved",
+		Dismissed: "dismissed",
+	}
+	ModerationActionType = moderationActionType{
+		Dismiss:       "dismiss",
+		HidePost:      "hide_post",
+		ArchiveThread: "archive_thread",
+		HideThread:    "hide_thread",
+		BlockUser:     "block_user",
+	}
+	PermittedReportTargets  = []string{ReportTarget.Post, ReportTarget.Thread, ReportTarget.User}
+	PermittedReportStatuses = []string{ReportStatus.Pending, ReportStatus.Resolved, ReportStatus.Dismissed}
+)
+
+// PermittedModerationActions returns the actions a moderator can take on a report about the given type of target
+func PermittedModerationActions(targetType string) []string {
+	switch targetType {
+	case ReportTarget.Post:
+		return []string{ModerationActionType.Dismiss, ModerationActionType.HidePost, ModerationActionType.BlockUser}
+	case ReportTarget.Thread:
+		return []string{ModerationActionType.Dismiss, ModerationActionType.ArchiveThread, ModerationActionType.HideThread, ModerationActionType.BlockUser}
+	case ReportTarget.User:
+		return []string{ModerationActionType.Dismiss, ModerationActionType.BlockUser}
+	default:
+		return nil
+	}
+}
+
+func (report *Report) Validate(v *validator.Validator) {
+	v.Check(validator.PermittedValue(report.TargetType, PermittedReportTargets...), "target_type", "must be a permitted value")
+	v.Check(report.TargetID > 0, "target_id", "must be greater than zero")
+	v.StringCheck(report.Reason, 5, 500, true, "reason")
+}
+
+type ReportModel struct {
+	DB *sql.DB
+}
+
+func (m ReportModel) Insert(report *Report) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO reports (Target_type, Id_target, Id_reporter, Reason)
+		VALUES (?, ?, ?, ?);`
+
+	var mySQLError *mysql.MySQLError
+
+	rs, err := tx.ExecContext(ctx, query, report.TargetType, report.TargetID, report.Reporter.ID, report.Reason)
+	if err != nil {
+		// a user can only have one pending report on the same target (unique key on the pending reporter)
+		if errors.As(err, &mySQLError) && mySQLError.Number == 1062 {
+			return ErrDuplicateReport
+		}
+		return err
+	}
+	reportID, err := rs.LastInsertId()
+	if err != nil {
+		return err
+	}
+	report.ID = int(reportID)
+
+	query = `
+		SELECT Status, Created_at, Version
+		FROM reports
+		WHERE Id_reports = ?;`
+
+	err = tx.QueryRowContext(ctx, query, report.ID).Scan(&report.Status, &report.CreatedAt, &report.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m ReportModel) GetByID(id int) (*Report, error) {
+
+	query := `
+		SELECT r.Id_reports, r.Target_type, r.Id_target, r.Reason, r.Status, r.Created_at, r.Resolved_at, COALESCE(r.Id_reporter, 0), COALESCE(u.Username, ''), r.Version
+		FROM reports r
+		LEFT JOIN users u ON r.Id_reporter = u.Id_users
+		WHERE r.Id_reports = ?;`
+
+	var report Report
+	var resolvedAt sql.NullTime
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&report.ID,
+		&report.TargetType,
+		&report.TargetID,
+		&report.Reason,
+		&report.Status,
+		&report.CreatedAt,
+		&resolvedAt,
+		&report.Reporter.ID,
+		&report.Reporter.Name,
+		&report.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	if resolvedAt.Valid {
+		report.ResolvedAt = &resolvedAt.Time
+	}
+
+	return &report, nil
+}
+
+// GetQueue returns the reports with the given status (and type of target if any)
+func (m ReportModel) GetQueue(status, targetType string, filters Filters) ([]*Report, Metadata, error) {
+
+	var typeCondition string
+	args := []any{status}
+
+	if targetType != "" {
+		typeCondition = " AND r.Target_type = ?"
+		args = append(args, targetType)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), r.Id_reports, r.Target_type, r.Id_target, r.Reason, r.Status, r.Created_at, r.Resolved_at, COALESCE(r.Id_reporter, 0), COALESCE(u.Username, ''), r.Version
+		FROM reports r
+		LEFT JOIN users u ON r.Id_reporter = u.Id_users
+		WHERE r.Status = ?%s
+		ORDER BY %s %s, Id_reports ASC
+		LIMIT ? OFFSET ?;`, typeCondition, filters.sortColumn(), filters.sortDirection())
+
+	args = append(args, filters.limit(), filters.offset())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	var totalRecords int
+	var reports []*Report
+
+	for rows.Next() {
+		var report Report
+		var resolvedAt sql.NullTime
+
+		err = rows.Scan(
+			&totalRecords,
+			&report.ID,
+			&report.TargetType,
+			&report.TargetID,
+			&report.Reason,
+			&report.Status,
+			&report.CreatedAt,
+			&resolvedAt,
+			&report.Reporter.ID,
+			&report.Reporter.Name,
+			&report.Version,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		if resolvedAt.Valid {
+			report.ResolvedAt = &resolvedAt.Time
+		}
+
+		reports = append(reports, &report)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return reports, metadata, nil
+}
+
+func (m ReportModel) GetActions(report *Report) error {
+
+	query := `
+		SELECT ma.Id_moderation_actions, ma.Action, ma.Note, ma.Created_at, COALESCE(ma.Id_moderator, 0), COALESCE(u.Username, '')
+		FROM moderation_actions ma
+		LEFT JOIN users u ON ma.Id_moderator = u.Id_users
+		WHERE ma.Id_reports = ?
+		ORDER BY ma.Created_at ASC, ma.Id_moderation_actions ASC;`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, report.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var action ModerationAction
+
+		err = rows.Scan(
+			&action.ID,
+			&action.Action,
+			&action.Note,
+			&action.CreatedAt,
+			&action.Moderator.ID,
+			&action.Moderator.Name,
+		)
+		if err != nil {
+			return err
+		}
+
+		report.Actions = append(report.Actions, action)
+	}
+
+	return rows.Err()
+}
+
+// Resolve applies the moderation action to the target of the report (or to its author when blocking),
+// records it with the moderator and the note, and closes every pending report on the same target
+func (m ReportModel) Resolve(report *Report, action *ModerationAction, targetAuthorID int) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var query string
+	var args []any
+
+	switch action.Action {
+	case ModerationActionType.HidePost:
+		query = `
+			UPDATE posts
+			SET Status = ?, Version = Version + 1
+			WHERE Id_posts = ?;`
+		args = []any{PostStatus.Hidden, report.TargetID}
+	case ModerationActionType.ArchiveThread:
+		query = `
+			UPDATE threads
+			SET Status = ?, Version = Version + 1
+			WHERE Id_threads = ?;`
+		args = []any{ThreadStatus.Archived, report.TargetID}
+	case ModerationActionType.HideThread:
+		query = `
+			UPDATE threads
+			SET Status = ?, Version = Version + 1
+			WHERE Id_threads = ?;`
+		args = []any{ThreadStatus.Hidden, report.TargetID}
+	case ModerationActionType.BlockUser:
+		query = `
+			UPDATE users
+			SET Status = ?, Suspension_reason = ?, Suspended_until = NULL, Updated_at = CURRENT_TIMESTAMP, Version = Version + 1
+			WHERE Id_users = ?;`
+		args = []any{UserStatus.Blocked, action.Note, targetAuthorID}
+	}
+
+	if query != "" {
+		_, err = tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+	}
+
+	status := ReportStatus.Resolved
+	if action.Action == ModerationActionType.Dismiss {
+		status = ReportStatus.Dismissed
+	}
+
+	query = `
+		UPDATE reports
+		SET Status = ?, Resolved_at = CURRENT_TIMESTAMP, Version = Version + 1
+		WHERE Id_reports = ? AND Version = ?;`
+
+	rs, err := tx.ExecContext(ctx, query, status, report.ID, report.Version)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := rs.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrEditConflict
+	}
+
+	query = `
+		INSERT INTO moderation_actions (Id_reports, Id_moderator, Action, Note)
+		VALUES (?, ?, ?, ?);`
+
+	rs, err = tx.ExecContext(ctx, query, report.ID, action.Moderator.ID, action.Action, action.Note)
+	if err != nil {
+		return err
+	}
+	actionID, err := rs.LastInsertId()
+	if err != nil {
+		return err
+	}
+	action.ID = int(actionID)
+
+	// the other pending reports on the same target are settled by the same action
+	query = `
+		UPDATE reports
+		SET Status = ?, Resolved_at = CURRENT_TIMESTAMP, Version = Version + 1
+		WHERE Target_type = ? AND Id_target = ? AND Status = ?;`
+
+	_, err = tx.ExecContext(ctx, query, status, report.TargetType, report.TargetID, ReportStatus.Pending)
+	if err != nil {
+		return err
+	}
+
+	query = `
+		SELECT r.Status, r.Resolved_at, r.Version, ma.Created_at
+		FROM reports r
+		INNER JOIN moderation_actions ma ON ma.Id_reports = r.Id_reports
+		WHERE ma.Id_moderation_actions = ?;`
+
+	var resolvedAt time.Time
+
+	err = tx.QueryRowContext(ctx, query, action.ID).Scan(&report.Status, &resolvedAt, &report.Version, &action.CreatedAt)
+	if err != nil {
+		return err
+	}
+	report.ResolvedAt = &resolvedAt
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,264 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Editor is the user who wrote a revision
+type Editor struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// PostRevision is the content of a post at one of its versions
+type PostRevision struct {
+	Version   int       `json:"version"`
+	Content   string    `json:"content"`
+	Editor    Editor    `json:"editor"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ThreadRevision is the title and description of a thread at one of its versions
+type ThreadRevision struct {
+	Version     int       `json:"version"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Editor      Editor    `json:"editor"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// postRevisionsQuery selects the revisions of a post (given three times as argument),
+// or its current state as only revision if it has never been edited since the revisions exist
+const postRevisionsQuery = `
+		SELECT r.Version, r.Content, r.Created_at, COALESCE(r.Id_editor, 0) AS Id_editor, COALESCE(u.Username, '') AS Username
+		FROM post_revisions r
+		LEFT JOIN users u ON r.Id_editor = u.Id_users
+		WHERE r.Id_posts = ?
+		UNION ALL
+		SELECT p.Version, p.Content, p.Updated_at, COALESCE(p.Id_author, 0), COALESCE(u.Username, '')
+		FROM posts p
+		LEFT JOIN users u ON p.Id_author = u.Id_users
+		WHERE p.Id_posts = ? AND NOT EXISTS (SELECT 1 FROM post_revisions WHERE Id_posts = ?)`
+
+// threadRevisionsQuery selects the revisions of a thread (given three times as argument),
+// or its current state as only revision if it has never been edited since the revisions exist
+const threadRevisionsQuery = `
+		SELECT r.Version, r.Title, r.Description, r.Created_at, COALESCE(r.Id_editor, 0) AS Id_editor, COALESCE(u.Username, '') AS Username
+		FROM thread_revisions r
+		LEFT JOIN users u ON r.Id_editor = u.Id_users
+		WHERE r.Id_threads = ?
+		UNION ALL
+		SELECT t.Version, t.Title, t.Description, t.Updated_at, COALESCE(t.Id_author, 0), COALESCE(u.Username, '')
+		FROM threads t
+		LEFT JOIN users u ON t.Id_author = u.Id_users
+		WHERE t.Id_threads = ? AND NOT EXISTS (SELECT 1 FROM thread_revisions WHERE Id_threads = ?)`
+
+type RevisionModel struct {
+	DB *sql.DB
+}
+
+// insertOriginalPostRevision records the current state of the post as a revision written by its author,
+// unless the post already has revisions (posts created before the revisions existed have none).
+func insertOriginalPostRevision(ctx context.Context, tx *sql.Tx, postID int) error {
+
+	query := `
+		INSERT INTO post_revisions (Id_posts, Version, Content, Id_editor, Created_at)
+		SELECT Id_posts, Version, Content, Id_author, Updated_at
+		FROM posts
+		WHERE Id_posts = ? AND NOT EXISTS (SELECT 1 FROM post_revisions WHERE Id_posts = ?);`
+
+	_, err := tx.ExecContext(ctx, query, postID, postID)
+
+	return err
+}
+
+func insertPostRevision(ctx context.Context, tx *sql.Tx, post *Post, editorID int) error {
+
+	query := `
+		INSERT INTO post_revisions (Id_posts, Version, Content, Id_editor)
+		VALUES (?, ?, ?, ?);`
+
+	_, err := tx.ExecContext(ctx, query, post.ID, post.Version, post.Content, editorID)
+
+	return err
+}
+
+// insertOriginalThreadRevision records the current title and description of the thread as a revision written by its author,
+// unless the thread already has revisions.
+func insertOriginalThreadRevision(ctx context.Context, tx *sql.Tx, threadID int) error {
+
+	query := `
+		INSERT INTO thread_revisions (Id_threads, Version, Title, Description, Id_editor, Created_at)
+		SELECT Id_threads, Version, Title, Description, Id_author, Updated_at
+		FROM threads
+		WHERE Id_threads = ? AND NOT EXISTS (SELECT 1 FROM thread_revisions WHERE Id_threads = ?);`
+
+	_, err := tx.ExecContext(ctx, query, threadID, threadID)
+
+	return err
+}
+
+func insertThreadRevision(ctx context.Context, tx *sql.Tx, thread *Thread, editorID int) error {
+
+	query := `
+		INSERT INTO thread_revisions (Id_threads, Version, Title, Description, Id_editor)
+		VALUES (?, ?, ?, ?, ?);`
+
+	_, err := tx.ExecContext(ctx, query, thread.ID, thread.Version, thread.Title, thread.Description, editorID)
+
+	return err
+}
+
+// GetForPost returns the revisions of the post, the latest first
+func (m RevisionModel) GetForPost(postID int) ([]*PostRevision, error) {
+
+	query := fmt.Sprintf(`
+		SELECT revisions.Version, revisions.Content, revisions.Created_at, revisions.Id_editor, revisions.Username
+		FROM (%s) AS revisions
+		ORDER BY revisions.Version DESC;`, postRevisionsQuery)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, postID, postID, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []*PostRevision
+
+	for rows.Next() {
+		var revision PostRevision
+
+		err = rows.Scan(
+			&revision.Version,
+			&revision.Content,
+			&revision.CreatedAt,
+			&revision.Editor.ID,
+			&revision.Editor.Name,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		revisions = append(revisions, &revision)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+func (m RevisionModel) GetPostRevision(postID, version int) (*PostRevision, error) {
+
+	query := fmt.Sprintf(`
+		SELECT revisions.Version, revisions.Content, revisions.Created_at, revisions.Id_editor, revisions.Username
+		FROM (%s) AS revisions
+		WHERE revisions.Version = ?;`, postRevisionsQuery)
+
+	var revision PostRevision
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, postID, postID, postID, version).Scan(
+		&revision.Version,
+		&revision.Content,
+		&revision.CreatedAt,
+		&revision.Editor.ID,
+		&revision.Editor.Name,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &revision, nil
+}
+
+// GetForThread returns the revisions of the thread, the latest first
+func (m RevisionModel) GetForThread(threadID int) ([]*ThreadRevision, error) {
+
+	query := fmt.Sprintf(`
+		SELECT revisions.Version, revisions.Title, revisions.Description, revisions.Created_at, revisions.Id_editor, revisions.Username
+		FROM (%s) AS revisions
+		ORDER BY revisions.Version DESC;`, threadRevisionsQuery)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, threadID, threadID, threadID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []*ThreadRevision
+
+	for rows.Next() {
+		var revision ThreadRevision
+
+		err = rows.Scan(
+			&revision.Version,
+			&revision.Title,
+			&revision.Description,
+			&revision.CreatedAt,
+			&revision.Editor.ID,
+			&revision.Editor.Name,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		revisions = append(revisions, &revision)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+func (m RevisionModel) GetThreadRevision(threadID, version int) (*ThreadRevision, error) {
+
+	query := fmt.Sprintf(`
+		SELECT revisions.Version, revisions.Title, revisions.Description, revisions.Created_at, revisions.Id_editor, revisions.Username
+		FROM (%s) AS revisions
+		WHERE revisions.Version = ?;`, threadRevisionsQuery)
+
+	var revision ThreadRevision
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, threadID, threadID, threadID, version).Scan(
+		&revision.Version,
+		&revision.Title,
+		&revision.Description,
+		&revision.CreatedAt,
+		&revision.Editor.ID,
+		&revision.Editor.Name,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &revision, nil
+}
@@ -0,0 +1,374 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	snippetLength = 160
+	snippetRadius = 60
+)
+
+type searchType struct {
+	Thread   string
+	Post     string
+	Tag      string
+	Category string
+}
+
+var (
+	SearchType = searchType{
+		Thread:   "thread",
+		Post:     "post",
+		Tag:      "tag",
+		Category: "category",
+	}
+	PermittedSearchTypes = []string{SearchType.Thread, SearchType.Post, SearchType.Tag, SearchType.Category}
+)
+
+// Highlight locates a matched term in a SearchHit snippet (offset and length are counted in characters)
+type Highlight struct {
+	Offset int `json:"offset"`
+	Length int `json:"length"`
+}
+
+type SearchHit struct {
+	Type       string      `json:"type"`
+	ID         int         `json:"id"`
+	Title      string      `json:"title"`
+	Snippet    string      `json:"snippet"`
+	Status     string      `json:"status,omitempty"`
+	Highlights []Highlight `json:"highlights,omitempty"`
+	Score      float64     `json:"score"`
+	CreatedAt  time.Time   `json:"created_at"`
+	Author     struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	} `json:"author"`
+	Thread struct {
+		ID    int    `json:"id,omitempty"`
+		Title string `json:"title,omitempty"`
+	} `json:"thread,omitempty"`
+}
+
+// SearchFilters is the set of structured filters available for a search
+//
+// CategoryID, TagIDs and Status only apply to threads and posts (Status being the status of the thread):
+// when one of them is set, tags and categories are left out of the results.
+type SearchFilters struct {
+	Types                []string
+	CategoryID           int
+	IncludeSubcategories bool
+	TagIDs               []int
+	AllTags              bool
+	Status               string
+	AuthorID             int
+	CreatedAfter         time.Time
+	CreatedBefore        time.Time
+}
+
+func (f SearchFilters) includes(hitType string) bool {
+	if len(f.Types) > 0 && !slices.Contains(f.Types, hitType) {
+		return false
+	}
+	if f.CategoryID > 0 || len(f.TagIDs) > 0 || f.Status != "" {
+		return hitType == SearchType.Thread || hitType == SearchType.Post
+	}
+	return true
+}
+
+// conditions returns the SQL conditions (and their arguments) common to all types of hits
+func (f SearchFilters) conditions(authorColumn, createdAtColumn string) (string, []any) {
+
+	var conditions string
+	var args []any
+
+	if f.AuthorID > 0 {
+		conditions += fmt.Sprintf(" AND %s = ?", authorColumn)
+		args = append(args, f.AuthorID)
+	}
+	if !f.CreatedAfter.IsZero() {
+		conditions += fmt.Sprintf(" AND %s >= ?", createdAtColumn)
+		args = append(args, f.CreatedAfter)
+	}
+	if !f.CreatedBefore.IsZero() {
+		conditions += fmt.Sprintf(" AND %s < ?", createdAtColumn)
+		args = append(args, f.CreatedBefore)
+	}
+
+	return conditions, args
+}
+
+// threadConditions returns the SQL conditions (and their arguments) restricting the thread aliased t
+//
+// Hidden threads (and their posts) never appear in the results.
+func (f SearchFilters) threadConditions() (string, []any) {
+
+	conditions := " AND t.Status <> ?"
+	args := []any{ThreadStatus.Hidden}
+
+	if f.CategoryID > 0 {
+		condition, conditionArgs := categoryCondition("t.Id_categories", f.CategoryID, f.IncludeSubcategories)
+		conditions += condition
+		args = append(args, conditionArgs...)
+	}
+	if len(f.TagIDs) > 0 {
+		condition, conditionArgs := tagsCondition("t.Id_threads", f.TagIDs, f.AllTags)
+		conditions += condition
+		args = append(args, conditionArgs...)
+	}
+	if f.Status != "" {
+		conditions += " AND t.Status = ?"
+		args = append(args, f.Status)
+	}
+
+	return conditions, args
+}
+
+type SearchModel struct {
+	DB *sql.DB
+}
+
+// query returns the search query and its arguments, or an empty query if no type is searched.
+// Every member of the union aliases its columns, the derived table taking its column names from the first one.
+func (f SearchFilters) query(search string, filters Filters) (string, []any) {
+
+	var subQueries []string
+	var args []any
+
+	if f.includes(SearchType.Thread) {
+		conditions, conditionArgs := f.conditions("t.Id_author", "t.Created_at")
+		threadConditions, threadArgs := f.threadConditions()
+
+		subQueries = append(subQueries, fmt.Sprintf(`
+			SELECT 'thread' AS Type, t.Id_threads AS Id, t.Title AS Title, t.Description AS Content, t.Status AS Status, t.Id_author AS Id_author, u.Username AS Username, 0 AS Id_threads, '' AS Thread_title, t.Created_at AS Created_at, MATCH(t.Title, t.Description) AGAINST (? IN NATURAL LANGUAGE MODE) AS Score
+			FROM threads t
+			INNER JOIN users u ON t.Id_author = u.Id_users
+			WHERE MATCH(t.Title, t.Description) AGAINST (? IN NATURAL LANGUAGE MODE) AND t.Deleted_at IS NULL%s%s`, conditions, threadConditions))
+
+		args = append(args, search, search)
+		args = append(args, conditionArgs...)
+		args = append(args, threadArgs...)
+	}
+
+	if f.includes(SearchType.Post) {
+		conditions, conditionArgs := f.conditions("p.Id_author", "p.Created_at")
+		threadConditions, threadArgs := f.threadConditions()
+
+		subQueries = append(subQueries, fmt.Sprintf(`
+			SELECT 'post' AS Type, p.Id_posts AS Id, t.Title AS Title, p.Content AS Content, '' AS Status, p.Id_author AS Id_author, u.Username AS Username, t.Id_threads AS Id_threads, t.Title AS Thread_title, p.Created_at AS Created_at, MATCH(p.Content) AGAINST (? IN NATURAL LANGUAGE MODE) AS Score
+			FROM posts p
+			INNER JOIN threads t ON p.Id_threads = t.Id_threads
+			INNER JOIN users u ON p.Id_author = u.Id_users
+			WHERE MATCH(p.Content) AGAINST (? IN NATURAL LANGUAGE MODE) AND p.Status <> ? AND p.Deleted_at IS NULL%s%s`, conditions, threadConditions))
+
+		args = append(args, search, search, PostStatus.Hidden)
+		args = append(args, conditionArgs...)
+		args = append(args, threadArgs...)
+	}
+
+	if f.includes(SearchType.Tag) {
+		conditions, conditionArgs := f.conditions("t.Id_author", "t.Created_at")
+
+		subQueries = append(subQueries, fmt.Sprintf(`
+			SELECT 'tag' AS Type, t.Id_tags AS Id, t.Name AS Title, t.Name AS Content, '' AS Status, t.Id_author AS Id_author, u.Username AS Username, 0 AS Id_threads, '' AS Thread_title, t.Created_at AS Created_at, MATCH(t.Name) AGAINST (? IN NATURAL LANGUAGE MODE) AS Score
+			FROM tags t
+			INNER JOIN users u ON t.Id_author = u.Id_users
+			WHERE MATCH(t.Name) AGAINST (? IN NATURAL LANGUAGE MODE) AND t.Deleted_at IS NULL%s`, conditions))
+
+		args = append(args, search, search)
+		args = append(args, conditionArgs...)
+	}
+
+	if f.includes(SearchType.Category) {
+		conditions, conditionArgs := f.conditions("c.Id_author", "c.Created_at")
+
+		subQueries = append(subQueries, fmt.Sprintf(`
+			SELECT 'category' AS Type, c.Id_categories AS Id, c.Name AS Title, c.Name AS Content, '' AS Status, c.Id_author AS Id_author, u.Username AS Username, 0 AS Id_threads, '' AS Thread_title, c.Created_at AS Created_at, MATCH(c.Name) AGAINST (? IN NATURAL LANGUAGE MODE) AS Score
+			FROM categories c
+			INNER JOIN users u ON c.Id_author = u.Id_users
+			WHERE MATCH(c.Name) AGAINST (? IN NATURAL LANGUAGE MODE) AND c.Deleted_at IS NULL%s`, conditions))
+
+		args = append(args, search, search)
+		args = append(args, conditionArgs...)
+	}
+
+	if len(subQueries) == 0 {
+		return "", nil
+	}
+
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), hits.Type, hits.Id, hits.Title, hits.Content, hits.Status, hits.Id_author, hits.Username, hits.Id_threads, hits.Thread_title, hits.Created_at, hits.Score
+		FROM (%s
+		) AS hits
+		ORDER BY %s %s, hits.Type ASC, hits.Id ASC
+		LIMIT ? OFFSET ?;`, strings.Join(subQueries, `
+			UNION ALL`), filters.sortColumn(), filters.sortDirection())
+
+	args = append(args, filters.limit(), filters.offset())
+
+	return query, args
+}
+
+func (m SearchModel) Search(search string, searchFilters SearchFilters, filters Filters) ([]*SearchHit, Metadata, error) {
+
+	query, args := searchFilters.query(search, filters)
+	if query == "" {
+		return nil, Metadata{}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	terms := searchTerms(search)
+
+	var totalRecords int
+	var hits []*SearchHit
+
+	for rows.Next() {
+		var hit SearchHit
+		var content string
+
+		err = rows.Scan(
+			&totalRecords,
+			&hit.Type,
+			&hit.ID,
+			&hit.Title,
+			&content,
+			&hit.Status,
+			&hit.Author.ID,
+			&hit.Author.Name,
+			&hit.Thread.ID,
+			&hit.Thread.Title,
+			&hit.CreatedAt,
+			&hit.Score,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		// a thread matching only by its title gets its title as snippet
+		if hit.Type == SearchType.Thread && (content == "" || !containsAny(content, terms)) {
+			content = hit.Title
+		}
+
+		hit.Snippet, hit.Highlights = buildSnippet(content, terms)
+
+		hits = append(hits, &hit)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return hits, metadata, nil
+}
+
+// searchTerms splits the search in lowercase words, ignoring punctuation and one-letter words
+func searchTerms(search string) []string {
+
+	var terms []string
+
+	for _, word := range strings.FieldsFunc(strings.ToLower(search), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}) {
+		if len([]rune(word)) > 1 && !slices.Contains(terms, word) {
+			terms = append(terms, word)
+		}
+	}
+
+	// longest terms first, so that they take precedence when highlighting
+	slices.SortFunc(terms, func(a, b string) int {
+		return len([]rune(b)) - len([]rune(a))
+	})
+
+	return terms
+}
+
+func containsAny(text string, terms []string) bool {
+	text = strings.ToLower(text)
+	for _, term := range terms {
+		if strings.Contains(text, term) {
+			return true
+		}
+	}
+	return false
+}
+
+// buildSnippet cuts a window of text around the first matched term and locates every term inside it
+func buildSnippet(text string, terms []string) (string, []Highlight) {
+
+	runes := []rune(text)
+	lower := []rune(strings.ToLower(text))
+
+	// finding the first match
+	first := -1
+	for i := range lower {
+		for _, term := range terms {
+			if hasPrefixAt(lower, []rune(term), i) {
+				first = i
+				break
+			}
+		}
+		if first != -1 {
+			break
+		}
+	}
+
+	// setting the window around the first match
+	start := 0
+	if first > snippetRadius {
+		start = first - snippetRadius
+	}
+	end := min(len(runes), start+snippetLength)
+	if end-start < snippetLength {
+		start = max(0, end-snippetLength)
+	}
+
+	var snippet []rune
+	if start > 0 {
+		snippet = append(snippet, '…')
+	}
+	prefix := len(snippet)
+	snippet = append(snippet, runes[start:end]...)
+	if end < len(runes) {
+		snippet = append(snippet, '…')
+	}
+
+	// locating all the terms in the window
+	var highlights []Highlight
+	window := lower[start:end]
+	for i := 0; i < len(window); i++ {
+		for _, term := range terms {
+			termRunes := []rune(term)
+			if hasPrefixAt(window, termRunes, i) {
+				highlights = append(highlights, Highlight{Offset: prefix + i, Length: len(termRunes)})
+				i += len(termRunes) - 1
+				break
+			}
+		}
+	}
+
+	return string(snippet), highlights
+}
+
+func hasPrefixAt(text, prefix []rune, i int) bool {
+	if i+len(prefix) > len(text) {
+		return false
+	}
+	return slices.Equal(text[i:i+len(prefix)], prefix)
+}
@@ -0,0 +1,114 @@
+package data
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// unionAliases returns the column aliases of each member of the union selected from the derived table of the query
+// (alias being the derived table's name), failing if a column isn't aliased
+func unionAliases(t *testing.T, query, alias string) [][]string {
+
+	start := strings.Index(query, "FROM (")
+	end := strings.Index(query, ") AS "+alias)
+	if start < 0 || end < 0 {
+		t.Fatalf("no derived table %s in %q", alias, query)
+	}
+
+	var members [][]string
+
+	for _, member := range strings.Split(query[start+len("FROM ("):end], "UNION ALL") {
+		selectList := strings.TrimSpace(member)
+		selectList = strings.TrimPrefix(selectList, "SELECT ")
+		selectList, _, _ = strings.Cut(selectList, "\n")
+
+		// splitting on the commas outside the parentheses
+		var columns []string
+		depth, from := 0, 0
+		for i, r := range selectList {
+			switch r {
+			case '(':
+				depth++
+			case ')':
+				depth--
+			case ',':
+				if depth == 0 {
+					columns = append(columns, selectList[from:i])
+					from = i + 1
+				}
+			}
+		}
+		columns = append(columns, selectList[from:])
+
+		var aliases []string
+		for _, column := range columns {
+			_, name, ok := strings.Cut(column, " AS ")
+			if !ok {
+				t.Fatalf("column %q not aliased in %q", strings.TrimSpace(column), member)
+			}
+			aliases = append(aliases, strings.TrimSpace(name))
+		}
+		members = append(members, aliases)
+	}
+
+	return members
+}
+
+// checkUnion checks that every member of the union has the same distinct aliases,
+// among which the columns of the derived table used by the outer query
+func checkUnion(t *testing.T, query, alias string, wantMembers int) {
+
+	members := unionAliases(t, query, alias)
+	if len(members) != wantMembers {
+		t.Fatalf("got %d members; want %d", len(members), wantMembers)
+	}
+
+	for _, aliases := range members {
+		if !slices.Equal(aliases, members[0]) {
+			t.Errorf("got columns %v; want %v", aliases, members[0])
+		}
+		for i, name := range aliases {
+			if slices.Contains(aliases[i+1:], name) {
+				t.Errorf("duplicate column %s in %v", name, aliases)
+			}
+		}
+	}
+
+	for _, match := range regexp.MustCompile(alias+`\.(\w+)`).FindAllStringSubmatch(query, -1) {
+		if !slices.Contains(members[0], match[1]) {
+			t.Errorf("unknown column %s.%s", alias, match[1])
+		}
+	}
+}
+
+func TestSearchQuery(t *testing.T) {
+
+	filters := Filters{Page: 1, PageSize: 20, Sort: "-Score", SortSafelist: []string{"-Score", "Score"}}
+
+	tests := []struct {
+		name        string
+		types       []string
+		wantMembers int
+	}{
+		{name: "All types", wantMembers: 4},
+		{name: "Threads", types: []string{SearchType.Thread}, wantMembers: 1},
+		{name: "Posts", types: []string{SearchType.Post}, wantMembers: 1},
+		{name: "Tags", types: []string{SearchType.Tag}, wantMembers: 1},
+		{name: "Categories", types: []string{SearchType.Category}, wantMembers: 1},
+		{name: "Posts and tags", types: []string{SearchType.Post, SearchType.Tag}, wantMembers: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, args := SearchFilters{Types: tt.types}.query("golang", filters)
+
+			checkUnion(t, query, "hits", tt.wantMembers)
+
+			if placeholders := strings.Count(query, "?"); placeholders != len(args) {
+				t.Errorf("got %d arguments for %d placeholders", len(args), placeholders)
+			}
+		})
+	}
+}
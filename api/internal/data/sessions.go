@@ -0,0 +1,163 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Session is a device on which a user logged in, tying together the successive authentication and refresh tokens of that device
+type Session struct {
+	ID         int       `json:"id"`
+	UserID     int       `json:"-"`
+	Device     string    `json:"device"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	Current    bool      `json:"current"`
+}
+
+type SessionModel struct {
+	DB *sql.DB
+}
+
+func (m SessionModel) Insert(session *Session) error {
+
+	query := `
+		INSERT INTO user_sessions (Id_users, Device, User_agent, Ip)
+		VALUES (?, ?, ?, ?);`
+
+	args := []any{session.UserID, session.Device, session.UserAgent, session.IP}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	res, err := m.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	session.ID = int(id)
+	session.CreatedAt = time.Now()
+	session.LastSeenAt = session.CreatedAt
+
+	return nil
+}
+
+// GetByUserID returns the sessions of the user still holding valid tokens, the most recently used first
+func (m SessionModel) GetByUserID(userID int) ([]*Session, error) {
+
+	query := `
+		SELECT s.Id_user_sessions, s.Device, s.User_agent, s.Ip, s.Created_at, s.Last_seen_at
+		FROM user_sessions s
+		WHERE s.Id_users = ?
+		AND EXISTS (SELECT 1 FROM tokens t WHERE t.Id_user_sessions = s.Id_user_sessions AND t.Expiry > CURRENT_TIMESTAMP)
+		ORDER BY s.Last_seen_at DESC;`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []*Session
+
+	for rows.Next() {
+		session := Session{UserID: userID}
+		err = rows.Scan(&session.ID, &session.Device, &session.UserAgent, &session.IP, &session.CreatedAt, &session.LastSeenAt)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, &session)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// Touch updates the last time the session was used, at most once a minute
+func (m SessionModel) Touch(id int) error {
+
+	query := `
+		UPDATE user_sessions
+		SET Last_seen_at = CURRENT_TIMESTAMP
+		WHERE Id_user_sessions = ? AND Last_seen_at < CURRENT_TIMESTAMP - INTERVAL 1 MINUTE;`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, id)
+
+	return err
+}
+
+// Delete revokes the session of the user, along with its tokens
+func (m SessionModel) Delete(id, userID int) error {
+
+	query := `
+		DELETE FROM user_sessions
+		WHERE Id_user_sessions = ? AND Id_users = ?;`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	res, err := m.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// DeleteAllForUser revokes every session of the user, along with their tokens
+func (m SessionModel) DeleteAllForUser(userID int) error {
+
+	query := `
+		DELETE FROM user_sessions
+		WHERE Id_users = ?;`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, userID)
+
+	return err
+}
+
+// DeleteEmpty removes the sessions whose tokens all expired or were revoked
+// (leaving alone the ones just created, whose tokens may not be inserted yet)
+func (m SessionModel) DeleteEmpty() error {
+
+	query := `
+		DELETE FROM user_sessions
+		WHERE Created_at < CURRENT_TIMESTAMP - INTERVAL 1 MINUTE
+		AND NOT EXISTS (SELECT 1 FROM tokens WHERE tokens.Id_user_sessions = user_sessions.Id_user_sessions);`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query)
+
+	return err
+}
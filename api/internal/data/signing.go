@@ -0,0 +1,38 @@
+package data
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+)
+
+// signingKey signs the tokens given to the clients (the cursors, the unsubscribe links) so that they can't forge them.
+// It is random by default, making the tokens invalid after a restart, unless set is called.
+type signingKey struct {
+	key []byte
+}
+
+func newSigningKey() *signingKey {
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	if err != nil {
+		panic(err)
+	}
+	return &signingKey{key: key}
+}
+
+func (k *signingKey) set(key string) {
+	k.key = []byte(key)
+}
+
+// sign returns the HMAC-SHA256 of the payload
+func (k *signingKey) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, k.key)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// verify tells whether the signature is the payload's one, in constant time
+func (k *signingKey) verify(payload, signature []byte) bool {
+	return hmac.Equal(signature, k.sign(payload))
+}
@@ -0,0 +1,301 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+var (
+	ErrInvalidUnsubscribeToken = errors.New("invalid unsubscribe token")
+
+	SubscriptionLevel = &subscriptionLevel{
+		None:      "none",
+		InApp:     "in_app",
+		Immediate: "immediate",
+		Daily:     "daily",
+	}
+	PermittedSubscriptionLevels = []string{SubscriptionLevel.None, SubscriptionLevel.InApp, SubscriptionLevel.Immediate, SubscriptionLevel.Daily}
+)
+
+type subscriptionLevel struct {
+	None      string
+	InApp     string
+	Immediate string
+	Daily     string
+}
+
+// unsubscribeKey signs the unsubscribe links sent by email
+var unsubscribeKey = newSigningKey()
+
+func SetUnsubscribeKey(key string) {
+	unsubscribeKey.set(key)
+}
+
+// UnsubscribeToken returns the signed token unsubscribing the user from the thread without authentication
+func UnsubscribeToken(userID, threadID int) string {
+
+	payload := fmt.Sprintf("%d.%d", userID, threadID)
+
+	return fmt.Sprintf("%s.%s", payload, base64.RawURLEncoding.EncodeToString(unsubscribeKey.sign([]byte(payload))))
+}
+
+// ParseUnsubscribeToken checks the token's signature and returns the user and thread it unsubscribes
+func ParseUnsubscribeToken(token string) (userID, threadID int, err error) {
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return 0, 0, ErrInvalidUnsubscribeToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return 0, 0, ErrInvalidUnsubscribeToken
+	}
+
+	if !unsubscribeKey.verify([]byte(parts[0]+"."+parts[1]), signature) {
+		return 0, 0, ErrInvalidUnsubscribeToken
+	}
+
+	userID, err = strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, ErrInvalidUnsubscribeToken
+	}
+	threadID, err = strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0, ErrInvalidUnsubscribeToken
+	}
+
+	return userID, threadID, nil
+}
+
+type Subscription struct {
+	ThreadID int    `json:"thread_id"`
+	Level    string `json:"level"`
+}
+
+// Subscriber is a user to send an email to about a thread
+type Subscriber struct {
+	ID    int
+	Name  string
+	Email string
+}
+
+// Digest gathers the posts of the threads a user subscribed to since their last digest
+type Digest struct {
+	UserID   int
+	Username string
+	Email    string
+	Threads  []*DigestThread
+}
+
+type DigestThread struct {
+	ID               int
+	Title            string
+	UnsubscribeToken string
+	Posts            []*DigestPost
+}
+
+type DigestPost struct {
+	ID         int
+	AuthorName string
+	CreatedAt  time.Time
+}
+
+type SubscriptionModel struct {
+	DB *sql.DB
+}
+
+// Get returns the subscription of the user to the thread, at the level none if they never subscribed
+func (m SubscriptionModel) Get(userID, threadID int) (*Subscription, error) {
+
+	query := `
+		SELECT Level
+		FROM thread_subscriptions
+		WHERE Id_users = ? AND Id_threads = ?;`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	subscription := &Subscription{
+		ThreadID: threadID,
+	}
+
+	err := m.DB.QueryRowContext(ctx, query, userID, threadID).Scan(&subscription.Level)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			subscription.Level = SubscriptionLevel.None
+		default:
+			return nil, err
+		}
+	}
+
+	return subscription, nil
+}
+
+// Set sets the level of the user's subscription to the thread.
+// The digests of a new daily subscription start from now, without the thread's older posts.
+func (m SubscriptionModel) Set(userID, threadID int, level string) error {
+
+	query := `
+		INSERT INTO thread_subscriptions (Id_users, Id_threads, Level)
+		VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE Last_digest_at = IF(Level = VALUES(Level), Last_digest_at, CURRENT_TIMESTAMP), Level = VALUES(Level);`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, userID, threadID, level)
+	if err != nil {
+		var mySQLError *mysql.MySQLError
+		if errors.As(err, &mySQLError) && mySQLError.Number == 1452 {
+			return ErrRecordNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Subscribe subscribes the user to the in-app notifications of the thread, unless they already chose a level
+func (m SubscriptionModel) Subscribe(userID, threadID int) error {
+
+	query := `
+		INSERT IGNORE INTO thread_subscriptions (Id_users, Id_threads, Level)
+		VALUES (?, ?, ?);`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, userID, threadID, SubscriptionLevel.InApp)
+
+	return err
+}
+
+// GetImmediateSubscribers returns the users to email as soon as a post is added to the thread, except the excluded ones
+func (m SubscriptionModel) GetImmediateSubscribers(threadID int, excluded ...int) ([]*Subscriber, error) {
+
+	query := `
+		SELECT u.Id_users, u.Username, u.Email
+		FROM thread_subscriptions s
+		INNER JOIN users u ON s.Id_users = u.Id_users
+		WHERE s.Id_threads = ? AND s.Level = ? AND u.Status = ?`
+
+	args := []any{threadID, SubscriptionLevel.Immediate, UserStatus.Activated}
+
+	if len(excluded) > 0 {
+		query += fmt.Sprintf(" AND s.Id_users NOT IN (%s)", placeholders(len(excluded)))
+		for _, id := range excluded {
+			args = append(args, id)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query+";", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subscribers []*Subscriber
+
+	for rows.Next() {
+		var subscriber Subscriber
+		if err = rows.Scan(&subscriber.ID, &subscriber.Name, &subscriber.Email); err != nil {
+			return nil, err
+		}
+		subscribers = append(subscribers, &subscriber)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return subscribers, nil
+}
+
+// GetDigests returns the digests due at the time until, for the daily subscriptions whose last digest is a day old.
+// Only the posts created in between by the other users are gathered, so the users without any are left out.
+func (m SubscriptionModel) GetDigests(until time.Time) ([]*Digest, error) {
+
+	query := `
+		SELECT u.Id_users, u.Username, u.Email, t.Id_threads, t.Title, p.Id_posts, a.Username, p.Created_at
+		FROM thread_subscriptions s
+		INNER JOIN users u ON s.Id_users = u.Id_users
+		INNER JOIN threads t ON s.Id_threads = t.Id_threads
+		INNER JOIN posts p ON p.Id_threads = s.Id_threads
+		INNER JOIN users a ON p.Id_author = a.Id_users
+		WHERE s.Level = ? AND s.Last_digest_at <= ? AND u.Status = ?
+		    AND p.Created_at > s.Last_digest_at AND p.Created_at <= ? AND p.Id_author <> s.Id_users
+		    AND p.Status <> ? AND p.Deleted_at IS NULL AND t.Deleted_at IS NULL
+		ORDER BY u.Id_users, t.Id_threads, p.Created_at;`
+
+	args := []any{SubscriptionLevel.Daily, until.Add(-24 * time.Hour), UserStatus.Activated, until, PostStatus.Hidden}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var digests []*Digest
+	var digest *Digest
+	var thread *DigestThread
+
+	for rows.Next() {
+		var userID, threadID int
+		var username, email, title string
+		var post DigestPost
+
+		err = rows.Scan(&userID, &username, &email, &threadID, &title, &post.ID, &post.AuthorName, &post.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		if digest == nil || digest.UserID != userID {
+			digest = &Digest{UserID: userID, Username: username, Email: email}
+			digests = append(digests, digest)
+			thread = nil
+		}
+		if thread == nil || thread.ID != threadID {
+			thread = &DigestThread{ID: threadID, Title: title, UnsubscribeToken: UnsubscribeToken(userID, threadID)}
+			digest.Threads = append(digest.Threads, thread)
+		}
+		thread.Posts = append(thread.Posts, &post)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return digests, nil
+}
+
+// MarkDigestSent moves the last digest of the user's daily subscriptions due at the time until to that time
+func (m SubscriptionModel) MarkDigestSent(userID int, until time.Time) error {
+
+	query := `
+		UPDATE thread_subscriptions
+		SET Last_digest_at = ?
+		WHERE Id_users = ? AND Level = ? AND Last_digest_at <= ?;`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, until, userID, SubscriptionLevel.Daily, until.Add(-24*time.Hour))
+
+	return err
+}
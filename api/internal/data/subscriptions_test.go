@@ -0,0 +1,56 @@
+package data
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestUnsubscribeToken(t *testing.T) {
+
+	SetUnsubscribeKey("secret")
+
+	token := UnsubscribeToken(42, 7)
+
+	userID, threadID, err := ParseUnsubscribeToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if userID != 42 || threadID != 7 {
+		t.Errorf("got user %d and thread %d; want user 42 and thread 7", userID, threadID)
+	}
+
+	_, signature, _ := strings.Cut(strings.TrimPrefix(token, "42."), ".")
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "Other user", token: "43.7." + signature},
+		{name: "Other thread", token: "42.8." + signature},
+		{name: "Wrong signature", token: "42.7." + strings.Repeat("A", len(signature))},
+		{name: "No signature", token: "42.7"},
+		{name: "Invalid encoding", token: "42.7.!"},
+		{name: "Not a number", token: "a.7." + signature},
+		{name: "Empty", token: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := ParseUnsubscribeToken(tt.token)
+			if !errors.Is(err, ErrInvalidUnsubscribeToken) {
+				t.Errorf("got %v; want %v", err, ErrInvalidUnsubscribeToken)
+			}
+		})
+	}
+
+	t.Run("Other key", func(t *testing.T) {
+		SetUnsubscribeKey("other")
+		defer SetUnsubscribeKey("secret")
+
+		_, _, err := ParseUnsubscribeToken(token)
+		if !errors.Is(err, ErrInvalidUnsubscribeToken) {
+			t.Errorf("got %v; want %v", err, ErrInvalidUnsubscribeToken)
+		}
+	})
+}
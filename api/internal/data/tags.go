@@ -147,7 +147,7 @@ func (m TagModel) GetByID(id int) (*Tag, error) {
 		SELECT t.Id_tags, t.Name, t.Created_at, t.Updated_at, t.Id_author, u.Username, t.Version
 		FROM tags t
 		INNER JOIN users u on t.Id_author = u.Id_users
-		WHERE t.Id_tags = ?;`
+		WHERE t.Id_tags = ? AND t.Deleted_at IS NULL;`
 
 	var tag Tag
 
@@ -181,7 +181,7 @@ func (m TagModel) GetByAuthorID(id int) ([]Tag, error) {
 	query := `
 		SELECT Id_tags, Name, Created_at, Updated_at, Version
 		FROM tags
-		WHERE Id_author = ?;`
+		WHERE Id_author = ? AND Deleted_at IS NULL;`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -246,6 +246,7 @@ func (m TagModel) GetPopular() ([]*Tag, error) {
 																						WHERE tu.Id_tags = t.Id_tags) AS popularity
 		FROM tags t
 		INNER JOIN users u on t.Id_author = u.Id_users
+		WHERE t.Deleted_at IS NULL
 		ORDER BY popularity DESC, Id_tags ASC
 		LIMIT 10;`
 
@@ -295,15 +296,22 @@ func (m TagModel) Get(search string, filters Filters) ([]*Tag, Metadata, error)
 		search = "%"
 	}
 
+	keyset, keysetArgs, err := filters.keyset("t", "t.Id_tags")
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
 	query := fmt.Sprintf(`
-		SELECT count(*) OVER(), t.Id_tags, t.Name, t.Created_at, t.Updated_at, t.Id_author, u.Username, t.Version
+		SELECT %s, t.Id_tags, t.Name, t.Created_at, t.Updated_at, t.Id_author, u.Username, t.Version, %s
 		FROM tags t
 		INNER JOIN users u ON t.Id_author = u.Id_users
-		WHERE t.Name LIKE ?
-		ORDER BY %s %s, Id_tags ASC
-		LIMIT ? OFFSET ?;`, filters.sortColumn(), filters.sortDirection())
+		WHERE t.Name LIKE ? AND t.Deleted_at IS NULL%s
+		ORDER BY %s %s, Id_tags %s
+		LIMIT ? OFFSET ?;`, filters.countColumn(), filters.sortKey("t"), keyset, filters.sortKey("t"), filters.sortDirection(), filters.idDirection())
 
-	args := []any{search, filters.limit(), filters.offset()}
+	args := []any{search}
+	args = append(args, keysetArgs...)
+	args = append(args, filters.limit(), filters.offset())
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -322,9 +330,11 @@ func (m TagModel) Get(search string, filters Filters) ([]*Tag, Metadata, error)
 
 	var tags []*Tag
 	var totalRecords int
+	var cursors []Cursor
 
 	for rows.Next() {
 		var tag Tag
+		var sortKey any
 		if err := rows.Scan(
 			&totalRecords,
 			&tag.ID,
@@ -333,10 +343,12 @@ func (m TagModel) Get(search string, filters Filters) ([]*Tag, Metadata, error)
 			&tag.UpdatedAt,
 			&tag.Author.ID,
 			&tag.Author.Name,
-			&tag.Version); err != nil {
+			&tag.Version,
+			&sortKey); err != nil {
 			log.Fatal(err)
 		}
 		tags = append(tags, &tag)
+		cursors = append(cursors, newCursor(filters.Sort, sortKey, tag.ID))
 	}
 	rerr := rows.Close()
 	if rerr != nil {
@@ -346,7 +358,7 @@ func (m TagModel) Get(search string, filters Filters) ([]*Tag, Metadata, error)
 		log.Fatal(err)
 	}
 
-	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	tags, metadata := paginate(tags, cursors, totalRecords, filters)
 
 	return tags, metadata, nil
 }
@@ -358,7 +370,7 @@ func (m TagModel) GetByThread(id int) ([]Tag, error) {
 		FROM threads_tags tt
 		INNER JOIN tags t ON tt.Id_tags = t.Id_tags
 		INNER JOIN users u ON t.Id_author = u.Id_users
-		WHERE tt.Id_threads = ?;`
+		WHERE tt.Id_threads = ? AND t.Deleted_at IS NULL;`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -401,7 +413,7 @@ func (m TagModel) GetByFollowingUserID(id int) ([]Tag, error) {
 		SELECT tu.Id_tags, t.Name
 		FROM tags_users tu
 		INNER JOIN tags t ON tu.Id_tags = t.Id_tags
-		WHERE Id_users = ?;`
+		WHERE Id_users = ? AND t.Deleted_at IS NULL;`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -443,7 +455,7 @@ func (m TagModel) Update(tag *Tag, removeThreads []int) error {
 	query := `
 		UPDATE tags 
 		SET Name = ?, Id_author = ?, Version = Version + 1
-		WHERE Id_tags = ? AND Version = ?;`
+		WHERE Id_tags = ? AND Version = ? AND Deleted_at IS NULL;`
 
 	args := []any{tag.Name, tag.Author.ID, tag.ID, tag.Version}
 
@@ -609,11 +621,16 @@ func (m TagModel) Follow(user *User, id int) error {
 	query = `
 		SELECT Name
 		FROM tags
-		WHERE Id_tags = ?;`
+		WHERE Id_tags = ? AND Deleted_at IS NULL;`
 
 	err = tx.QueryRowContext(ctx, query, id).Scan(&followingTag.Name)
 	if err != nil {
-		return err
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
 	}
 
 	if err = tx.Commit(); err != nil {
@@ -666,16 +683,18 @@ func (m TagModel) Unfollow(user *User, id int) error {
 	return nil
 }
 
-func (m TagModel) Delete(id int) error {
+// Delete moves the tag to the trash
+func (m TagModel) Delete(id, userID int) error {
 
 	query := `
-		DELETE FROM tags
-		WHERE Id_tags = ?;`
+		UPDATE tags
+		SET Deleted_at = NOW(), Deleted_by = ?
+		WHERE Id_tags = ? AND Deleted_at IS NULL;`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	result, err := m.DB.ExecContext(ctx, query, id)
+	result, err := m.DB.ExecContext(ctx, query, userID, id)
 	if err != nil {
 		return err
 	}
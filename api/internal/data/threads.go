@@ -43,6 +43,64 @@ func (thread *Thread) Validate(v *validator.Validator) {
 	v.Check(thread.Category.ID != 0, "parent_category.id", "must be provided")
 }
 
+// ThreadFilters is the set of structured filters available when listing threads
+type ThreadFilters struct {
+	AuthorID             int
+	CategoryID           int
+	IncludeSubcategories bool
+	TagIDs               []int
+	AllTags              bool
+	CreatedAfter         time.Time
+	CreatedBefore        time.Time
+	Status               string
+	IsPublic             *bool
+	IncludeHidden        bool // only for the moderation
+}
+
+// conditions returns the SQL conditions (and their arguments) restricting the thread aliased t
+func (f ThreadFilters) conditions() (string, []any) {
+
+	var conditions string
+	var args []any
+
+	if f.AuthorID > 0 {
+		conditions += " AND t.Id_author = ?"
+		args = append(args, f.AuthorID)
+	}
+	if f.CategoryID > 0 {
+		condition, conditionArgs := categoryCondition("t.Id_categories", f.CategoryID, f.IncludeSubcategories)
+		conditions += condition
+		args = append(args, conditionArgs...)
+	}
+	if len(f.TagIDs) > 0 {
+		condition, conditionArgs := tagsCondition("t.Id_threads", f.TagIDs, f.AllTags)
+		conditions += condition
+		args = append(args, conditionArgs...)
+	}
+	if !f.CreatedAfter.IsZero() {
+		conditions += " AND t.Created_at >= ?"
+		args = append(args, f.CreatedAfter)
+	}
+	if !f.CreatedBefore.IsZero() {
+		conditions += " AND t.Created_at < ?"
+		args = append(args, f.CreatedBefore)
+	}
+	if f.Status != "" {
+		conditions += " AND t.Status = ?"
+		args = append(args, f.Status)
+	}
+	if f.IsPublic != nil {
+		conditions += " AND t.Is_public = ?"
+		args = append(args, *f.IsPublic)
+	}
+	if !f.IncludeHidden {
+		conditions += " AND t.Status <> ?"
+		args = append(args, ThreadStatus.Hidden)
+	}
+
+	return conditions, args
+}
+
 type ThreadModel struct {
 	DB *sql.DB
 }
@@ -110,7 +168,7 @@ func (m ThreadModel) Insert(thread *Thread) error {
 		SELECT t.Created_at, c.Name, t.Version
 		FROM threads t
 		INNER JOIN categories c ON t.Id_categories = c.Id_categories
-		WHERE t.Id_threads = ?;`
+		WHERE t.Id_threads = ? AND c.Deleted_at IS NULL;`
 
 	err = tx.QueryRowContext(ctx, query, thread.ID).Scan(&thread.CreatedAt, &thread.Category.Name, &thread.Version)
 	if err != nil {
@@ -129,7 +187,7 @@ func (m ThreadModel) Insert(thread *Thread) error {
 	return nil
 }
 
-func (m ThreadModel) Get(search string, filters Filters) ([]*Thread, Metadata, error) {
+func (m ThreadModel) Get(search string, threadFilters ThreadFilters, filters Filters) ([]*Thread, Metadata, error) {
 
 	if search != "" {
 		search = fmt.Sprintf("%%%s%%", search)
@@ -137,16 +195,25 @@ func (m ThreadModel) Get(search string, filters Filters) ([]*Thread, Metadata, e
 		search = "%"
 	}
 
+	conditions, conditionArgs := threadFilters.conditions()
+	keyset, keysetArgs, err := filters.keyset("t", "t.Id_threads")
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
 	query := fmt.Sprintf(`
-		SELECT count(*) OVER(), t.Id_threads, t.Title, t.Description, t.Is_public, t.Created_at, t.Updated_at, t.Id_author, u.Username, t.Id_categories, c.Name, t.Status
+		SELECT %s, t.Id_threads, t.Title, t.Description, t.Is_public, t.Created_at, t.Updated_at, t.Id_author, u.Username, t.Id_categories, c.Name, t.Status, %s
 		FROM threads t
 		INNER JOIN users u ON t.Id_author = u.Id_users
 		INNER JOIN categories c ON t.Id_categories = c.Id_categories
-		WHERE t.Title LIKE ? OR t.Description LIKE ?
-		ORDER BY %s %s, Id_threads ASC
-		LIMIT ? OFFSET ?;`, filters.sortColumn(), filters.sortDirection())
+		WHERE (t.Title LIKE ? OR t.Description LIKE ?) AND t.Deleted_at IS NULL%s%s
+		ORDER BY %s %s, Id_threads %s
+		LIMIT ? OFFSET ?;`, filters.countColumn(), filters.sortKey("t"), conditions, keyset, filters.sortKey("t"), filters.sortDirection(), filters.idDirection())
 
-	args := []any{search, search, filters.limit(), filters.offset()}
+	args := []any{search, search}
+	args = append(args, conditionArgs...)
+	args = append(args, keysetArgs...)
+	args = append(args, filters.limit(), filters.offset())
 
 	var threads []*Thread
 
@@ -164,9 +231,11 @@ func (m ThreadModel) Get(search string, filters Filters) ([]*Thread, Metadata, e
 	}
 
 	var totalRecords int
+	var cursors []Cursor
 
 	for rows.Next() {
 		var thread Thread
+		var sortKey any
 
 		err := rows.Scan(
 			&totalRecords,
@@ -181,19 +250,21 @@ func (m ThreadModel) Get(search string, filters Filters) ([]*Thread, Metadata, e
 			&thread.Category.ID,
 			&thread.Category.Name,
 			&thread.Status,
+			&sortKey,
 		)
 		if err != nil {
 			return nil, Metadata{}, err
 		}
 
 		threads = append(threads, &thread)
+		cursors = append(cursors, newCursor(filters.Sort, sortKey, thread.ID))
 	}
 
 	if err = rows.Err(); err != nil {
 		return nil, Metadata{}, err
 	}
 
-	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	threads, metadata := paginate(threads, cursors, totalRecords, filters)
 
 	return threads, metadata, nil
 }
@@ -203,7 +274,7 @@ func (m ThreadModel) GetByID(id int) (*Thread, error) {
 	query := `
 		SELECT Id_threads, Title, Description, Is_public, Created_at, Updated_at, Status, Id_author, Id_categories, Version
 		FROM threads
-		WHERE Id_threads = ?;`
+		WHERE Id_threads = ? AND Deleted_at IS NULL;`
 
 	var thread Thread
 
@@ -241,7 +312,7 @@ func (m ThreadModel) GetByCategory(id int) ([]Thread, error) {
 		SELECT t.Id_threads, t.Title, t.Description, t.Is_public, t.Created_at, t.Updated_at, t.Id_author, u.Username, t.Status
 		FROM threads t
 		INNER JOIN users u on t.Id_author = u.Id_users
-		WHERE t.Id_categories = ?;`
+		WHERE t.Id_categories = ? AND t.Deleted_at IS NULL;`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -294,7 +365,7 @@ func (m ThreadModel) GetByTag(id int) ([]Thread, error) {
 		FROM threads t
 		INNER JOIN threads_tags tt ON t.Id_threads = tt.Id_threads
 		INNER JOIN users u ON t.Id_author = u.Id_users
-		WHERE tt.Id_tags = ?;`
+		WHERE tt.Id_tags = ? AND t.Deleted_at IS NULL;`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -345,12 +416,12 @@ func (m ThreadModel) GetOwnedThreadsByUserID(id int) ([]Thread, error) {
 	query := `
 		SELECT Id_threads, Title, Description, Is_public, Created_at, Updated_at, Status, Id_categories, Version
 		FROM threads
-		WHERE Id_author = ?;`
+		WHERE Id_author = ? AND Deleted_at IS NULL AND Status <> ?;`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	rows, err := m.DB.QueryContext(ctx, query, id)
+	rows, err := m.DB.QueryContext(ctx, query, id, ThreadStatus.Hidden)
 
 	if err != nil {
 		switch {
@@ -388,7 +459,7 @@ func (m ThreadModel) GetFavoriteThreadsByUserID(id int) ([]Thread, error) {
 		SELECT tu.Id_threads, t.Title
 		FROM threads_users tu
 		INNER JOIN threads t ON tu.Id_threads = t.Id_threads
-		WHERE Id_users = ?;`
+		WHERE Id_users = ? AND t.Deleted_at IS NULL;`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -434,6 +505,7 @@ func (m ThreadModel) GetPopular() ([]*Thread, error) {
 		FROM threads t
 		INNER JOIN users u ON t.Id_author = u.Id_users
 		INNER JOIN categories c ON t.Id_categories = c.Id_categories
+		WHERE t.Deleted_at IS NULL
 		ORDER BY popularity DESC, Id_threads ASC
 		LIMIT 10;`
 
@@ -485,14 +557,8 @@ func (m ThreadModel) GetPopular() ([]*Thread, error) {
 	return threads, nil
 }
 
-func (m ThreadModel) Update(thread *Thread) error {
-
-	query := `
-		UPDATE threads 
-		SET Title = ?, Description = ?, Is_public = ?, Status = ?, Id_author = ?, Id_categories = ?, Version = Version + 1
-		WHERE Id_threads = ? AND Version = ?;`
-
-	args := []any{thread.Title, thread.Description, thread.IsPublic, thread.Status, thread.Author.ID, thread.Category.ID, thread.Version}
+// Update saves the thread and, when its title or description changed, records them as a revision written by the editor
+func (m ThreadModel) Update(thread *Thread, editorID int) error {
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -503,6 +569,38 @@ func (m ThreadModel) Update(thread *Thread) error {
 	}
 	defer tx.Rollback()
 
+	query := `
+		SELECT Title, Description
+		FROM threads
+		WHERE Id_threads = ? AND Version = ? AND Deleted_at IS NULL
+		FOR UPDATE;`
+
+	var previousTitle, previousDescription string
+	err = tx.QueryRowContext(ctx, query, thread.ID, thread.Version).Scan(&previousTitle, &previousDescription)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	textChanged := previousTitle != thread.Title || previousDescription != thread.Description
+	if textChanged {
+		err = insertOriginalThreadRevision(ctx, tx, thread.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	query = `
+		UPDATE threads 
+		SET Title = ?, Description = ?, Is_public = ?, Status = ?, Id_author = ?, Id_categories = ?, Version = Version + 1
+		WHERE Id_threads = ? AND Version = ?;`
+
+	args := []any{thread.Title, thread.Description, thread.IsPublic, thread.Status, thread.Author.ID, thread.Category.ID, thread.ID, thread.Version}
+
 	rs, err := tx.ExecContext(ctx, query, args...)
 	if err != nil {
 		var mySQLError *mysql.MySQLError
@@ -513,6 +611,7 @@ func (m ThreadModel) Update(thread *Thread) error {
 					return ErrDuplicateTitle
 				}
 			}
+			return err
 		default:
 			return err
 		}
@@ -541,6 +640,13 @@ func (m ThreadModel) Update(thread *Thread) error {
 		}
 	}
 
+	if textChanged {
+		err = insertThreadRevision(ctx, tx, thread, editorID)
+		if err != nil {
+			return err
+		}
+	}
+
 	if err = tx.Commit(); err != nil {
 		return err
 	}
@@ -548,16 +654,24 @@ func (m ThreadModel) Update(thread *Thread) error {
 	return nil
 }
 
-func (m ThreadModel) Delete(id int) error {
+// Delete moves the thread to the trash along with its posts, which are restored with it
+func (m ThreadModel) Delete(id, userID int) error {
 
 	query := `
-		DELETE FROM threads
-		WHERE Id_threads = ?;`
+		UPDATE threads
+		SET Deleted_at = NOW(), Deleted_by = ?
+		WHERE Id_threads = ? AND Deleted_at IS NULL;`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	result, err := m.DB.ExecContext(ctx, query, id)
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, query, userID, id)
 	if err != nil {
 		return err
 	}
@@ -571,7 +685,18 @@ func (m ThreadModel) Delete(id int) error {
 		return ErrRecordNotFound
 	}
 
-	return nil
+	// the posts share the deletion date of the thread to be restored along with it
+	query = `
+		UPDATE posts
+		SET Deleted_at = (SELECT Deleted_at FROM threads WHERE Id_threads = ?), Deleted_by = ?
+		WHERE Id_threads = ? AND Deleted_at IS NULL;`
+
+	_, err = tx.ExecContext(ctx, query, id, userID, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 func (m ThreadModel) GetPopularity(id int) (int, error) {
@@ -634,11 +759,16 @@ func (m ThreadModel) AddToFavorites(user *User, id int) error {
 	query = `
 		SELECT Title
 		FROM threads
-		WHERE Id_threads = ?;`
+		WHERE Id_threads = ? AND Deleted_at IS NULL;`
 
 	err = tx.QueryRowContext(ctx, query, id).Scan(&favoriteThread.Title)
 	if err != nil {
-		return err
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
 	}
 
 	if err = tx.Commit(); err != nil {
@@ -0,0 +1,173 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type throttleKind struct {
+	Account string
+	IP      string
+}
+
+var ThrottleKind = throttleKind{
+	Account: "account",
+	IP:      "ip",
+}
+
+// LoginThrottle counts the recent failed logins of an account (by email address, existing or not) or of an IP address
+type LoginThrottle struct {
+	Kind          string
+	Subject       string
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   *time.Time
+}
+
+// IsLocked tells whether the logins are refused until the end of a lockout
+func (t *LoginThrottle) IsLocked(now time.Time) bool {
+	return t.LockedUntil != nil && t.LockedUntil.After(now)
+}
+
+// RetryAfter returns how long the next login must wait: until the end of the lockout, or the delay doubling
+// with each failure beyond the free ones (base for the first one, at most maxDelay) since the last failure
+func (t *LoginThrottle) RetryAfter(now time.Time, free int, base, maxDelay time.Duration) time.Duration {
+
+	if t.IsLocked(now) {
+		return t.LockedUntil.Sub(now)
+	}
+
+	if t.Failures <= free {
+		return 0
+	}
+
+	delay := maxDelay
+	if shift := t.Failures - free - 1; shift < 32 && base<<shift < maxDelay {
+		delay = base << shift
+	}
+
+	wait := t.LastFailureAt.Add(delay).Sub(now)
+	if wait < 0 {
+		return 0
+	}
+
+	return wait
+}
+
+type LoginThrottleModel struct {
+	DB *sql.DB
+}
+
+// Get returns the failures of the subject within the window (no failure if there is none)
+func (m LoginThrottleModel) Get(kind, subject string, window time.Duration) (*LoginThrottle, error) {
+
+	query := `
+		SELECT Failures, Last_failure_at, Locked_until
+		FROM login_throttles
+		WHERE Kind = ? AND Subject = ? AND (Last_failure_at > ? OR Locked_until > ?);`
+
+	now := time.Now()
+	args := []any{kind, subject, now.Add(-window), now}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	throttle := &LoginThrottle{Kind: kind, Subject: subject}
+	var lockedUntil sql.NullTime
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&throttle.Failures, &throttle.LastFailureAt, &lockedUntil)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return throttle, nil
+		default:
+			return nil, err
+		}
+	}
+
+	if lockedUntil.Valid {
+		throttle.LockedUntil = &lockedUntil.Time
+	}
+
+	return throttle, nil
+}
+
+// RecordFailure counts a failed login of the subject, starting over when the previous failure is older than the window
+func (m LoginThrottleModel) RecordFailure(kind, subject string, window time.Duration) (*LoginThrottle, error) {
+
+	query := `
+		INSERT INTO login_throttles (Kind, Subject, Failures, Last_failure_at)
+		VALUES (?, ?, 1, ?)
+		ON DUPLICATE KEY UPDATE
+			Failures = IF(Last_failure_at > ? OR Locked_until > ?, Failures + 1, 1),
+			Locked_until = IF(Locked_until > ?, Locked_until, NULL),
+			Last_failure_at = VALUES(Last_failure_at);`
+
+	now := time.Now()
+	args := []any{kind, subject, now, now.Add(-window), now, now}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.Get(kind, subject, window)
+}
+
+// Lock refuses the logins of the subject until the time given
+func (m LoginThrottleModel) Lock(throttle *LoginThrottle, until time.Time) error {
+
+	query := `
+		UPDATE login_throttles
+		SET Locked_until = ?
+		WHERE Kind = ? AND Subject = ?;`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, until, throttle.Kind, throttle.Subject)
+	if err != nil {
+		return err
+	}
+
+	throttle.LockedUntil = &until
+
+	return nil
+}
+
+// Reset forgets the failures of the subject (after a successful login or with the unlock link)
+func (m LoginThrottleModel) Reset(kind, subject string) error {
+
+	query := `
+		DELETE FROM login_throttles
+		WHERE Kind = ? AND Subject = ?;`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, kind, subject)
+
+	return err
+}
+
+// DeleteExpired removes the failures older than the window which aren't locked anymore
+func (m LoginThrottleModel) DeleteExpired(window time.Duration) error {
+
+	query := `
+		DELETE FROM login_throttles
+		WHERE Last_failure_at < ? AND (Locked_until IS NULL OR Locked_until < ?);`
+
+	now := time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, now.Add(-window), now)
+
+	return err
+}
@@ -0,0 +1,48 @@
+package data
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoginThrottle_RetryAfter(t *testing.T) {
+
+	now := time.Date(2024, 5, 25, 20, 0, 0, 0, time.UTC)
+	lockedUntil := now.Add(10 * time.Minute)
+	expiredLock := now.Add(-time.Minute)
+
+	const (
+		free     = 3
+		base     = time.Second
+		maxDelay = time.Minute
+	)
+
+	tests := []struct {
+		name       string
+		throttle   LoginThrottle
+		wantLocked bool
+		want       time.Duration
+	}{
+		{name: "No failure", throttle: LoginThrottle{}},
+		{name: "Free failures", throttle: LoginThrottle{Failures: free, LastFailureAt: now}},
+		{name: "First delayed failure", throttle: LoginThrottle{Failures: free + 1, LastFailureAt: now}, want: base},
+		{name: "Doubling", throttle: LoginThrottle{Failures: free + 3, LastFailureAt: now}, want: 4 * base},
+		{name: "Time already waited", throttle: LoginThrottle{Failures: free + 3, LastFailureAt: now.Add(-3 * time.Second)}, want: base},
+		{name: "Delay over", throttle: LoginThrottle{Failures: free + 3, LastFailureAt: now.Add(-time.Hour)}},
+		{name: "Capped", throttle: LoginThrottle{Failures: free + 10, LastFailureAt: now}, want: maxDelay},
+		{name: "Capped without overflow", throttle: LoginThrottle{Failures: free + 100, LastFailureAt: now}, want: maxDelay},
+		{name: "Locked", throttle: LoginThrottle{Failures: free + 10, LastFailureAt: now, LockedUntil: &lockedUntil}, wantLocked: true, want: 10 * time.Minute},
+		{name: "Lock over", throttle: LoginThrottle{Failures: free, LastFailureAt: now, LockedUntil: &expiredLock}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.throttle.IsLocked(now); got != tt.wantLocked {
+				t.Errorf("IsLocked() = %v, want %v", got, tt.wantLocked)
+			}
+			if got := tt.throttle.RetryAfter(now, free, base, maxDelay); got != tt.want {
+				t.Errorf("RetryAfter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
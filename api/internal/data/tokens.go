@@ -25,6 +25,8 @@ type tokenScope struct {
 	UpdatePassword string
 	Client         string
 	HostSecret     string
+	MFAPending     string
+	Unlock         string
 }
 
 var (
@@ -35,15 +37,34 @@ var (
 		UpdatePassword: "update_password",
 		Client:         "client",
 		HostSecret:     "host_secret",
+		MFAPending:     "mfa_pending",
+		Unlock:         "unlock",
 	}
 )
 
 type Token struct {
-	Plaintext string    `json:"token"`
-	Hash      []byte    `json:"-"`
-	UserID    int       `json:"-"`
-	Expiry    time.Time `json:"expiry"`
-	Scope     string    `json:"-"`
+	Plaintext  string     `json:"token"`
+	Hash       []byte     `json:"-"`
+	UserID     int        `json:"-"`
+	SessionID  int        `json:"-"`
+	Expiry     time.Time  `json:"expiry"`
+	Scope      string     `json:"-"`
+	Family     string     `json:"-"` // rotation family of the refresh tokens succeeding each other
+	ParentHash []byte     `json:"-"` // refresh token this one replaced
+	UsedAt     *time.Time `json:"-"` // time the refresh token was exchanged for new tokens
+}
+
+// NewTokenFamily returns the identifier of a new rotation family of refresh tokens
+func NewTokenFamily() (string, error) {
+
+	randomBytes := make([]byte, 16)
+
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(randomBytes), nil
 }
 
 func generateToken(userID int, ttl time.Duration, scope string) (*Token, error) {
@@ -79,14 +100,20 @@ type TokenModel struct {
 }
 
 func (m TokenModel) New(userID int, ttl time.Duration, scope string) (*Token, error) {
-	token, err := generateToken(userID, ttl, scope)
+	return m.NewForSession(userID, 0, ttl, scope, nil)
+}
+
+// NewForSession creates a token belonging to the session of the user (no session if sessionID is 0).
+// A refresh token replacing the parent one joins its rotation family.
+func (m TokenModel) NewForSession(userID, sessionID int, ttl time.Duration, scope string, parent *Token) (*Token, error) {
+	token, err := m.generateForSession(userID, sessionID, ttl, scope, parent)
 	if err != nil {
 		return nil, err
 	}
 
 	err = m.Insert(token)
 	if errors.Is(err, ErrDuplicateToken) {
-		token, err = generateToken(userID, ttl, scope)
+		token, err = m.generateForSession(userID, sessionID, ttl, scope, parent)
 		if err != nil {
 			return nil, err
 		}
@@ -96,13 +123,28 @@ func (m TokenModel) New(userID int, ttl time.Duration, scope string) (*Token, er
 	return token, err
 }
 
+func (m TokenModel) generateForSession(userID, sessionID int, ttl time.Duration, scope string, parent *Token) (*Token, error) {
+	token, err := generateToken(userID, ttl, scope)
+	if err != nil {
+		return nil, err
+	}
+	token.SessionID = sessionID
+
+	if parent != nil {
+		token.Family = parent.Family
+		token.ParentHash = parent.Hash
+	}
+
+	return token, nil
+}
+
 func (m TokenModel) Insert(token *Token) error {
 
 	query := `
-		INSERT INTO tokens (Hash, Id_users, Expiry, Scope)
-		VALUES (?, ?, ?, ?);`
+		INSERT INTO tokens (Hash, Id_users, Expiry, Scope, Id_user_sessions, Family, Parent_hash)
+		VALUES (?, ?, ?, ?, NULLIF(?, 0), NULLIF(?, ''), NULLIF(?, ''));`
 
-	args := []any{hex.EncodeToString(token.Hash), token.UserID, token.Expiry, token.Scope}
+	args := []any{hex.EncodeToString(token.Hash), token.UserID, token.Expiry, token.Scope, token.SessionID, token.Family, hex.EncodeToString(token.ParentHash)}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -155,6 +197,117 @@ func (m TokenModel) DeleteAllForUser(scope string, userID int) error {
 	return err
 }
 
+// DeleteAllForSession deletes the tokens of the session with the scope, before replacing them
+func (m TokenModel) DeleteAllForSession(scope string, sessionID int) error {
+
+	query := `
+		DELETE FROM tokens
+		WHERE Scope = ? AND Id_user_sessions = ?;`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, scope, sessionID)
+	return err
+}
+
+// GetRefresh returns the valid refresh token, whether it was already used or not
+func (m TokenModel) GetRefresh(tokenPlaintext string) (*Token, error) {
+
+	hash := sha256.Sum256([]byte(tokenPlaintext))
+
+	query := `
+		SELECT Id_users, Expiry, Id_user_sessions, Family, Used_at
+		FROM tokens
+		WHERE Hash = ? AND Scope = ? AND Expiry > ?;`
+
+	args := []any{hex.EncodeToString(hash[:]), TokenScope.Refresh, time.Now()}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	token := &Token{
+		Plaintext: tokenPlaintext,
+		Hash:      hash[:],
+		Scope:     TokenScope.Refresh,
+	}
+
+	var sessionID sql.NullInt64
+	var family sql.NullString
+	var usedAt sql.NullTime
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&token.UserID, &token.Expiry, &sessionID, &family, &usedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	token.SessionID = int(sessionID.Int64)
+	token.Family = family.String
+	if usedAt.Valid {
+		token.UsedAt = &usedAt.Time
+	}
+
+	return token, nil
+}
+
+// MarkUsed marks the refresh token as exchanged for new tokens.
+// It returns false if the token was already used (e.g. replayed by someone who stole it).
+func (m TokenModel) MarkUsed(token *Token) (bool, error) {
+
+	query := `
+		UPDATE tokens
+		SET Used_at = ?
+		WHERE Hash = ? AND Used_at IS NULL;`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	res, err := m.DB.ExecContext(ctx, query, time.Now(), hex.EncodeToString(token.Hash))
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected == 1, nil
+}
+
+// DeleteFamily deletes every token of the rotation family
+func (m TokenModel) DeleteFamily(family string) error {
+
+	query := `
+		DELETE FROM tokens
+		WHERE Family = ?;`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, family)
+	return err
+}
+
+// DeleteUnboundForUser deletes the authentication and refresh tokens of the user created before the sessions existed
+func (m TokenModel) DeleteUnboundForUser(userID int) error {
+
+	query := `
+		DELETE FROM tokens
+		WHERE Id_users = ? AND Scope IN (?, ?) AND Id_user_sessions IS NULL;`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, userID, TokenScope.Authentication, TokenScope.Refresh)
+	return err
+}
+
 func (m TokenModel) DeleteExpired() error {
 
 	query := `
@@ -0,0 +1,284 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	// RecoveryCodesCount is the number of recovery codes given to the user when enabling the two-factor authentication
+	RecoveryCodesCount = 10
+	// recoveryCodeSize is the number of random bytes of each recovery code (giving 10 base32 characters)
+	recoveryCodeSize = 5
+)
+
+// TOTP is the time-based one-time password secret (RFC 6238) of a user's two-factor authentication.
+// It is only enabled once confirmed with a first code.
+type TOTP struct {
+	UserID      int        `json:"-"`
+	Secret      string     `json:"-"`
+	LastStep    int64      `json:"-"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ConfirmedAt *time.Time `json:"confirmed_at,omitempty"`
+}
+
+func (t *TOTP) IsEnabled() bool {
+	return t != nil && t.ConfirmedAt != nil
+}
+
+type TOTPModel struct {
+	DB *sql.DB
+}
+
+// normalizeRecoveryCode ignores the case, spaces and dashes the user may type
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
+
+func hashRecoveryCode(code string) string {
+	hash := sha256.Sum256([]byte(normalizeRecoveryCode(code)))
+	return hex.EncodeToString(hash[:])
+}
+
+// generateRecoveryCode returns a random code formatted as "xxxxx-xxxxx"
+func generateRecoveryCode() (string, error) {
+
+	randomBytes := make([]byte, recoveryCodeSize)
+
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return "", err
+	}
+
+	code := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes))
+
+	return code[:5] + "-" + code[5:], nil
+}
+
+func (m TOTPModel) Get(userID int) (*TOTP, error) {
+
+	query := `
+		SELECT Secret, Last_step, Created_at, Confirmed_at
+		FROM user_totp
+		WHERE Id_users = ?;`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	totp := &TOTP{UserID: userID}
+	var confirmedAt sql.NullTime
+
+	err := m.DB.QueryRowContext(ctx, query, userID).Scan(&totp.Secret, &totp.LastStep, &totp.CreatedAt, &confirmedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	if confirmedAt.Valid {
+		totp.ConfirmedAt = &confirmedAt.Time
+	}
+
+	return totp, nil
+}
+
+// Enroll stores a new secret for the user, waiting for its confirmation (replacing any previous unconfirmed one)
+func (m TOTPModel) Enroll(userID int, secret string) error {
+
+	query := `
+		INSERT INTO user_totp (Id_users, Secret)
+		VALUES (?, ?)
+		ON DUPLICATE KEY UPDATE Secret = VALUES(Secret), Last_step = 0, Created_at = CURRENT_TIMESTAMP, Confirmed_at = NULL;`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, userID, secret)
+
+	return err
+}
+
+// Confirm enables the two-factor authentication of the user, the first code (of the time step) being used
+func (m TOTPModel) Confirm(userID int, step int64) error {
+
+	query := `
+		UPDATE user_totp
+		SET Confirmed_at = CURRENT_TIMESTAMP, Last_step = ?
+		WHERE Id_users = ? AND Confirmed_at IS NULL;`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	res, err := m.DB.ExecContext(ctx, query, step, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// UseStep records the time step of a valid code, returning false if a code of that step
+// (or of a later one) was already used, so that each code is accepted only once
+func (m TOTPModel) UseStep(userID int, step int64) (bool, error) {
+
+	query := `
+		UPDATE user_totp
+		SET Last_step = ?
+		WHERE Id_users = ? AND Last_step < ?;`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	res, err := m.DB.ExecContext(ctx, query, step, userID, step)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected == 1, nil
+}
+
+// Delete disables the two-factor authentication of the user, along with the recovery codes
+func (m TOTPModel) Delete(userID int) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM user_totp WHERE Id_users = ?;`, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM user_recovery_codes WHERE Id_users = ?;`, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// NewRecoveryCodes replaces the recovery codes of the user, returning the plaintext codes to show them only once
+func (m TOTPModel) NewRecoveryCodes(userID int) ([]string, error) {
+
+	codes := make([]string, RecoveryCodesCount)
+	args := make([]any, 0, RecoveryCodesCount*2)
+
+	for i := range codes {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code
+		args = append(args, userID, hashRecoveryCode(code))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM user_recovery_codes WHERE Id_users = ?;`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	values := strings.TrimSuffix(strings.Repeat("(?, ?), ", RecoveryCodesCount), ", ")
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO user_recovery_codes (Id_users, Hash) VALUES `+values+`;`, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// UseRecoveryCode consumes the recovery code of the user, returning false if it doesn't exist or was already used
+func (m TOTPModel) UseRecoveryCode(userID int, code string) (bool, error) {
+
+	query := `
+		UPDATE user_recovery_codes
+		SET Used_at = CURRENT_TIMESTAMP
+		WHERE Id_users = ? AND Hash = ? AND Used_at IS NULL;`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	res, err := m.DB.ExecContext(ctx, query, userID, hashRecoveryCode(code))
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected == 1, nil
+}
+
+// CountRecoveryCodes returns the number of recovery codes the user still has
+func (m TOTPModel) CountRecoveryCodes(userID int) (int, error) {
+
+	query := `
+		SELECT COUNT(*)
+		FROM user_recovery_codes
+		WHERE Id_users = ? AND Used_at IS NULL;`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var count int
+
+	err := m.DB.QueryRowContext(ctx, query, userID).Scan(&count)
+
+	return count, err
+}
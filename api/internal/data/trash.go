@@ -0,0 +1,366 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type trashType struct {
+	Category string
+	Tag      string
+	Thread   string
+	Post     string
+}
+
+var (
+	TrashType = trashType{
+		Category: "category",
+		Tag:      "tag",
+		Thread:   "thread",
+		Post:     "post",
+	}
+	PermittedTrashTypes = []string{TrashType.Category, TrashType.Tag, TrashType.Thread, TrashType.Post}
+)
+
+// TrashItem is a deleted category, tag, thread or post waiting to be restored or purged.
+// The title of a post is its content, and the replies (or posts of a thread) deleted along with it are not listed.
+type TrashItem struct {
+	Type      string    `json:"type"`
+	ID        int       `json:"id"`
+	Title     string    `json:"title"`
+	ThreadID  int       `json:"thread_id,omitempty"`
+	DeletedAt time.Time `json:"deleted_at"`
+	DeletedBy struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	} `json:"deleted_by"`
+}
+
+type TrashModel struct {
+	DB *sql.DB
+}
+
+// queryIDs returns the ids selected by the query, ready to be used as arguments
+func queryIDs(ctx context.Context, tx *sql.Tx, query string, args ...any) ([]any, error) {
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []any
+
+	for rows.Next() {
+		var id int
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// placeholders returns the placeholders of n arguments separated by commas
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// trashQuery returns the query listing the items in the trash, of the given type if not empty.
+// Every member of the union aliases its columns, the derived table taking its column names from the first one.
+func trashQuery(itemType string, filters Filters) string {
+
+	subQueries := map[string]string{
+		TrashType.Category: `
+			SELECT 'category' AS Type, Id_categories AS Id, Name AS Title, 0 AS Id_threads, Deleted_at AS Deleted_at, Deleted_by AS Deleted_by
+			FROM categories
+			WHERE Deleted_at IS NOT NULL`,
+		TrashType.Tag: `
+			SELECT 'tag' AS Type, Id_tags AS Id, Name AS Title, 0 AS Id_threads, Deleted_at AS Deleted_at, Deleted_by AS Deleted_by
+			FROM tags
+			WHERE Deleted_at IS NOT NULL`,
+		TrashType.Thread: `
+			SELECT 'thread' AS Type, Id_threads AS Id, Title AS Title, Id_threads AS Id_threads, Deleted_at AS Deleted_at, Deleted_by AS Deleted_by
+			FROM threads
+			WHERE Deleted_at IS NOT NULL`,
+		TrashType.Post: `
+			SELECT 'post' AS Type, p.Id_posts AS Id, p.Content AS Title, p.Id_threads AS Id_threads, p.Deleted_at AS Deleted_at, p.Deleted_by AS Deleted_by
+			FROM posts p
+			INNER JOIN threads t ON p.Id_threads = t.Id_threads
+			LEFT JOIN posts pp ON p.Id_parent_posts = pp.Id_posts
+			WHERE p.Deleted_at IS NOT NULL AND t.Deleted_at IS NULL AND (pp.Deleted_at IS NULL OR pp.Deleted_at <> p.Deleted_at)`,
+	}
+
+	var selected []string
+	for _, permittedType := range PermittedTrashTypes {
+		if itemType == "" || itemType == permittedType {
+			selected = append(selected, subQueries[permittedType])
+		}
+	}
+
+	return fmt.Sprintf(`
+		SELECT count(*) OVER(), trash.Type, trash.Id, trash.Title, trash.Id_threads, trash.Deleted_at, COALESCE(trash.Deleted_by, 0), COALESCE(u.Username, '')
+		FROM (%s
+		) AS trash
+		LEFT JOIN users u ON trash.Deleted_by = u.Id_users
+		ORDER BY trash.%s %s, trash.Type ASC, trash.Id ASC
+		LIMIT ? OFFSET ?;`, strings.Join(selected, `
+			UNION ALL`), filters.sortColumn(), filters.sortDirection())
+}
+
+// Get returns the items in the trash, of the given type if not empty
+func (m TrashModel) Get(itemType string, filters Filters) ([]*TrashItem, Metadata, error) {
+
+	query := trashQuery(itemType, filters)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	var totalRecords int
+	var items []*TrashItem
+
+	for rows.Next() {
+		var item TrashItem
+
+		err = rows.Scan(
+			&totalRecords,
+			&item.Type,
+			&item.ID,
+			&item.Title,
+			&item.ThreadID,
+			&item.DeletedAt,
+			&item.DeletedBy.ID,
+			&item.DeletedBy.Name,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		items = append(items, &item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return items, metadata, nil
+}
+
+// Restore takes the item out of the trash, along with the replies or posts deleted with it.
+// It fails with ErrParentDeleted while the category, thread or post containing the item is itself in the trash.
+func (m TrashModel) Restore(itemType string, id int) error {
+
+	var query string
+
+	switch itemType {
+	case TrashType.Category:
+		query = `
+			SELECT c.Deleted_at, pc.Deleted_at IS NOT NULL
+			FROM categories c
+			LEFT JOIN categories pc ON c.Id_parent_categories = pc.Id_categories
+			WHERE c.Id_categories = ? AND c.Deleted_at IS NOT NULL;`
+	case TrashType.Tag:
+		query = `
+			SELECT Deleted_at, false
+			FROM tags
+			WHERE Id_tags = ? AND Deleted_at IS NOT NULL;`
+	case TrashType.Thread:
+		query = `
+			SELECT t.Deleted_at, c.Deleted_at IS NOT NULL
+			FROM threads t
+			INNER JOIN categories c ON t.Id_categories = c.Id_categories
+			WHERE t.Id_threads = ? AND t.Deleted_at IS NOT NULL;`
+	case TrashType.Post:
+		query = `
+			SELECT p.Deleted_at, t.Deleted_at IS NOT NULL OR pp.Deleted_at IS NOT NULL
+			FROM posts p
+			INNER JOIN threads t ON p.Id_threads = t.Id_threads
+			LEFT JOIN posts pp ON p.Id_parent_posts = pp.Id_posts
+			WHERE p.Id_posts = ? AND p.Deleted_at IS NOT NULL;`
+	default:
+		return fmt.Errorf("unknown trash type %s", itemType)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var deletedAt time.Time
+	var parentDeleted bool
+
+	err = tx.QueryRowContext(ctx, query, id).Scan(&deletedAt, &parentDeleted)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+	if parentDeleted {
+		return ErrParentDeleted
+	}
+
+	switch itemType {
+	case TrashType.Category:
+		_, err = tx.ExecContext(ctx, `
+			UPDATE categories
+			SET Deleted_at = NULL, Deleted_by = NULL
+			WHERE Id_categories = ?;`, id)
+	case TrashType.Tag:
+		_, err = tx.ExecContext(ctx, `
+			UPDATE tags
+			SET Deleted_at = NULL, Deleted_by = NULL
+			WHERE Id_tags = ?;`, id)
+	case TrashType.Thread:
+		_, err = tx.ExecContext(ctx, `
+			UPDATE posts
+			SET Deleted_at = NULL, Deleted_by = NULL
+			WHERE Id_threads = ? AND Deleted_at = ?;`, id, deletedAt)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+			UPDATE threads
+			SET Deleted_at = NULL, Deleted_by = NULL
+			WHERE Id_threads = ?;`, id)
+	case TrashType.Post:
+		// the replies deleted along with the post share its deletion date
+		var ids []any
+		ids, err = queryIDs(ctx, tx, `
+			WITH RECURSIVE subtree AS (
+				SELECT Id_posts
+				FROM posts
+				WHERE Id_posts = ?
+				UNION ALL
+				SELECT p.Id_posts
+				FROM posts p
+				INNER JOIN subtree s ON p.Id_parent_posts = s.Id_posts
+				WHERE p.Deleted_at = ?
+			)
+			SELECT Id_posts
+			FROM subtree;`, id, deletedAt)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+			UPDATE posts
+			SET Deleted_at = NULL, Deleted_by = NULL
+			WHERE Id_posts IN (%s);`, placeholders(len(ids))), ids...)
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Purge permanently deletes the items which have been in the trash for longer than the retention period.
+// Categories are only purged once they contain nothing anymore, which may take several runs for nested ones.
+func (m TrashModel) Purge(retention time.Duration) error {
+
+	seconds := int(retention.Seconds())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// the posts expired themselves or through their thread, with all their replies
+	ids, err := queryIDs(ctx, tx, `
+		WITH RECURSIVE purged (Id) AS (
+			SELECT p.Id_posts
+			FROM posts p
+			INNER JOIN threads t ON p.Id_threads = t.Id_threads
+			WHERE p.Deleted_at < NOW() - INTERVAL ? SECOND OR t.Deleted_at < NOW() - INTERVAL ? SECOND
+			UNION
+			SELECT p.Id_posts
+			FROM posts p
+			INNER JOIN purged ON p.Id_parent_posts = purged.Id
+		)
+		SELECT Id
+		FROM purged;`, seconds, seconds)
+	if err != nil {
+		return fmt.Errorf("failed to select the posts to purge: %w", err)
+	}
+
+	if len(ids) > 0 {
+
+		// detaching the posts from their parents first, since they reference each other
+		_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+			UPDATE posts
+			SET Id_parent_posts = NULL
+			WHERE Id_posts IN (%s);`, placeholders(len(ids))), ids...)
+		if err != nil {
+			return fmt.Errorf("failed to detach the posts to purge: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+			DELETE FROM posts
+			WHERE Id_posts IN (%s);`, placeholders(len(ids))), ids...)
+		if err != nil {
+			return fmt.Errorf("failed to purge posts: %w", err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM threads
+		WHERE Deleted_at < NOW() - INTERVAL ? SECOND;`, seconds)
+	if err != nil {
+		return fmt.Errorf("failed to purge threads: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM tags
+		WHERE Deleted_at < NOW() - INTERVAL ? SECOND;`, seconds)
+	if err != nil {
+		return fmt.Errorf("failed to purge tags: %w", err)
+	}
+
+	ids, err = queryIDs(ctx, tx, `
+		SELECT c.Id_categories
+		FROM categories c
+		WHERE c.Deleted_at < NOW() - INTERVAL ? SECOND
+		AND NOT EXISTS (SELECT 1 FROM threads t WHERE t.Id_categories = c.Id_categories)
+		AND NOT EXISTS (SELECT 1 FROM categories sc WHERE sc.Id_parent_categories = c.Id_categories);`, seconds)
+	if err != nil {
+		return fmt.Errorf("failed to select the categories to purge: %w", err)
+	}
+
+	if len(ids) > 0 {
+		_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+			DELETE FROM categories
+			WHERE Id_categories IN (%s);`, placeholders(len(ids))), ids...)
+		if err != nil {
+			return fmt.Errorf("failed to purge categories: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
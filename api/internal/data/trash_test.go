@@ -0,0 +1,28 @@
+package data
+
+import (
+	"testing"
+)
+
+func TestTrashQuery(t *testing.T) {
+
+	filters := Filters{Page: 1, PageSize: 20, Sort: "-Deleted_at", SortSafelist: []string{"-Deleted_at", "Deleted_at"}}
+
+	tests := []struct {
+		name        string
+		itemType    string
+		wantMembers int
+	}{
+		{name: "All types", wantMembers: 4},
+		{name: "Categories", itemType: TrashType.Category, wantMembers: 1},
+		{name: "Tags", itemType: TrashType.Tag, wantMembers: 1},
+		{name: "Threads", itemType: TrashType.Thread, wantMembers: 1},
+		{name: "Posts", itemType: TrashType.Post, wantMembers: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkUnion(t, trashQuery(tt.itemType, filters), "trash", tt.wantMembers)
+		})
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/go-sql-driver/mysql"
 	"golang.org/x/crypto/bcrypt"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -18,6 +19,8 @@ const (
 	MinPasswordLength = 8
 	MaxPasswordLength = 72
 	MinAge            = 13
+	// noLoginHash is the password hash of the users who cannot log in with a password (e.g. created by an identity provider)
+	noLoginHash = "no-login"
 )
 
 var (
@@ -79,10 +82,13 @@ type User struct {
 		Received []Friend `json:"received,omitempty"`
 		Sent     []Friend `json:"sent,omitempty"`
 	} `json:"invitations,omitempty"`
+	SuspensionReason string     `json:"suspension_reason,omitempty"`
+	SuspendedUntil   *time.Time `json:"suspended_until,omitempty"`
+	SessionID        int        `json:"-"` // session of the token the user was retrieved with (see GetForToken)
 }
 
 func (u *User) IsActivated() bool {
-	return u.Status == UserStatus.Activated
+	return u.Status == UserStatus.Activated || (u.Status == UserStatus.Blocked && u.suspensionExpired())
 }
 
 func (u *User) IsToConfirm() bool {
@@ -90,7 +96,12 @@ func (u *User) IsToConfirm() bool {
 }
 
 func (u *User) IsBlocked() bool {
-	return u.Status == UserStatus.Blocked
+	return u.Status == UserStatus.Blocked && !u.suspensionExpired()
+}
+
+// suspensionExpired tells whether the user's suspension is over, even if LiftExpiredSuspensions hasn't cleared it yet
+func (u *User) suspensionExpired() bool {
+	return u.SuspendedUntil != nil && u.SuspendedUntil.Before(time.Now())
 }
 
 func (u *User) IsAnonymous() bool {
@@ -108,10 +119,14 @@ func (u *User) HasPermission(authorID int) bool {
 	}
 }
 
+func (u *User) IsModerator() bool {
+	return u.Role == UserRole.Admin || u.Role == UserRole.Moderator
+}
+
 func (u *User) NoLogin() {
 	u.Password = password{
 		plaintext: nil,
-		hash:      []byte("no-login"),
+		hash:      []byte(noLoginHash),
 	}
 }
 
@@ -132,11 +147,37 @@ func (p *password) Set(plainTextPassword string) error {
 	return nil
 }
 
+var (
+	dummyPasswordOnce sync.Once
+	dummyPassword     password
+)
+
+// DummyPasswordMatches compares the password with a dummy hash, so that checking the credentials of an unknown
+// email address takes as long as checking the password of an existing user
+func DummyPasswordMatches(plainTextPassword string) {
+	dummyPasswordOnce.Do(func() {
+		err := dummyPassword.Set("dummy password of the unknown users")
+		if err != nil {
+			dummyPassword.hash = []byte(noLoginHash)
+		}
+	})
+
+	_ = bcrypt.CompareHashAndPassword(dummyPassword.hash, []byte(plainTextPassword))
+}
+
+// Matches compares the password with the hash, the users without password (or with an invalid hash) never matching
 func (p *password) Matches(plainTextPassword string) (bool, error) {
+
+	if string(p.hash) == noLoginHash {
+		DummyPasswordMatches(plainTextPassword)
+		return false, nil
+	}
+
 	err := bcrypt.CompareHashAndPassword(p.hash, []byte(plainTextPassword))
 	if err != nil {
+		var invalidHashPrefixError bcrypt.InvalidHashPrefixError
 		switch {
-		case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
+		case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword), errors.Is(err, bcrypt.ErrHashTooShort), errors.As(err, &invalidHashPrefixError):
 			return false, nil
 		default:
 			return false, err
@@ -177,6 +218,15 @@ func (u *User) Validate(v *validator.Validator) {
 	}
 }
 
+// UserFilters is the set of structured filters available when listing users
+type UserFilters struct {
+	Role          string
+	Status        string
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	SearchEmail   bool
+}
+
 type UserModel struct {
 	DB *sql.DB
 }
@@ -292,14 +342,102 @@ func (m UserModel) GetByID(id int) (*User, error) {
 	return &user, nil
 }
 
+func (m UserModel) Get(search string, userFilters UserFilters, filters Filters) ([]*User, Metadata, error) {
+
+	if search != "" {
+		search = fmt.Sprintf("%%%s%%", search)
+	} else {
+		search = "%"
+	}
+
+	// client and host secret accounts are technical users, not forum members
+	args := []any{search, userFilters.SearchEmail, search, UserStatus.Client, UserStatus.HostSecret}
+
+	var conditions string
+	if userFilters.Role != "" {
+		conditions += " AND Role = ?"
+		args = append(args, userFilters.Role)
+	}
+	if userFilters.Status != "" {
+		conditions += " AND Status = ?"
+		args = append(args, userFilters.Status)
+	}
+	if !userFilters.CreatedAfter.IsZero() {
+		conditions += " AND Created_at >= ?"
+		args = append(args, userFilters.CreatedAfter)
+	}
+	if !userFilters.CreatedBefore.IsZero() {
+		conditions += " AND Created_at < ?"
+		args = append(args, userFilters.CreatedBefore)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), Id_users, Username, Email, Avatar_path, Role, Birth_date, Created_at, Bio, Signature, Status
+		FROM users
+		WHERE (Username LIKE ? OR (? AND Email LIKE ?)) AND Status NOT IN (?, ?)%s
+		ORDER BY %s %s, Id_users ASC
+		LIMIT ? OFFSET ?;`, conditions, filters.sortColumn(), filters.sortDirection())
+
+	args = append(args, filters.limit(), filters.offset())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	var totalRecords int
+	var users []*User
+
+	for rows.Next() {
+		var user User
+		var birth sql.NullTime
+
+		err = rows.Scan(
+			&totalRecords,
+			&user.ID,
+			&user.Name,
+			&user.Email,
+			&user.Avatar,
+			&user.Role,
+			&birth,
+			&user.CreatedAt,
+			&user.Bio,
+			&user.Signature,
+			&user.Status,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		if birth.Valid {
+			user.BirthDate = birth.Time
+		}
+
+		users = append(users, &user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return users, metadata, nil
+}
+
 func (m UserModel) GetByEmail(email string) (*User, error) {
 
 	query := `
-		SELECT Id_users, Created_at, Username, Email, Hashed_password, Status, Version
+		SELECT Id_users, Created_at, Username, Email, Hashed_password, Role, Status, Suspension_reason, Suspended_until, Version
 		FROM users
 		WHERE Email = ?;`
 
 	var user User
+	var suspendedUntil sql.NullTime
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -310,7 +448,10 @@ func (m UserModel) GetByEmail(email string) (*User, error) {
 		&user.Name,
 		&user.Email,
 		&user.Password.hash,
+		&user.Role,
 		&user.Status,
+		&user.SuspensionReason,
+		&suspendedUntil,
 		&user.Version,
 	)
 
@@ -323,6 +464,10 @@ func (m UserModel) GetByEmail(email string) (*User, error) {
 		}
 	}
 
+	if suspendedUntil.Valid {
+		user.SuspendedUntil = &suspendedUntil.Time
+	}
+
 	return &user, nil
 }
 
@@ -406,7 +551,7 @@ func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error)
 	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
 
 	query := `
-		SELECT users.Id_users, users.Created_at, users.Username, users.Email, users.Hashed_password, users.Status, users.Version
+		SELECT users.Id_users, users.Created_at, users.Username, users.Email, users.Hashed_password, users.Role, users.Status, users.Suspension_reason, users.Suspended_until, users.Version, tokens.Id_user_sessions
 		FROM users
 		INNER JOIN tokens
 		ON users.Id_users = tokens.Id_users
@@ -417,6 +562,8 @@ func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error)
 	args := []any{hex.EncodeToString(tokenHash[:]), tokenScope, time.Now()}
 
 	var user User
+	var suspendedUntil sql.NullTime
+	var sessionID sql.NullInt64
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -427,8 +574,12 @@ func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error)
 		&user.Name,
 		&user.Email,
 		&user.Password.hash,
+		&user.Role,
 		&user.Status,
+		&user.SuspensionReason,
+		&suspendedUntil,
 		&user.Version,
+		&sessionID,
 	)
 
 	if err != nil {
@@ -440,9 +591,90 @@ func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error)
 		}
 	}
 
+	if suspendedUntil.Valid {
+		user.SuspendedUntil = &suspendedUntil.Time
+	}
+	user.SessionID = int(sessionID.Int64)
+
 	return &user, nil
 }
 
+// Suspend blocks the user with the given id until the given time (or until further notice if nil)
+func (m UserModel) Suspend(id int, reason string, until *time.Time) error {
+
+	query := `
+		UPDATE users
+		SET Status = ?, Suspension_reason = ?, Suspended_until = ?, Updated_at = CURRENT_TIMESTAMP, Version = Version + 1
+		WHERE Id_users = ?;`
+
+	var suspendedUntil sql.NullTime
+	if until != nil {
+		suspendedUntil = sql.NullTime{Time: *until, Valid: true}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	res, err := m.DB.ExecContext(ctx, query, UserStatus.Blocked, reason, suspendedUntil, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// LiftSuspension reactivates the user with the given id if the user is blocked
+func (m UserModel) LiftSuspension(id int) error {
+
+	query := `
+		UPDATE users
+		SET Status = ?, Suspension_reason = '', Suspended_until = NULL, Updated_at = CURRENT_TIMESTAMP, Version = Version + 1
+		WHERE Id_users = ? AND Status = ?;`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	res, err := m.DB.ExecContext(ctx, query, UserStatus.Activated, id, UserStatus.Blocked)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// LiftExpiredSuspensions reactivates the users whose suspension has come to an end
+func (m UserModel) LiftExpiredSuspensions() error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `
+		UPDATE users
+		SET Status = ?, Suspension_reason = '', Suspended_until = NULL, Updated_at = CURRENT_TIMESTAMP, Version = Version + 1
+		WHERE Status = ? AND Suspended_until IS NOT NULL AND Suspended_until < CURRENT_TIMESTAMP;`
+
+	_, err := m.DB.ExecContext(ctx, query, UserStatus.Activated, UserStatus.Blocked)
+	if err != nil {
+		return fmt.Errorf("failed to lift expired suspensions: %w", err)
+	}
+
+	return nil
+}
+
 func (m UserModel) DeleteExpired() error {
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
@@ -468,6 +700,49 @@ func (m UserModel) DeleteExpired() error {
 	return nil
 }
 
+// GetAuthoredContent returns the ids of the categories, tags, threads and posts authored by the user
+// (used to keep track of the original author before Delete reassigns them to the deleted user)
+func (m UserModel) GetAuthoredContent(id int) (map[string][]int, error) {
+
+	query := `
+		SELECT 'categories', Id_categories FROM categories WHERE Id_author = ?
+		UNION ALL
+		SELECT 'tags', Id_tags FROM tags WHERE Id_author = ?
+		UNION ALL
+		SELECT 'threads', Id_threads FROM threads WHERE Id_author = ?
+		UNION ALL
+		SELECT 'posts', Id_posts FROM posts WHERE Id_author = ?;`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, id, id, id, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	content := make(map[string][]int)
+
+	for rows.Next() {
+		var table string
+		var contentID int
+
+		err = rows.Scan(&table, &contentID)
+		if err != nil {
+			return nil, err
+		}
+
+		content[table] = append(content[table], contentID)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return content, nil
+}
+
 func (m UserModel) Delete(id int) error {
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
@@ -114,3 +114,33 @@ func TestUserModel_GetByID(t *testing.T) {
 		})
 	}
 }
+
+func TestUser_IsBlocked(t *testing.T) {
+
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name          string
+		user          User
+		wantBlocked   bool
+		wantActivated bool
+	}{
+		{name: "Activated", user: User{Status: UserStatus.Activated}, wantActivated: true},
+		{name: "Suspended indefinitely", user: User{Status: UserStatus.Blocked}, wantBlocked: true},
+		{name: "Suspended until later", user: User{Status: UserStatus.Blocked, SuspendedUntil: &future}, wantBlocked: true},
+		{name: "Suspension expired", user: User{Status: UserStatus.Blocked, SuspendedUntil: &past}, wantActivated: true},
+		{name: "To confirm", user: User{Status: UserStatus.ToConfirm}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.user.IsBlocked(); got != tt.wantBlocked {
+				t.Errorf("IsBlocked() = %v, want %v", got, tt.wantBlocked)
+			}
+			if got := tt.user.IsActivated(); got != tt.wantActivated {
+				t.Errorf("IsActivated() = %v, want %v", got, tt.wantActivated)
+			}
+		})
+	}
+}
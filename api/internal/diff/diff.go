@@ -0,0 +1,151 @@
+package diff
+
+import (
+	"unicode"
+)
+
+// maxCells bounds the size of the LCS table: beyond it, the texts are considered entirely replaced
+const maxCells = 4_000_000
+
+type opType struct {
+	Equal  string
+	Insert string
+	Delete string
+}
+
+var OpType = opType{
+	Equal:  "equal",
+	Insert: "insert",
+	Delete: "delete",
+}
+
+// Op is a piece of text kept, inserted or deleted when going from the old text to the new one
+type Op struct {
+	Type string `json:"op"`
+	Text string `json:"text"`
+}
+
+// Words returns the operations turning the text from into the text to, word by word.
+// The whitespaces are kept as separate tokens, so that joining the texts of the equal and
+// deleted operations gives back from, and joining the equal and inserted ones gives back to.
+func Words(from, to string) []Op {
+
+	a := tokenize(from)
+	b := tokenize(to)
+
+	// trimming the common prefix and suffix to keep the table small
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []Op
+	for _, token := range a[:prefix] {
+		ops = appendOp(ops, OpType.Equal, token)
+	}
+	ops = append(ops, lcs(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, token := range a[len(a)-suffix:] {
+		ops = appendOp(ops, OpType.Equal, token)
+	}
+
+	return merge(ops)
+}
+
+// lcs diffs the tokens with the longest common subsequence
+func lcs(a, b []string) []Op {
+
+	var ops []Op
+
+	if (len(a)+1)*(len(b)+1) > maxCells {
+		for _, token := range a {
+			ops = appendOp(ops, OpType.Delete, token)
+		}
+		for _, token := range b {
+			ops = appendOp(ops, OpType.Insert, token)
+		}
+		return ops
+	}
+
+	// table[i][j] is the length of the LCS of a[i:] and b[j:]
+	table := make([][]int, len(a)+1)
+	for i := range table {
+		table[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				table[i][j] = table[i+1][j+1] + 1
+			} else {
+				table[i][j] = max(table[i+1][j], table[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = appendOp(ops, OpType.Equal, a[i])
+			i++
+			j++
+		case table[i+1][j] >= table[i][j+1]:
+			ops = appendOp(ops, OpType.Delete, a[i])
+			i++
+		default:
+			ops = appendOp(ops, OpType.Insert, b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = appendOp(ops, OpType.Delete, a[i])
+	}
+	for ; j < len(b); j++ {
+		ops = appendOp(ops, OpType.Insert, b[j])
+	}
+
+	return ops
+}
+
+// tokenize splits the text in words and runs of whitespaces
+func tokenize(text string) []string {
+
+	var tokens []string
+
+	start := 0
+	inSpace := false
+	for i, r := range text {
+		if i > start && unicode.IsSpace(r) != inSpace {
+			tokens = append(tokens, text[start:i])
+			start = i
+		}
+		inSpace = unicode.IsSpace(r)
+	}
+	if start < len(text) {
+		tokens = append(tokens, text[start:])
+	}
+
+	return tokens
+}
+
+func appendOp(ops []Op, opType, text string) []Op {
+	if len(ops) > 0 && ops[len(ops)-1].Type == opType {
+		ops[len(ops)-1].Text += text
+		return ops
+	}
+	return append(ops, Op{Type: opType, Text: text})
+}
+
+// merge joins the consecutive operations of the same type
+func merge(ops []Op) []Op {
+
+	var merged []Op
+	for _, op := range ops {
+		merged = appendOp(merged, op.Type, op.Text)
+	}
+
+	return merged
+}
@@ -0,0 +1,81 @@
+package diff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWords(t *testing.T) {
+
+	tests := []struct {
+		name string
+		from string
+		to   string
+		want []Op
+	}{
+		{
+			name: "identical",
+			from: "hello world",
+			to:   "hello world",
+			want: []Op{{Type: OpType.Equal, Text: "hello world"}},
+		},
+		{
+			name: "word replaced",
+			from: "the quick brown fox",
+			to:   "the slow brown fox",
+			want: []Op{
+				{Type: OpType.Equal, Text: "the "},
+				{Type: OpType.Delete, Text: "quick"},
+				{Type: OpType.Insert, Text: "slow"},
+				{Type: OpType.Equal, Text: " brown fox"},
+			},
+		},
+		{
+			name: "words appended",
+			from: "hello",
+			to:   "hello there world",
+			want: []Op{
+				{Type: OpType.Equal, Text: "hello"},
+				{Type: OpType.Insert, Text: " there world"},
+			},
+		},
+		{
+			name: "from empty",
+			from: "",
+			to:   "new text",
+			want: []Op{{Type: OpType.Insert, Text: "new text"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Words(tt.from, tt.to)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Words() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWords_RebuildsTexts(t *testing.T) {
+
+	from := "Il était une fois,\nun forum   très calme."
+	to := "Il était deux fois,\nun forum calme et très actif."
+
+	var rebuiltFrom, rebuiltTo strings.Builder
+	for _, op := range Words(from, to) {
+		if op.Type != OpType.Insert {
+			rebuiltFrom.WriteString(op.Text)
+		}
+		if op.Type != OpType.Delete {
+			rebuiltTo.WriteString(op.Text)
+		}
+	}
+
+	if rebuiltFrom.String() != from {
+		t.Errorf("rebuilt from = %q, want %q", rebuiltFrom.String(), from)
+	}
+	if rebuiltTo.String() != to {
+		t.Errorf("rebuilt to = %q, want %q", rebuiltTo.String(), to)
+	}
+}
@@ -0,0 +1,52 @@
+package markdown
+
+import (
+	"bytes"
+	"regexp"
+
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	highlighting "github.com/yuin/goldmark-highlighting/v2"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer/html"
+)
+
+var (
+	// converter renders the GitHub flavoured Markdown, keeping the line breaks typed by the users
+	// and highlighting the fenced code blocks with CSS classes (the raw HTML is never rendered)
+	converter = goldmark.New(
+		goldmark.WithExtensions(
+			extension.GFM,
+			highlighting.NewHighlighting(
+				highlighting.WithFormatOptions(chromahtml.WithClasses(true)),
+			),
+		),
+		goldmark.WithRendererOptions(html.WithHardWraps()),
+	)
+
+	// policy keeps the user generated content elements and the classes of the highlighted code
+	policy = newPolicy()
+)
+
+func newPolicy() *bluemonday.Policy {
+
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^[a-z0-9 -]+$`)).OnElements("pre", "code", "span")
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+
+	return p
+}
+
+// Render converts the Markdown source to sanitized HTML
+func Render(source string) (string, error) {
+
+	var buf bytes.Buffer
+
+	err := converter.Convert([]byte(source), &buf)
+	if err != nil {
+		return "", err
+	}
+
+	return policy.Sanitize(buf.String()), nil
+}
@@ -0,0 +1,68 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		source   string
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "paragraph with line break",
+			source:   "first line\nsecond line",
+			contains: []string{"<p>first line<br>", "second line</p>"},
+		},
+		{
+			name:     "fenced code block",
+			source:   "```go\nfunc main() {}\n```",
+			contains: []string{`<pre class="chroma">`, `<span class="kd">func</span>`},
+		},
+		{
+			name:     "list and quote",
+			source:   "- one\n- two\n\n> quoted",
+			contains: []string{"<ul>", "<li>one</li>", "<blockquote>"},
+		},
+		{
+			name:     "link",
+			source:   "[forum](https://example.com)",
+			contains: []string{`href="https://example.com"`, `rel="nofollow noopener"`, `target="_blank"`},
+		},
+		{
+			name:     "raw html",
+			source:   "<script>alert(1)</script><b onclick=\"alert(1)\">bold</b>",
+			excludes: []string{"<script", "onclick"},
+		},
+		{
+			name:     "javascript link",
+			source:   "[click](javascript:alert(1))",
+			excludes: []string{"javascript:"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			html, err := Render(tt.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, s := range tt.contains {
+				if !strings.Contains(html, s) {
+					t.Errorf("got %q; want it to contain %q", html, s)
+				}
+			}
+			for _, s := range tt.excludes {
+				if strings.Contains(html, s) {
+					t.Errorf("got %q; want it not to contain %q", html, s)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package markdown
+
+import (
+	"regexp"
+)
+
+var (
+	// referenceRX matches an @username or a #tagname which is not part of a word, an email address, an url or an HTML entity
+	referenceRX = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_@#&/])([@#])([\p{L}\p{N}_-]+(?:\.[\p{L}\p{N}_-]+)*)`)
+
+	// codeRX matches the fenced code blocks (possibly unterminated) and the code spans, where nothing is referenced
+	codeRX = regexp.MustCompile("(?s)```.*?(?:```|$)|`[^`\n]*`")
+)
+
+// Reference is an @username or #tagname found in a Markdown source
+//
+// Offset and Length are in bytes and include the prefix.
+type Reference struct {
+	Prefix byte
+	Name   string
+	Offset int
+	Length int
+}
+
+// References returns the @username and #tagname references of the source, in order of appearance
+func References(source string) []Reference {
+
+	code := codeRX.FindAllStringIndex(source, -1)
+
+	var references []Reference
+
+	for _, match := range referenceRX.FindAllStringSubmatchIndex(source, -1) {
+		start, end := match[2], match[5]
+
+		inCode := false
+		for _, span := range code {
+			if start >= span[0] && start < span[1] {
+				inCode = true
+				break
+			}
+		}
+		if inCode {
+			continue
+		}
+
+		references = append(references, Reference{
+			Prefix: source[start],
+			Name:   source[match[4]:end],
+			Offset: start,
+			Length: end - start,
+		})
+	}
+
+	return references
+}
@@ -0,0 +1,52 @@
+package markdown
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReferences(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		source string
+		want   []Reference
+	}{
+		{
+			name:   "user and tag",
+			source: "thanks @alice, see #golang",
+			want: []Reference{
+				{Prefix: '@', Name: "alice", Offset: 7, Length: 6},
+				{Prefix: '#', Name: "golang", Offset: 19, Length: 7},
+			},
+		},
+		{
+			name:   "trailing punctuation",
+			source: "ask @bob.smith.",
+			want:   []Reference{{Prefix: '@', Name: "bob.smith", Offset: 4, Length: 10}},
+		},
+		{
+			name:   "email, url and entity",
+			source: "mail me at bob@example.com or https://example.com/#top &#35;",
+		},
+		{
+			name:   "markdown heading",
+			source: "# Title",
+		},
+		{
+			name:   "code",
+			source: "`@alice` then\n```\n#golang\n```\n@bob",
+			want:   []Reference{{Prefix: '@', Name: "bob", Offset: 30, Length: 4}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			got := References(tt.source)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v; want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"slices"
+
+	xdraw "golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// MaxPixels is the maximum number of pixels of an image, checked before decoding it to avoid decompression bombs
+const MaxPixels = 40_000_000
+
+var (
+	ErrUnsupportedType = errors.New("unsupported file type")
+	ErrTooManyPixels   = errors.New("image too large")
+
+	// ImageContentTypes are the sniffed content types of the images accepted for uploads
+	ImageContentTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp"}
+
+	// PermittedContentTypes are the sniffed content types accepted for uploads
+	PermittedContentTypes = slices.Concat(ImageContentTypes, []string{"application/pdf", "text/plain; charset=utf-8"})
+
+	extensions = map[string]string{
+		"image/jpeg":                ".jpg",
+		"image/png":                 ".png",
+		"application/pdf":           ".pdf",
+		"text/plain; charset=utf-8": ".txt",
+	}
+)
+
+// File is the content of an uploaded file once processed, ready to be stored
+type File struct {
+	Data        []byte
+	ContentType string
+	Extension   string
+	Width       int
+	Height      int
+}
+
+// Sniff returns the content type of the data, ignoring whatever the client declared
+func Sniff(data []byte) string {
+	return http.DetectContentType(data)
+}
+
+// IsImage reports whether the content type is one of the permitted images
+func IsImage(contentType string) bool {
+	return slices.Contains(ImageContentTypes, contentType)
+}
+
+// Process checks the type of the data and returns it ready to be stored.
+// The images are decoded and re-encoded (dropping their metadata) along with a thumbnail
+// fitting in a thumbnailSize square, whereas the other files are kept as they are (without thumbnail).
+func Process(data []byte, thumbnailSize int) (*File, *File, error) {
+
+	contentType := Sniff(data)
+	if !slices.Contains(PermittedContentTypes, contentType) {
+		return nil, nil, ErrUnsupportedType
+	}
+
+	if !IsImage(contentType) {
+		return &File{Data: data, ContentType: contentType, Extension: extensions[contentType]}, nil, nil
+	}
+
+	img, err := decode(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// PNG and GIF images may be transparent, so they stay lossless (an animated GIF keeps its first frame)
+	lossless := contentType == "image/png" || contentType == "image/gif"
+
+	file, err := encode(img, lossless)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	thumbnail, err := encode(fit(img, thumbnailSize), lossless)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return file, thumbnail, nil
+}
+
+// Avatar returns the image cropped to a centred square and resized to size pixels wide, as a PNG image
+func Avatar(data []byte, size int) (*File, error) {
+
+	if !IsImage(Sniff(data)) {
+		return nil, ErrUnsupportedType
+	}
+
+	img, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := img.Bounds()
+	side := min(bounds.Dx(), bounds.Dy())
+	square := image.Rect(0, 0, side, side).Add(image.Pt(bounds.Min.X+(bounds.Dx()-side)/2, bounds.Min.Y+(bounds.Dy()-side)/2))
+
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, square, draw.Src, nil)
+
+	return encode(dst, true)
+}
+
+// decode decodes the image after checking its dimensions
+func decode(data []byte) (image.Image, error) {
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedType
+	}
+	if config.Width*config.Height > MaxPixels {
+		return nil, ErrTooManyPixels
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedType
+	}
+
+	return img, nil
+}
+
+// fit returns the image scaled down to fit in a size pixels wide square, or the image itself if it already fits
+func fit(img image.Image, size int) image.Image {
+
+	bounds := img.Bounds()
+	if bounds.Dx() <= size && bounds.Dy() <= size {
+		return img
+	}
+
+	width, height := size, bounds.Dy()*size/bounds.Dx()
+	if bounds.Dy() > bounds.Dx() {
+		width, height = bounds.Dx()*size/bounds.Dy(), size
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, max(width, 1), max(height, 1)))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+
+	return dst
+}
+
+// encode encodes the image as PNG if lossless, as JPEG otherwise
+func encode(img image.Image, lossless bool) (*File, error) {
+
+	var buf bytes.Buffer
+	var err error
+
+	file := &File{
+		Width:  img.Bounds().Dx(),
+		Height: img.Bounds().Dy(),
+	}
+
+	if lossless {
+		err = png.Encode(&buf, img)
+		file.ContentType = "image/png"
+	} else {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85})
+		file.ContentType = "image/jpeg"
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	file.Data = buf.Bytes()
+	file.Extension = extensions[file.ContentType]
+
+	return file, nil
+}
@@ -0,0 +1,121 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func newImage(width, height int) image.Image {
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := range width {
+		for y := range height {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+
+	return img
+}
+
+func TestProcess(t *testing.T) {
+
+	var pngData, jpegData bytes.Buffer
+	if err := png.Encode(&pngData, newImage(800, 400)); err != nil {
+		t.Fatal(err)
+	}
+	if err := jpeg.Encode(&jpegData, newImage(100, 50), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name            string
+		data            []byte
+		wantType        string
+		wantThumbnail   bool
+		thumbnailWidth  int
+		thumbnailHeight int
+		wantErr         error
+	}{
+		{
+			name:            "png scaled down",
+			data:            pngData.Bytes(),
+			wantType:        "image/png",
+			wantThumbnail:   true,
+			thumbnailWidth:  320,
+			thumbnailHeight: 160,
+		},
+		{
+			name:            "small jpeg",
+			data:            jpegData.Bytes(),
+			wantType:        "image/jpeg",
+			wantThumbnail:   true,
+			thumbnailWidth:  100,
+			thumbnailHeight: 50,
+		},
+		{
+			name:     "plain text",
+			data:     []byte("some notes"),
+			wantType: "text/plain; charset=utf-8",
+		},
+		{
+			name:    "executable",
+			data:    []byte("MZ\x90\x00\x03\x00\x00\x00\x04\x00\x00\x00\xff\xff\x00\x00"),
+			wantErr: ErrUnsupportedType,
+		},
+		{
+			name:    "fake png",
+			data:    append([]byte("\x89PNG\r\n\x1a\n"), []byte("not really an image")...),
+			wantErr: ErrUnsupportedType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			file, thumbnail, err := Process(tt.data, 320)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v; want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			if file.ContentType != tt.wantType {
+				t.Errorf("got type %q; want %q", file.ContentType, tt.wantType)
+			}
+			if (thumbnail != nil) != tt.wantThumbnail {
+				t.Fatalf("got thumbnail %v; want %v", thumbnail != nil, tt.wantThumbnail)
+			}
+			if thumbnail != nil && (thumbnail.Width != tt.thumbnailWidth || thumbnail.Height != tt.thumbnailHeight) {
+				t.Errorf("got thumbnail of %dx%d; want %dx%d", thumbnail.Width, thumbnail.Height, tt.thumbnailWidth, tt.thumbnailHeight)
+			}
+		})
+	}
+}
+
+func TestAvatar(t *testing.T) {
+
+	var data bytes.Buffer
+	if err := jpeg.Encode(&data, newImage(300, 200), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	avatar, err := Avatar(data.Bytes(), 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if avatar.ContentType != "image/png" || avatar.Width != 256 || avatar.Height != 256 {
+		t.Errorf("got %s of %dx%d; want image/png of 256x256", avatar.ContentType, avatar.Width, avatar.Height)
+	}
+
+	_, err = Avatar([]byte("%PDF-1.4 not an image"), 256)
+	if !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("got error %v; want %v", err, ErrUnsupportedType)
+	}
+}
@@ -0,0 +1,149 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang.org/x/oauth2"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	// GitHubKey is the key of the GitHub provider in the routes and the user_identities table
+	GitHubKey = "github"
+	// GitHubURL and GitHubAPIURL are the default addresses of GitHub (overridden for GitHub Enterprise or the tests)
+	GitHubURL    = "https://github.com"
+	GitHubAPIURL = "https://api.github.com"
+)
+
+// GitHub logs the users in with a GitHub OAuth app, which doesn't implement OpenID Connect:
+// the identity is read from the REST API with the access token instead of an ID token.
+type GitHub struct {
+	webURL       string
+	apiURL       string
+	clientID     string
+	clientSecret string
+}
+
+func NewGitHub(webURL, apiURL, clientID, clientSecret string) *GitHub {
+	return &GitHub{
+		webURL:       strings.TrimSuffix(webURL, "/"),
+		apiURL:       strings.TrimSuffix(apiURL, "/"),
+		clientID:     clientID,
+		clientSecret: clientSecret,
+	}
+}
+
+func (p *GitHub) Key() string {
+	return GitHubKey
+}
+
+func (p *GitHub) Name() string {
+	return "GitHub"
+}
+
+func (p *GitHub) config(redirectURI string) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     p.clientID,
+		ClientSecret: p.clientSecret,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:   p.webURL + "/login/oauth/authorize",
+			TokenURL:  p.webURL + "/login/oauth/access_token",
+			AuthStyle: oauth2.AuthStyleInParams,
+		},
+		RedirectURL: redirectURI,
+		Scopes:      []string{"read:user", "user:email"},
+	}
+}
+
+// AuthCodeURL ignores the nonce, which only exists with ID tokens (the state and PKCE protect the flow)
+func (p *GitHub) AuthCodeURL(_ context.Context, redirectURI, state, codeChallenge, _ string) (string, error) {
+	return p.config(redirectURI).AuthCodeURL(state, pkceOptions(codeChallenge)...), nil
+}
+
+func (p *GitHub) Exchange(ctx context.Context, code, codeVerifier, redirectURI, _ string) (*Identity, error) {
+
+	config := p.config(redirectURI)
+
+	token, err := config.Exchange(ctx, code, oauth2.VerifierOption(codeVerifier))
+	if err != nil {
+		return nil, err
+	}
+
+	client := config.Client(ctx, token)
+
+	var user struct {
+		ID    int64  `json:"id"`
+		Login string `json:"login"`
+	}
+
+	err = p.get(client, "/user", &user)
+	if err != nil {
+		return nil, err
+	}
+
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+
+	err = p.get(client, "/user/emails", &emails)
+	if err != nil {
+		return nil, err
+	}
+
+	identity := &Identity{
+		Provider: GitHubKey,
+		Subject:  strconv.FormatInt(user.ID, 10),
+		Name:     user.Login,
+	}
+
+	if user.ID == 0 {
+		return nil, errors.New("no user id in the GitHub response")
+	}
+
+	// the primary address if verified, or else the first verified one, or else the unverified primary one
+	for _, email := range emails {
+		switch {
+		case email.Primary && email.Verified:
+			identity.Email, identity.EmailVerified = email.Email, true
+		case email.Verified && !identity.EmailVerified:
+			identity.Email, identity.EmailVerified = email.Email, true
+		case email.Primary && identity.Email == "":
+			identity.Email = email.Email
+		}
+	}
+
+	if identity.Email == "" {
+		return nil, ErrNoEmail
+	}
+
+	return identity, nil
+}
+
+// get reads the JSON response of the GitHub API endpoint
+func (p *GitHub) get(client *http.Client, path string, dst any) error {
+
+	req, err := http.NewRequest(http.MethodGet, p.apiURL+path, nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("GitHub %s: unexpected status %s", path, res.Status)
+	}
+
+	return json.NewDecoder(res.Body).Decode(dst)
+}
@@ -0,0 +1,214 @@
+package oidc
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	gooidc "github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+	"sync"
+)
+
+var (
+	ErrUnknownProvider = errors.New("unknown identity provider")
+	ErrInvalidNonce    = errors.New("invalid ID token nonce")
+	ErrNoEmail         = errors.New("no email address given by the identity provider")
+)
+
+// Identity is the user as known by an external identity provider
+type Identity struct {
+	Provider      string
+	Subject       string // the user's unique and stable id at the provider
+	Email         string
+	EmailVerified bool
+	Name          string // the user's login or name, used to name the new accounts
+}
+
+// Provider logs the users in with the authorization code flow, protected with PKCE (RFC 7636).
+// The client starting the flow keeps the code verifier, the state and the nonce in its session,
+// and only sends the code challenge (S256) to build the authorization URL.
+type Provider interface {
+	Key() string
+	Name() string
+	AuthCodeURL(ctx context.Context, redirectURI, state, codeChallenge, nonce string) (string, error)
+	Exchange(ctx context.Context, code, codeVerifier, redirectURI, nonce string) (*Identity, error)
+}
+
+// pkceOptions adds the code challenge of the verifier kept by the client to the authorization URL
+func pkceOptions(codeChallenge string) []oauth2.AuthCodeOption {
+	return []oauth2.AuthCodeOption{
+		oauth2.SetAuthURLParam("code_challenge", codeChallenge),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+	}
+}
+
+// Find returns the provider of the key among the configured ones
+func Find(providers []Provider, key string) (Provider, error) {
+	for _, provider := range providers {
+		if provider.Key() == key {
+			return provider, nil
+		}
+	}
+	return nil, ErrUnknownProvider
+}
+
+// OpenID is a generic OpenID Connect provider, configured with its issuer's discovery document
+type OpenID struct {
+	key          string
+	name         string
+	issuer       string
+	clientID     string
+	clientSecret string
+
+	mu       sync.Mutex
+	provider *gooidc.Provider
+}
+
+func NewOpenID(key, name, issuer, clientID, clientSecret string) *OpenID {
+	return &OpenID{
+		key:          key,
+		name:         name,
+		issuer:       issuer,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+	}
+}
+
+func (p *OpenID) Key() string {
+	return p.key
+}
+
+func (p *OpenID) Name() string {
+	return p.name
+}
+
+// discover fetches the issuer's configuration the first time it is needed
+// (so that the API starts even if the provider is unreachable, and retries later)
+func (p *OpenID) discover(ctx context.Context) (*gooidc.Provider, error) {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.provider != nil {
+		return p.provider, nil
+	}
+
+	provider, err := gooidc.NewProvider(ctx, p.issuer)
+	if err != nil {
+		return nil, fmt.Errorf("discovering %s: %w", p.issuer, err)
+	}
+
+	p.provider = provider
+
+	return provider, nil
+}
+
+func (p *OpenID) config(provider *gooidc.Provider, redirectURI string) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     p.clientID,
+		ClientSecret: p.clientSecret,
+		Endpoint:     provider.Endpoint(),
+		RedirectURL:  redirectURI,
+		Scopes:       []string{gooidc.ScopeOpenID, "email", "profile"},
+	}
+}
+
+func (p *OpenID) AuthCodeURL(ctx context.Context, redirectURI, state, codeChallenge, nonce string) (string, error) {
+
+	provider, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	options := append(pkceOptions(codeChallenge), gooidc.Nonce(nonce))
+
+	return p.config(provider, redirectURI).AuthCodeURL(state, options...), nil
+}
+
+func (p *OpenID) Exchange(ctx context.Context, code, codeVerifier, redirectURI, nonce string) (*Identity, error) {
+
+	provider, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	config := p.config(provider, redirectURI)
+
+	token, err := config.Exchange(ctx, code, oauth2.VerifierOption(codeVerifier))
+	if err != nil {
+		return nil, err
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("no ID token in the token response")
+	}
+
+	idToken, err := provider.Verifier(&gooidc.Config{ClientID: p.clientID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// the nonce of the authorization request is mandatory, binding the ID token to the client's session
+	if nonce == "" || subtle.ConstantTimeCompare([]byte(idToken.Nonce), []byte(nonce)) != 1 {
+		return nil, ErrInvalidNonce
+	}
+
+	var claims struct {
+		Email             string `json:"email"`
+		EmailVerified     bool   `json:"email_verified"`
+		Name              string `json:"name"`
+		PreferredUsername string `json:"preferred_username"`
+	}
+
+	err = idToken.Claims(&claims)
+	if err != nil {
+		return nil, err
+	}
+
+	// some providers only give the email address with the userinfo endpoint
+	if claims.Email == "" && provider.UserInfoEndpoint() != "" {
+		userInfo, err := provider.UserInfo(ctx, config.TokenSource(ctx, token))
+		if err != nil {
+			return nil, err
+		}
+
+		if userInfo.Subject != idToken.Subject {
+			return nil, errors.New("userinfo subject doesn't match the ID token")
+		}
+
+		// go-oidc already reads email_verified given as a string by some providers
+		var profile struct {
+			Name              string `json:"name"`
+			PreferredUsername string `json:"preferred_username"`
+		}
+
+		err = userInfo.Claims(&profile)
+		if err != nil {
+			return nil, err
+		}
+
+		claims.Email = userInfo.Email
+		claims.EmailVerified = userInfo.EmailVerified
+		claims.Name = profile.Name
+		claims.PreferredUsername = profile.PreferredUsername
+	}
+
+	if claims.Email == "" {
+		return nil, ErrNoEmail
+	}
+
+	identity := &Identity{
+		Provider:      p.key,
+		Subject:       idToken.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Name:          claims.PreferredUsername,
+	}
+	if identity.Name == "" {
+		identity.Name = claims.Name
+	}
+
+	return identity, nil
+}
@@ -0,0 +1,365 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/go-jose/go-jose/v4"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testClientID     = "forum"
+	testClientSecret = "secret"
+	testRedirectURI  = "http://localhost:3000/auth/mock/callback"
+	testVerifier     = "dBjftJeZ4CVP-mJ92K1s-0MdkH-gy0tX7jqjvKZAAqR"
+)
+
+func challenge(verifier string) string {
+	hash := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+// mockServer is a minimal OpenID Connect provider (or GitHub) checking the PKCE verifier of the code it issued
+type mockServer struct {
+	*httptest.Server
+	t         *testing.T
+	key       *rsa.PrivateKey
+	challenge string
+	nonce     string
+	claims    map[string]any
+	userInfo  map[string]any
+}
+
+func newMockServer(t *testing.T, claims map[string]any) *mockServer {
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := &mockServer{t: t, key: key, claims: claims}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", m.discovery)
+	mux.HandleFunc("/keys", m.keys)
+	mux.HandleFunc("/authorize", m.authorize)
+	mux.HandleFunc("/token", m.token)
+	mux.HandleFunc("/userinfo", m.userinfo)
+	// GitHub's endpoints
+	mux.HandleFunc("/login/oauth/authorize", m.authorize)
+	mux.HandleFunc("/login/oauth/access_token", m.token)
+
+	m.Server = httptest.NewServer(mux)
+	t.Cleanup(m.Close)
+
+	return m
+}
+
+func (m *mockServer) writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func (m *mockServer) discovery(w http.ResponseWriter, r *http.Request) {
+	m.writeJSON(w, map[string]any{
+		"issuer":                                m.URL,
+		"authorization_endpoint":                m.URL + "/authorize",
+		"token_endpoint":                        m.URL + "/token",
+		"userinfo_endpoint":                     m.URL + "/userinfo",
+		"jwks_uri":                              m.URL + "/keys",
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+func (m *mockServer) keys(w http.ResponseWriter, r *http.Request) {
+	m.writeJSON(w, jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{Key: &m.key.PublicKey, KeyID: "test", Algorithm: "RS256", Use: "sig"},
+	}})
+}
+
+// authorize records the challenge and the nonce of the authorization request, as if the user had consented
+func (m *mockServer) authorize(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	if qs.Get("code_challenge_method") != "S256" {
+		http.Error(w, "invalid code_challenge_method", http.StatusBadRequest)
+		return
+	}
+	m.challenge = qs.Get("code_challenge")
+	m.nonce = qs.Get("nonce")
+}
+
+func (m *mockServer) token(w http.ResponseWriter, r *http.Request) {
+
+	err := r.ParseForm()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if r.PostForm.Get("code") != "code" || challenge(r.PostForm.Get("code_verifier")) != m.challenge {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid_grant"}`))
+		return
+	}
+
+	response := map[string]any{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+	}
+
+	if m.claims != nil {
+		claims := map[string]any{
+			"iss":   m.URL,
+			"aud":   testClientID,
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"iat":   time.Now().Unix(),
+			"nonce": m.nonce,
+		}
+		for key, value := range m.claims {
+			claims[key] = value
+		}
+		response["id_token"] = m.sign(claims)
+	}
+
+	m.writeJSON(w, response)
+}
+
+func (m *mockServer) sign(claims map[string]any) string {
+
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: m.key}, (&jose.SignerOptions{}).WithHeader("kid", "test"))
+	if err != nil {
+		m.t.Fatal(err)
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		m.t.Fatal(err)
+	}
+
+	jws, err := signer.Sign(payload)
+	if err != nil {
+		m.t.Fatal(err)
+	}
+
+	token, err := jws.CompactSerialize()
+	if err != nil {
+		m.t.Fatal(err)
+	}
+
+	return token
+}
+
+func (m *mockServer) userinfo(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer access" || m.userInfo == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	m.writeJSON(w, m.userInfo)
+}
+
+// authorize follows the authorization URL given by the provider, as the user's browser would
+func authorize(t *testing.T, provider Provider, state, nonce string) {
+
+	authURL, err := provider.AuthCodeURL(context.Background(), testRedirectURI, state, challenge(testVerifier), nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	qs := u.Query()
+	if qs.Get("state") != state || qs.Get("client_id") != testClientID || qs.Get("redirect_uri") != testRedirectURI {
+		t.Fatalf("unexpected authorization URL %q", authURL)
+	}
+
+	res, err := http.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("authorization: got status %d", res.StatusCode)
+	}
+}
+
+func TestOpenID(t *testing.T) {
+
+	t.Run("ID token", func(t *testing.T) {
+		server := newMockServer(t, map[string]any{
+			"sub":                "42",
+			"email":              "jane@example.com",
+			"email_verified":     true,
+			"name":               "Jane Doe",
+			"preferred_username": "jane",
+		})
+		provider := NewOpenID("mock", "Mock", server.URL, testClientID, testClientSecret)
+
+		authorize(t, provider, "state", "nonce")
+
+		identity, err := provider.Exchange(context.Background(), "code", testVerifier, testRedirectURI, "nonce")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := Identity{Provider: "mock", Subject: "42", Email: "jane@example.com", EmailVerified: true, Name: "jane"}
+		if *identity != want {
+			t.Errorf("got %+v; want %+v", *identity, want)
+		}
+	})
+
+	t.Run("userinfo", func(t *testing.T) {
+		server := newMockServer(t, map[string]any{"sub": "42"})
+		server.userInfo = map[string]any{"sub": "42", "email": "jane@example.com", "email_verified": false, "name": "Jane Doe"}
+		provider := NewOpenID("mock", "Mock", server.URL, testClientID, testClientSecret)
+
+		authorize(t, provider, "state", "nonce")
+
+		identity, err := provider.Exchange(context.Background(), "code", testVerifier, testRedirectURI, "nonce")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := Identity{Provider: "mock", Subject: "42", Email: "jane@example.com", Name: "Jane Doe"}
+		if *identity != want {
+			t.Errorf("got %+v; want %+v", *identity, want)
+		}
+	})
+
+	t.Run("wrong verifier", func(t *testing.T) {
+		server := newMockServer(t, map[string]any{"sub": "42", "email": "jane@example.com"})
+		provider := NewOpenID("mock", "Mock", server.URL, testClientID, testClientSecret)
+
+		authorize(t, provider, "state", "nonce")
+
+		_, err := provider.Exchange(context.Background(), "code", strings.Repeat("x", 43), testRedirectURI, "nonce")
+		if err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+			t.Errorf("got %v; want invalid_grant", err)
+		}
+	})
+
+	t.Run("wrong nonce", func(t *testing.T) {
+		server := newMockServer(t, map[string]any{"sub": "42", "email": "jane@example.com"})
+		provider := NewOpenID("mock", "Mock", server.URL, testClientID, testClientSecret)
+
+		authorize(t, provider, "state", "nonce")
+
+		_, err := provider.Exchange(context.Background(), "code", testVerifier, testRedirectURI, "other")
+		if !errors.Is(err, ErrInvalidNonce) {
+			t.Errorf("got %v; want %v", err, ErrInvalidNonce)
+		}
+	})
+
+	t.Run("missing nonce", func(t *testing.T) {
+		server := newMockServer(t, map[string]any{"sub": "42", "email": "jane@example.com"})
+		provider := NewOpenID("mock", "Mock", server.URL, testClientID, testClientSecret)
+
+		authorize(t, provider, "state", "")
+
+		_, err := provider.Exchange(context.Background(), "code", testVerifier, testRedirectURI, "")
+		if !errors.Is(err, ErrInvalidNonce) {
+			t.Errorf("got %v; want %v", err, ErrInvalidNonce)
+		}
+	})
+
+	t.Run("no email", func(t *testing.T) {
+		server := newMockServer(t, map[string]any{"sub": "42"})
+		provider := NewOpenID("mock", "Mock", server.URL, testClientID, testClientSecret)
+
+		authorize(t, provider, "state", "nonce")
+
+		_, err := provider.Exchange(context.Background(), "code", testVerifier, testRedirectURI, "nonce")
+		if err == nil {
+			t.Error("got no error; want the userinfo to be refused")
+		}
+	})
+}
+
+func TestGitHub(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		emails    string
+		wantEmail string
+		verified  bool
+		wantErr   error
+	}{
+		{
+			name:      "Primary verified",
+			emails:    `[{"email":"old@example.com","verified":true},{"email":"jane@example.com","primary":true,"verified":true}]`,
+			wantEmail: "jane@example.com",
+			verified:  true,
+		},
+		{
+			name:      "Primary unverified",
+			emails:    `[{"email":"jane@example.com","primary":true},{"email":"old@example.com","verified":true}]`,
+			wantEmail: "old@example.com",
+			verified:  true,
+		},
+		{
+			name:      "Unverified",
+			emails:    `[{"email":"jane@example.com","primary":true}]`,
+			wantEmail: "jane@example.com",
+		},
+		{
+			name:    "No email",
+			emails:  `[]`,
+			wantErr: ErrNoEmail,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newMockServer(t, nil)
+
+			api := http.NewServeMux()
+			api.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") != "Bearer access" {
+					http.Error(w, "unauthorized", http.StatusUnauthorized)
+					return
+				}
+				w.Write([]byte(`{"id":1234,"login":"jane"}`))
+			})
+			api.HandleFunc("/user/emails", func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(tt.emails))
+			})
+			apiServer := httptest.NewServer(api)
+			defer apiServer.Close()
+
+			provider := NewGitHub(server.URL, apiServer.URL, testClientID, testClientSecret)
+
+			authorize(t, provider, "state", "")
+
+			identity, err := provider.Exchange(context.Background(), "code", testVerifier, testRedirectURI, "")
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("got %v; want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := Identity{Provider: GitHubKey, Subject: "1234", Email: tt.wantEmail, EmailVerified: tt.verified, Name: "jane"}
+			if *identity != want {
+				t.Errorf("got %+v; want %+v", *identity, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLocal(t *testing.T) {
+
+	s, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	content := "hello"
+
+	err = s.Put(ctx, "attachments/1/file.txt", strings.NewReader(content), int64(len(content)), "text/plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := s.Get(ctx, "attachments/1/file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(file)
+	file.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != content {
+		t.Errorf("got %q; want %q", got, content)
+	}
+
+	err = s.Delete(ctx, "attachments/1/file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Delete(ctx, "attachments/1/file.txt")
+	if err != nil {
+		t.Errorf("deleting a missing file: got error %v; want nil", err)
+	}
+
+	_, err = s.Get(ctx, "attachments/1/file.txt")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("got error %v; want %v", err, ErrNotFound)
+	}
+
+	for _, key := range []string{"", "../outside.txt", "a//b", "a/./b", "/abs.txt", "a\\b"} {
+		_, err = s.Get(ctx, key)
+		if !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("key %q: got error %v; want %v", key, err, ErrInvalidKey)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+)
+
+var (
+	ErrNotFound   = errors.New("file not found")
+	ErrInvalidKey = errors.New("invalid file key")
+)
+
+// Storage keeps the uploaded files under keys made of slash separated segments (e.g. "avatars/12-x1y2z3.png")
+type Storage interface {
+
+	// Put stores the content read from r (size bytes long) under the key, replacing any previous file
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+
+	// Get opens the file stored under the key, the caller being responsible for closing it.
+	// It returns ErrNotFound if there is none.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// Delete removes the file stored under the key, and does nothing if there is none
+	Delete(ctx context.Context, key string) error
+}
+
+// ValidKey reports whether the key is made of non-empty segments which do not refer to their parents
+func ValidKey(key string) bool {
+
+	if key == "" || strings.ContainsAny(key, "\\\x00") {
+		return false
+	}
+
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return false
+		}
+	}
+
+	return true
+}
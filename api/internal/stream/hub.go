@@ -0,0 +1,163 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+var ErrHubClosed = errors.New("stream hub closed")
+
+type eventType struct {
+	PostCreated      string
+	PostUpdated      string
+	PostDeleted      string
+	ReactionsUpdated string
+	Notification     string
+	Message          string
+}
+
+var EventType = eventType{
+	PostCreated:      "post_created",
+	PostUpdated:      "post_updated",
+	PostDeleted:      "post_deleted",
+	ReactionsUpdated: "reactions_updated",
+	Notification:     "notification",
+	Message:          "message",
+}
+
+// Event is a message published on a channel (e.g. "thread:12" or "user:3")
+type Event struct {
+	ID      uint64 `json:"-"`
+	Channel string `json:"channel"`
+	Type    string `json:"type"`
+	Data    any    `json:"data"`
+}
+
+// Subscriber receives the events published on the channels it subscribed to.
+// Its Events channel is closed when it is unsubscribed, when it is too slow
+// to keep up with the events or when the hub is closed.
+type Subscriber struct {
+	channels map[string]bool
+	events   chan Event
+	once     sync.Once
+}
+
+func (s *Subscriber) Events() <-chan Event {
+	return s.events
+}
+
+func (s *Subscriber) close() {
+	s.once.Do(func() {
+		close(s.events)
+	})
+}
+
+// Hub is an in-process publish/subscribe hub.
+// Publishing never blocks: a subscriber whose buffer is full is considered a slow consumer
+// and is disconnected (an EventSource client reconnects by itself).
+type Hub struct {
+	mu          sync.RWMutex
+	subscribers map[*Subscriber]struct{}
+	bufferSize  int
+	closed      bool
+	lastID      atomic.Uint64
+}
+
+func NewHub(bufferSize int) *Hub {
+	return &Hub{
+		subscribers: make(map[*Subscriber]struct{}),
+		bufferSize:  bufferSize,
+	}
+}
+
+func ThreadChannel(id int) string {
+	return fmt.Sprintf("thread:%d", id)
+}
+
+func UserChannel(id int) string {
+	return fmt.Sprintf("user:%d", id)
+}
+
+func (h *Hub) Subscribe(channels ...string) (*Subscriber, error) {
+
+	subscriber := &Subscriber{
+		channels: make(map[string]bool),
+		events:   make(chan Event, h.bufferSize),
+	}
+	for _, channel := range channels {
+		subscriber.channels[channel] = true
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, ErrHubClosed
+	}
+
+	h.subscribers[subscriber] = struct{}{}
+
+	return subscriber, nil
+}
+
+func (h *Hub) Unsubscribe(subscriber *Subscriber) {
+
+	h.mu.Lock()
+	delete(h.subscribers, subscriber)
+	h.mu.Unlock()
+
+	subscriber.close()
+}
+
+// Publish sends the event to every subscriber of its channel
+func (h *Hub) Publish(channel, eventType string, data any) {
+
+	event := Event{
+		ID:      h.lastID.Add(1),
+		Channel: channel,
+		Type:    eventType,
+		Data:    data,
+	}
+
+	var slow []*Subscriber
+
+	h.mu.RLock()
+	for subscriber := range h.subscribers {
+		if !subscriber.channels[channel] {
+			continue
+		}
+		select {
+		case subscriber.events <- event:
+		default:
+			slow = append(slow, subscriber)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, subscriber := range slow {
+		h.Unsubscribe(subscriber)
+	}
+}
+
+// Subscribers returns the number of connected subscribers
+func (h *Hub) Subscribers() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return len(h.subscribers)
+}
+
+// Close disconnects every subscriber and refuses new subscriptions
+func (h *Hub) Close() {
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for subscriber := range h.subscribers {
+		delete(h.subscribers, subscriber)
+		subscriber.close()
+	}
+}
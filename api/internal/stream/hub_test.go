@@ -0,0 +1,91 @@
+package stream
+
+import (
+	"testing"
+)
+
+func TestHub_Publish(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		channels  []string
+		published string
+		want      int
+	}{
+		{
+			name:      "subscribed channel",
+			channels:  []string{ThreadChannel(1), UserChannel(2)},
+			published: ThreadChannel(1),
+			want:      1,
+		},
+		{
+			name:      "other channel",
+			channels:  []string{ThreadChannel(1)},
+			published: ThreadChannel(2),
+			want:      0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHub(4)
+
+			s, err := h.Subscribe(tt.channels...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			h.Publish(tt.published, "post_created", nil)
+
+			if got := len(s.events); got != tt.want {
+				t.Errorf("Publish() delivered %d events, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHub_SlowConsumer(t *testing.T) {
+
+	h := NewHub(1)
+
+	s, err := h.Subscribe(ThreadChannel(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h.Publish(ThreadChannel(1), "post_created", nil)
+	h.Publish(ThreadChannel(1), "post_created", nil)
+
+	if h.Subscribers() != 0 {
+		t.Errorf("slow consumer still subscribed")
+	}
+
+	// the buffered event is still delivered before the channel is closed
+	if _, ok := <-s.Events(); !ok {
+		t.Errorf("buffered event lost")
+	}
+	if _, ok := <-s.Events(); ok {
+		t.Errorf("events channel not closed")
+	}
+}
+
+func TestHub_Close(t *testing.T) {
+
+	h := NewHub(1)
+
+	s, err := h.Subscribe(UserChannel(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h.Close()
+
+	if _, ok := <-s.Events(); ok {
+		t.Errorf("events channel not closed")
+	}
+	if _, err = h.Subscribe(UserChannel(1)); err != ErrHubClosed {
+		t.Errorf("Subscribe() error = %v, want %v", err, ErrHubClosed)
+	}
+
+	// unsubscribing after the hub is closed must not panic
+	h.Unsubscribe(s)
+}
@@ -0,0 +1,132 @@
+package totp
+
+import (
+	"bytes"
+	"encoding/base32"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA1 seed of the RFC 6238 test vectors ("12345678901234567890")
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestCode(t *testing.T) {
+
+	// RFC 6238 appendix B, keeping the last 6 of the 8 digits
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1111111111, want: "050471"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+		{unix: 20000000000, want: "353130"},
+	}
+
+	for _, tt := range tests {
+		got, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code(%d): unexpected error: %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("Code(%d) = %q, want %q", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestCodeInvalidSecret(t *testing.T) {
+	if _, err := Code("not base32!", 1); err != ErrInvalidSecret {
+		t.Errorf("got error %v, want ErrInvalidSecret", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+
+	now := time.Unix(1111111111, 0)
+	step := Step(now)
+
+	previous, _ := Code(rfcSecret, step-1)
+	next, _ := Code(rfcSecret, step+1)
+	tooOld, _ := Code(rfcSecret, step-2)
+
+	tests := []struct {
+		name     string
+		code     string
+		wantStep int64
+		wantOK   bool
+	}{
+		{name: "current", code: "050471", wantStep: step, wantOK: true},
+		{name: "with spaces", code: " 050 471 ", wantStep: step, wantOK: true},
+		{name: "previous period", code: previous, wantStep: step - 1, wantOK: true},
+		{name: "next period", code: next, wantStep: step + 1, wantOK: true},
+		{name: "outside skew", code: tooOld, wantOK: false},
+		{name: "wrong code", code: "000000", wantOK: false},
+		{name: "wrong length", code: "05047", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStep, gotOK := Validate(rfcSecret, tt.code, now)
+			if gotOK != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", gotOK, tt.wantOK)
+			}
+			if gotOK && gotStep != tt.wantStep {
+				t.Errorf("step = %d, want %d", gotStep, tt.wantStep)
+			}
+		})
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+
+	a, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if a == b {
+		t.Error("two generated secrets are equal")
+	}
+	if _, err := Code(a, 1); err != nil {
+		t.Errorf("generated secret is not usable: %v", err)
+	}
+}
+
+func TestURI(t *testing.T) {
+
+	uri := URI("Threadive", "jane@example.com", "JBSWY3DPEHPK3PXP")
+
+	if !strings.HasPrefix(uri, "otpauth://totp/Threadive:jane@example.com?") {
+		t.Fatalf("unexpected URI %q", uri)
+	}
+
+	u, err := url.Parse(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := u.Query().Get("secret"); got != "JBSWY3DPEHPK3PXP" {
+		t.Errorf("secret = %q", got)
+	}
+	if got := u.Query().Get("issuer"); got != "Threadive" {
+		t.Errorf("issuer = %q", got)
+	}
+}
+
+func TestQRCode(t *testing.T) {
+
+	png, err := QRCode(URI("Threadive", "jane@example.com", "JBSWY3DPEHPK3PXP"), 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(png, []byte("\x89PNG")) {
+		t.Error("not a PNG image")
+	}
+}
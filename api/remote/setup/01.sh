@@ -69,6 +69,9 @@ sudo mysql \
 # to the system-wide environment variables in the /etc/environment file
 echo "FORUM_DB_DSN='threadive:${DB_PASSWORD}@/threadive?parseTime=true'" >> /etc/environment
 
+# Add a stable secret signing the unsubscribe links sent by email
+echo "UNSUBSCRIBE_SECRET='$(openssl rand -hex 32)'" >> /etc/environment
+
 # Install Caddy
 apt install -y debian-keyring debian-archive-keyring apt-transport-https
 curl -1sLf 'https://dl.cloudsmith.io/public/caddy/stable/gpg.key' | sudo gpg --dearmor -o /usr/share/keyrings/caddy-stable-archive-keyring.gpg
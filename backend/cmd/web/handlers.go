@@ -4,14 +4,17 @@ import (
 	"Projet-Forum/internal/api"
 	"Projet-Forum/internal/data"
 	"Projet-Forum/internal/validator"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/alexedwards/flow"
-	"html/template"
+	"io"
+	"net"
 	"net/http"
 	"net/url"
-	"strings"
+	"slices"
+	"strconv"
 	"time"
 )
 
@@ -116,9 +119,23 @@ func (app *application) threadGet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// fetching the branch to display instead of the whole thread if any
+	var branch *data.Post
+	if r.URL.Query().Has("branch") {
+		tmplData.Branch, err = strconv.Atoi(r.URL.Query().Get("branch"))
+		if err != nil || tmplData.Branch < 1 {
+			app.clientError(r, w, http.StatusBadRequest)
+			return
+		}
+	}
+
 	// setting the query according to the required data
 	query := url.Values{
-		"includes[]": {"posts", "tags", "popularity"},
+		"includes[]": {"tags", "popularity"},
+	}
+	if tmplData.Branch == 0 {
+		query["includes[]"] = append(query["includes[]"], "posts")
+		query.Set("layout", "tree")
 	}
 
 	// fetching the thread
@@ -134,6 +151,30 @@ func (app *application) threadGet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// fetching the branch with its replies
+	if tmplData.Branch > 0 && v.Valid() {
+		branch, err = app.models.PostModel.GetByID(app.getToken(r, authTokenSessionManager), tmplData.Branch, v)
+		if err == nil && v.Valid() {
+			branch.Replies, err = app.models.PostModel.GetReplies(app.getToken(r, authTokenSessionManager), branch.ID, nil, v)
+		}
+		if err != nil {
+			switch {
+			case errors.Is(err, api.ErrRecordNotFound):
+				app.clientError(r, w, http.StatusNotFound)
+			default:
+				app.serverError(w, r, err)
+			}
+			return
+		}
+		if v.Valid() {
+			if branch.Thread.ID != tmplData.Thread.ID {
+				app.clientError(r, w, http.StatusNotFound)
+				return
+			}
+			tmplData.Thread.Posts = []data.Post{*branch}
+		}
+	}
+
 	// checking API request errors
 	if !v.Valid() {
 		app.logger.Error(fmt.Sprintf("errors: %+v", string(v.Errors())))
@@ -144,6 +185,15 @@ func (app *application) threadGet(w http.ResponseWriter, r *http.Request) {
 	// DEBUG
 	app.logger.Debug(fmt.Sprintf("Thread: %+v", tmplData.Thread))
 
+	// fetching the subscription of the user to the thread
+	if tmplData.IsAuthenticated {
+		tmplData.Subscription, err = app.models.ThreadModel.GetSubscription(app.getToken(r, authTokenSessionManager), id, v)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
 	// setting the page's title
 	tmplData.Title = fmt.Sprintf("Threadive - %s", tmplData.Thread.Title)
 
@@ -151,6 +201,62 @@ func (app *application) threadGet(w http.ResponseWriter, r *http.Request) {
 	app.render(w, r, http.StatusOK, "thread.tmpl", tmplData)
 }
 
+func (app *application) unsubscribe(w http.ResponseWriter, r *http.Request) {
+
+	// retrieving the unsubscribe token from the URL
+	token := flow.Param(r.Context(), "token")
+	if token == "" {
+		app.clientError(r, w, http.StatusBadRequest)
+		return
+	}
+
+	// API request to unsubscribe the user from the thread
+	v := validator.New()
+	message, err := app.models.ThreadModel.Unsubscribe(token, v)
+	if err != nil && !errors.Is(err, api.ErrRecordNotFound) {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// looking for errors from the API
+	if err != nil || !v.Valid() {
+		app.sessionManager.Put(r.Context(), "flash", "This unsubscribe link is invalid.")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("You have %s.", message))
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+func (app *application) unlock(w http.ResponseWriter, r *http.Request) {
+
+	// retrieving the unlock token from the URL
+	token := flow.Param(r.Context(), "token")
+	if token == "" {
+		app.clientError(r, w, http.StatusBadRequest)
+		return
+	}
+
+	// API request to unlock the user's account
+	v := validator.New()
+	err := app.models.UserModel.Unlock(token, v)
+	if err != nil && !errors.Is(err, api.ErrRecordNotFound) {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// looking for errors from the API
+	if err != nil || !v.Valid() {
+		app.sessionManager.Put(r.Context(), "flash", "This unlock link is invalid or expired.")
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Your account is unlocked, you can log in again.")
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
 func (app *application) tagGet(w http.ResponseWriter, r *http.Request) {
 
 	// retrieving basic template data
@@ -261,45 +367,138 @@ func (app *application) search(w http.ResponseWriter, r *http.Request) {
 	tmplData := app.newTemplateData(r, false, Overlay.Default)
 	tmplData.Title = "Threadive - Search"
 
-	// retrieving the research text
-	tmplData.Search = r.URL.Query().Get("q")
-	if tmplData.Search == "" {
-		tmplData.Search = "*"
+	// retrieving the research text and the facets' values
+	form := newSearchForm()
+	err := app.formDecoder.Decode(form, r.URL.Query())
+	if err != nil {
+		app.clientError(r, w, http.StatusBadRequest)
+		return
 	}
+	tmplData.Search = form.Search
+	tmplData.Form = form
 
-	// fetching the categories
+	// fetching the categories and tags to filter with
 	v := validator.New()
-	var err error
-	tmplData.CategoryList.List, tmplData.CategoryList.Metadata, err = app.models.CategoryModel.Get(app.getToken(r, authTokenSessionManager), r.URL.Query(), v)
+	token := app.getToken(r, authTokenSessionManager)
+	facetsQuery := url.Values{"page_size": {"100"}, "sort": {"Name"}}
+	tmplData.SearchFacets.Categories, _, err = app.models.CategoryModel.Get(token, facetsQuery, v)
 	if err != nil && !errors.Is(err, api.ErrRecordNotFound) {
 		app.serverError(w, r, err)
 		return
 	}
-
-	// fetching the threads
-	tmplData.ThreadList.List, tmplData.ThreadList.Metadata, err = app.models.ThreadModel.Get(app.getToken(r, authTokenSessionManager), r.URL.Query(), v)
+	tmplData.SearchFacets.Tags, _, err = app.models.TagModel.Get(token, facetsQuery, v)
 	if err != nil && !errors.Is(err, api.ErrRecordNotFound) {
 		app.serverError(w, r, err)
 		return
 	}
 
-	// fetching the tags
-	tmplData.TagList.List, tmplData.TagList.Metadata, err = app.models.TagModel.Get(app.getToken(r, authTokenSessionManager), r.URL.Query(), v)
+	if tmplData.Search == "" {
+		app.render(w, r, http.StatusOK, "search.tmpl", tmplData)
+		return
+	}
+
+	// fetching the results
+	hits, metadata, err := app.models.SearchModel.Get(token, r.URL.Query(), v)
 	if err != nil && !errors.Is(err, api.ErrRecordNotFound) {
 		app.serverError(w, r, err)
 		return
 	}
 
-	// checking API request errors
+	// showing the invalid facets to the user
 	if !v.Valid() {
-		app.clientError(r, w, http.StatusBadRequest)
+		tmplData.FieldErrors = v.FieldErrors
+		tmplData.NonFieldErrors = v.NonFieldErrors
+		app.render(w, r, http.StatusUnprocessableEntity, "search.tmpl", tmplData)
 		return
 	}
 
+	// sorting the results by type and listing their authors
+	tmplData.SearchResults.Metadata = metadata
+	for _, hit := range hits {
+		if !slices.ContainsFunc(tmplData.SearchFacets.Authors, func(author data.User) bool { return author.ID == hit.Author.ID }) {
+			tmplData.SearchFacets.Authors = append(tmplData.SearchFacets.Authors, data.User{ID: hit.Author.ID, Name: hit.Author.Name})
+		}
+		switch hit.Type {
+		case "thread":
+			tmplData.SearchResults.Threads = append(tmplData.SearchResults.Threads, hit)
+		case "post":
+			tmplData.SearchResults.Posts = append(tmplData.SearchResults.Posts, hit)
+		case "tag":
+			tmplData.SearchResults.Tags = append(tmplData.SearchResults.Tags, hit)
+		case "category":
+			tmplData.SearchResults.Categories = append(tmplData.SearchResults.Categories, hit)
+		}
+	}
+
 	// render the template
 	app.render(w, r, http.StatusOK, "search.tmpl", tmplData)
 }
 
+func (app *application) stream(w http.ResponseWriter, r *http.Request) {
+
+	// retrieving the channels (e.g. ?channels=thread:12&channels=notifications)
+	channels := r.URL.Query()["channels"]
+	if len(channels) == 0 {
+		app.clientError(r, w, http.StatusBadRequest)
+		return
+	}
+
+	// the server's write timeout doesn't apply to the stream
+	rc := http.NewResponseController(w)
+	err := rc.SetWriteDeadline(time.Time{})
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// opening the stream with the API (closed when the browser disconnects)
+	res, err := app.models.StreamModel.Open(r.Context(), app.getToken(r, authTokenSessionManager), channels)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return
+		}
+		app.serverError(w, r, err)
+		return
+	}
+	defer res.Body.Close()
+
+	// forwarding the API errors as is
+	if res.StatusCode != http.StatusOK {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(res.StatusCode)
+		_, err = io.Copy(w, res.Body)
+		if err != nil {
+			app.logger.Error(err.Error())
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	// relaying the events as soon as they arrive
+	buf := make([]byte, 4096)
+	for {
+		n, err := res.Body.Read(buf)
+		if n > 0 {
+			_, err := w.Write(buf[:n])
+			if err != nil {
+				return
+			}
+			err = rc.Flush()
+			if err != nil {
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
 /* #############################################################################
 /*	USER ACCESS
 /* #############################################################################*/
@@ -503,10 +702,16 @@ func (app *application) loginPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// building the API request body
+	// building the API request body (with the user's device to name the session)
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
 	body := map[string]string{
-		"email":    form.Email,
-		"password": form.Password,
+		"email":      form.Email,
+		"password":   form.Password,
+		"user_agent": r.UserAgent(),
+		"ip":         ip,
 	}
 
 	// API request to authenticate the user
@@ -530,6 +735,11 @@ func (app *application) loginPost(w http.ResponseWriter, r *http.Request) {
 
 		tmplData.FieldErrors = form.FieldErrors
 
+		// showing the reason of the refusal (e.g. suspended account)
+		if len(v.NonFieldErrors) > 0 {
+			tmplData.Flash = v.NonFieldErrors[0]
+		}
+
 		form.Password = ""
 		tmplData.Form = form
 
@@ -545,39 +755,43 @@ func (app *application) loginPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// fetching the user id from the API
-	user, err := app.models.UserModel.GetByID(tokens.Authentication.Token, "me", nil, v)
-	if err != nil || !v.Valid() {
-		switch {
-		case errors.Is(err, api.ErrRecordNotFound):
-			app.clientError(r, w, http.StatusNotFound)
-		default:
-			app.serverError(w, r, err)
-		}
+	// asking for the two-factor authentication code
+	if tokens.MFA != nil {
+		app.sessionManager.Put(r.Context(), mfaTokenSessionManager, tokens.MFA.Token)
+		http.Redirect(w, r, "/login/mfa", http.StatusSeeOther)
 		return
 	}
 
-	// storing the user id and tokens in the user session
-	app.sessionManager.Put(r.Context(), authenticatedUserIDSessionManager, user.ID)
-	app.putToken(r, *tokens)
-
-	http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+	app.logIn(w, r, tokens)
 }
 
-func (app *application) forgotPassword(w http.ResponseWriter, r *http.Request) {
+func (app *application) loginMFA(w http.ResponseWriter, r *http.Request) {
+
+	// the password must be checked first
+	if app.getToken(r, mfaTokenSessionManager) == "" {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
 
 	// retrieving basic template data
-	tmplData := app.newTemplateData(r, false, Overlay.ForgotPassword)
-	tmplData.Title = "Threadive - Forgot password"
+	tmplData := app.newTemplateData(r, false, Overlay.MFA)
+	tmplData.Title = "Threadive - Two-factor authentication"
 
 	// render the template
 	app.render(w, r, http.StatusOK, "home.tmpl", tmplData)
 }
 
-func (app *application) forgotPasswordPost(w http.ResponseWriter, r *http.Request) {
+func (app *application) loginMFAPost(w http.ResponseWriter, r *http.Request) {
+
+	// the password must be checked first
+	mfaToken := app.getToken(r, mfaTokenSessionManager)
+	if mfaToken == "" {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
 
 	// retrieving the form data
-	form := newForgotPasswordForm()
+	form := newTOTPForm()
 	err := app.decodePostForm(r, &form)
 	if err != nil {
 		app.clientError(r, w, http.StatusBadRequest)
@@ -585,27 +799,36 @@ func (app *application) forgotPasswordPost(w http.ResponseWriter, r *http.Reques
 	}
 
 	// checking the data from the user
-	form.ValidateEmail(form.Email)
+	form.Check(validator.NotBlank(form.Code), "code", "This field cannot be blank")
 
-	// return to forgot-password page if there is an error
+	// return to the code prompt if there is an error
 	if !form.Valid() {
 
 		// retrieving basic template data
-		tmplData := app.newTemplateData(r, false, Overlay.ForgotPassword)
+		tmplData := app.newTemplateData(r, false, Overlay.MFA)
 
-		tmplData.NonFieldErrors = form.NonFieldErrors
 		tmplData.FieldErrors = form.FieldErrors
 
-		tmplData.Form = form
-
 		// render the template
-		app.render(w, r, http.StatusOK, "home.tmpl", tmplData)
+		app.render(w, r, http.StatusUnprocessableEntity, "home.tmpl", tmplData)
 		return
 	}
 
-	// API request to send a reset password token
+	// building the API request body (with the user's device to name the session)
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	body := map[string]string{
+		"token":      mfaToken,
+		"code":       form.Code,
+		"user_agent": r.UserAgent(),
+		"ip":         ip,
+	}
+
+	// API request to authenticate the user
 	v := validator.New()
-	err = app.models.UserModel.ForgotPassword(form.Email, v)
+	tokens, err := app.models.TokenModel.AuthenticateMFA(body, v)
 	if err != nil {
 		switch {
 		case errors.Is(err, api.ErrRecordNotFound):
@@ -619,78 +842,72 @@ func (app *application) forgotPasswordPost(w http.ResponseWriter, r *http.Reques
 	// looking for errors from the API
 	if !v.Valid() {
 
+		// the password must be typed again once the two-factor token expired
+		if _, ok := v.FieldErrors["token"]; ok {
+			app.sessionManager.Remove(r.Context(), mfaTokenSessionManager)
+			app.sessionManager.Put(r.Context(), "flash", "Your login expired, please log in again.")
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+
 		// retrieving basic template data
-		tmplData := app.newTemplateData(r, false, Overlay.ForgotPassword)
+		tmplData := app.newTemplateData(r, false, Overlay.MFA)
 
-		tmplData.NonFieldErrors = form.NonFieldErrors
-		tmplData.FieldErrors = form.FieldErrors
+		tmplData.FieldErrors = v.FieldErrors
 
-		tmplData.Form = form
+		// showing the reason of the refusal (e.g. suspended account)
+		if len(v.NonFieldErrors) > 0 {
+			tmplData.Flash = v.NonFieldErrors[0]
+		}
 
 		// render the template
-		app.render(w, r, http.StatusOK, "home.tmpl", tmplData)
+		app.render(w, r, http.StatusUnprocessableEntity, "home.tmpl", tmplData)
 		return
 	}
 
-	app.sessionManager.Put(r.Context(), "flash", "We've sent you a mail to reset your password!")
-	http.Redirect(w, r, "/login", http.StatusSeeOther)
-}
-
-func (app *application) resetPassword(w http.ResponseWriter, r *http.Request) {
-
-	// retrieving basic template data
-	tmplData := app.newTemplateData(r, false, Overlay.ResetPassword)
-	tmplData.Title = "Threadive - Reset password"
+	app.sessionManager.Remove(r.Context(), mfaTokenSessionManager)
 
-	// retrieving the reset token from the URL
-	tmplData.ResetToken = flow.Param(r.Context(), "token")
-	if tmplData.ResetToken == "" {
-		app.clientError(r, w, http.StatusBadRequest)
+	// renewing the user session
+	err = app.sessionManager.RenewToken(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
 		return
 	}
 
-	// render the template
-	app.render(w, r, http.StatusOK, "home.tmpl", tmplData)
+	app.logIn(w, r, tokens)
 }
 
-func (app *application) resetPasswordPost(w http.ResponseWriter, r *http.Request) {
+// loginProvider redirects the user to the identity provider, keeping the state, the nonce and the PKCE code verifier for the callback
+func (app *application) loginProvider(w http.ResponseWriter, r *http.Request) {
 
-	// retrieving the form data
-	form := newResetPasswordForm()
-	err := app.decodePostForm(r, &form)
+	// generating the secrets of the authorization request
+	state, err := newNonce()
 	if err != nil {
-		app.clientError(r, w, http.StatusBadRequest)
+		app.serverError(w, r, err)
 		return
 	}
-
-	// checking the data from the user
-	form.ValidateNewPassword(form.NewPassword, form.ConfirmPassword)
-	form.ValidateToken(form.Token)
-
-	// return to reset-password page if there is an error
-	if !form.Valid() {
-
-		// retrieving basic template data
-		tmplData := app.newTemplateData(r, false, Overlay.ResetPassword)
-
-		tmplData.NonFieldErrors = form.NonFieldErrors
-		tmplData.FieldErrors = form.FieldErrors
-
-		// render the template
-		app.render(w, r, http.StatusOK, "home.tmpl", tmplData)
+	nonce, err := newNonce()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	verifier, challenge, err := newPKCE()
+	if err != nil {
+		app.serverError(w, r, err)
 		return
 	}
 
-	// building the API request body
-	body := map[string]string{
-		"token":            form.Token,
-		"new_password":     form.NewPassword,
-		"confirm_password": form.ConfirmPassword,
+	// API request to build the authorization URL of the provider
+	provider := flow.Param(r.Context(), "provider")
+	query := url.Values{
+		"redirect_uri":   {providerRedirectURI(r, provider)},
+		"state":          {state},
+		"code_challenge": {challenge},
+		"nonce":          {nonce},
 	}
 
-	// API request to send a reset password token
 	v := validator.New()
-	err = app.models.UserModel.ResetPassword(body, v)
+	authURL, err := app.models.OIDCModel.GetAuthorizationURL(provider, query, v)
 	if err != nil {
 		switch {
 		case errors.Is(err, api.ErrRecordNotFound):
@@ -703,41 +920,62 @@ func (app *application) resetPasswordPost(w http.ResponseWriter, r *http.Request
 
 	// looking for errors from the API
 	if !v.Valid() {
-
-		// retrieving basic template data
-		tmplData := app.newTemplateData(r, false, Overlay.ResetPassword)
-
-		tmplData.NonFieldErrors = form.NonFieldErrors
-		tmplData.FieldErrors = form.FieldErrors
-
-		// render the template
-		app.render(w, r, http.StatusOK, "home.tmpl", tmplData)
+		app.sessionManager.Put(r.Context(), "flash", "The login with this provider is unavailable, please try again later.")
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
 		return
 	}
 
-	app.sessionManager.Put(r.Context(), "flash", "Your password has been updated successfully!")
-	http.Redirect(w, r, "/login", http.StatusSeeOther)
+	// keeping the secrets until the callback
+	app.sessionManager.Put(r.Context(), oidcStateSessionManager, state)
+	app.sessionManager.Put(r.Context(), oidcNonceSessionManager, nonce)
+	app.sessionManager.Put(r.Context(), oidcVerifierSessionManager, verifier)
+
+	http.Redirect(w, r, authURL, http.StatusSeeOther)
 }
 
-/* #############################################################################
-/*	RESTRICTED
-/* #############################################################################*/
+// loginProviderCallback logs the user in with the authorization code the identity provider redirected them with
+func (app *application) loginProviderCallback(w http.ResponseWriter, r *http.Request) {
 
-func (app *application) dashboard(w http.ResponseWriter, r *http.Request) {
+	// retrieving (only once) the secrets of the authorization request
+	state := app.sessionManager.PopString(r.Context(), oidcStateSessionManager)
+	nonce := app.sessionManager.PopString(r.Context(), oidcNonceSessionManager)
+	verifier := app.sessionManager.PopString(r.Context(), oidcVerifierSessionManager)
 
-	// retrieving basic template data
-	tmplData := app.newTemplateData(r, true, Overlay.Default)
-	tmplData.Title = "Threadive - Dashboard"
+	// checking the provider's answer belongs to the request of this session
+	qs := r.URL.Query()
+	if state == "" || qs.Get("state") != state {
+		app.sessionManager.Put(r.Context(), "flash", "Your login expired, please try again.")
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
 
-	// render the template
-	app.render(w, r, http.StatusOK, "dashboard.tmpl", tmplData)
-}
+	// the user refused the access or the provider failed
+	code := qs.Get("code")
+	if code == "" {
+		app.sessionManager.Put(r.Context(), "flash", "The login with this provider was cancelled.")
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
 
-func (app *application) logoutPost(w http.ResponseWriter, r *http.Request) {
+	// building the API request body (with the user's device to name the session)
+	provider := flow.Param(r.Context(), "provider")
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	body := map[string]string{
+		"provider":      provider,
+		"code":          code,
+		"code_verifier": verifier,
+		"redirect_uri":  providerRedirectURI(r, provider),
+		"nonce":         nonce,
+		"user_agent":    r.UserAgent(),
+		"ip":            ip,
+	}
 
-	// revoking the user's tokens
+	// API request to authenticate the user
 	v := validator.New()
-	err := app.models.TokenModel.Logout(app.getToken(r, authTokenSessionManager), v)
+	tokens, err := app.models.TokenModel.AuthenticateOIDC(body, v)
 	if err != nil {
 		switch {
 		case errors.Is(err, api.ErrRecordNotFound):
@@ -748,15 +986,280 @@ func (app *application) logoutPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// looking for errors from the API
+	// looking for errors from the API (e.g. an existing account with an unverified email address, or an account to activate)
 	if !v.Valid() {
-		app.logger.Error(fmt.Sprintf("errors: %s", string(v.Errors())))
-		app.clientError(r, w, http.StatusBadRequest)
+		flash := "The login with this provider failed."
+		switch {
+		case len(v.NonFieldErrors) > 0:
+			flash = v.NonFieldErrors[0]
+		case v.FieldErrors["email"] != "":
+			flash = v.FieldErrors["email"]
+		}
+		app.sessionManager.Put(r.Context(), "flash", flash)
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
 		return
 	}
 
-	// logging the user out
-	err = app.logout(r)
+	// renewing the user session
+	err = app.sessionManager.RenewToken(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// asking for the two-factor authentication code
+	if tokens.MFA != nil {
+		app.sessionManager.Put(r.Context(), mfaTokenSessionManager, tokens.MFA.Token)
+		http.Redirect(w, r, "/login/mfa", http.StatusSeeOther)
+		return
+	}
+
+	app.logIn(w, r, tokens)
+}
+
+func (app *application) forgotPassword(w http.ResponseWriter, r *http.Request) {
+
+	// retrieving basic template data
+	tmplData := app.newTemplateData(r, false, Overlay.ForgotPassword)
+	tmplData.Title = "Threadive - Forgot password"
+
+	// render the template
+	app.render(w, r, http.StatusOK, "home.tmpl", tmplData)
+}
+
+func (app *application) forgotPasswordPost(w http.ResponseWriter, r *http.Request) {
+
+	// retrieving the form data
+	form := newForgotPasswordForm()
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(r, w, http.StatusBadRequest)
+		return
+	}
+
+	// checking the data from the user
+	form.ValidateEmail(form.Email)
+
+	// return to forgot-password page if there is an error
+	if !form.Valid() {
+
+		// retrieving basic template data
+		tmplData := app.newTemplateData(r, false, Overlay.ForgotPassword)
+
+		tmplData.NonFieldErrors = form.NonFieldErrors
+		tmplData.FieldErrors = form.FieldErrors
+
+		tmplData.Form = form
+
+		// render the template
+		app.render(w, r, http.StatusOK, "home.tmpl", tmplData)
+		return
+	}
+
+	// API request to send a reset password token
+	v := validator.New()
+	err = app.models.UserModel.ForgotPassword(form.Email, v)
+	if err != nil {
+		switch {
+		case errors.Is(err, api.ErrRecordNotFound):
+			app.clientError(r, w, http.StatusNotFound)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	// looking for errors from the API
+	if !v.Valid() {
+
+		// retrieving basic template data
+		tmplData := app.newTemplateData(r, false, Overlay.ForgotPassword)
+
+		tmplData.NonFieldErrors = form.NonFieldErrors
+		tmplData.FieldErrors = form.FieldErrors
+
+		tmplData.Form = form
+
+		// render the template
+		app.render(w, r, http.StatusOK, "home.tmpl", tmplData)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "We've sent you a mail to reset your password!")
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+func (app *application) resetPassword(w http.ResponseWriter, r *http.Request) {
+
+	// retrieving basic template data
+	tmplData := app.newTemplateData(r, false, Overlay.ResetPassword)
+	tmplData.Title = "Threadive - Reset password"
+
+	// retrieving the reset token from the URL
+	tmplData.ResetToken = flow.Param(r.Context(), "token")
+	if tmplData.ResetToken == "" {
+		app.clientError(r, w, http.StatusBadRequest)
+		return
+	}
+
+	// render the template
+	app.render(w, r, http.StatusOK, "home.tmpl", tmplData)
+}
+
+func (app *application) resetPasswordPost(w http.ResponseWriter, r *http.Request) {
+
+	// retrieving the form data
+	form := newResetPasswordForm()
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(r, w, http.StatusBadRequest)
+		return
+	}
+
+	// checking the data from the user
+	form.ValidateNewPassword(form.NewPassword, form.ConfirmPassword)
+	form.ValidateToken(form.Token)
+
+	// return to reset-password page if there is an error
+	if !form.Valid() {
+
+		// retrieving basic template data
+		tmplData := app.newTemplateData(r, false, Overlay.ResetPassword)
+
+		tmplData.NonFieldErrors = form.NonFieldErrors
+		tmplData.FieldErrors = form.FieldErrors
+
+		// render the template
+		app.render(w, r, http.StatusOK, "home.tmpl", tmplData)
+		return
+	}
+
+	// building the API request body
+	body := map[string]string{
+		"token":            form.Token,
+		"new_password":     form.NewPassword,
+		"confirm_password": form.ConfirmPassword,
+	}
+
+	// API request to send a reset password token
+	v := validator.New()
+	err = app.models.UserModel.ResetPassword(body, v)
+	if err != nil {
+		switch {
+		case errors.Is(err, api.ErrRecordNotFound):
+			app.clientError(r, w, http.StatusNotFound)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	// looking for errors from the API
+	if !v.Valid() {
+
+		// retrieving basic template data
+		tmplData := app.newTemplateData(r, false, Overlay.ResetPassword)
+
+		tmplData.NonFieldErrors = form.NonFieldErrors
+		tmplData.FieldErrors = form.FieldErrors
+
+		// render the template
+		app.render(w, r, http.StatusOK, "home.tmpl", tmplData)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Your password has been updated successfully!")
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+/* #############################################################################
+/*	RESTRICTED
+/* #############################################################################*/
+
+func (app *application) dashboard(w http.ResponseWriter, r *http.Request) {
+
+	// retrieving basic template data
+	tmplData := app.newTemplateData(r, true, Overlay.Default)
+	tmplData.Title = "Threadive - Dashboard"
+
+	// fetching the privacy settings
+	v := validator.New()
+	privacy, err := app.models.UserModel.GetPrivacy(app.getToken(r, authTokenSessionManager), v)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	tmplData.Privacy = privacy
+
+	// fetching the devices on which the user is logged in
+	tmplData.Sessions, err = app.models.UserModel.GetSessions(app.getToken(r, authTokenSessionManager), v)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// render the template
+	app.render(w, r, http.StatusOK, "dashboard.tmpl", tmplData)
+}
+
+func (app *application) userGet(w http.ResponseWriter, r *http.Request) {
+
+	// retrieving basic template data (with the user's friends)
+	tmplData := app.newTemplateData(r, true, Overlay.Default)
+
+	// fetching the user id in the path
+	id, err := getPathID(r)
+	if err != nil {
+		app.clientError(r, w, http.StatusBadRequest)
+		return
+	}
+
+	// fetching the user with the public data of the profile
+	query := url.Values{
+		"includes[]": {"posts", "threads_owned", "tags_owned", "following_tags", "friends"},
+	}
+	v := validator.New()
+	tmplData.Profile, err = app.models.UserModel.GetByID(app.getToken(r, authTokenSessionManager), strconv.Itoa(id), query, v)
+	if err != nil {
+		switch {
+		case errors.Is(err, api.ErrRecordNotFound):
+			app.clientError(r, w, http.StatusNotFound)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	// checking API request errors
+	if !v.Valid() {
+		app.clientError(r, w, http.StatusBadRequest)
+		return
+	}
+
+	// setting the page's title
+	tmplData.Title = fmt.Sprintf("Threadive - %s", tmplData.Profile.Name)
+
+	// render the template
+	app.render(w, r, http.StatusOK, "user.tmpl", tmplData)
+}
+
+func (app *application) logoutPost(w http.ResponseWriter, r *http.Request) {
+
+	// revoking the session of the user's tokens (already gone if it was revoked from another device)
+	v := validator.New()
+	err := app.models.TokenModel.Logout(app.getToken(r, authTokenSessionManager), v)
+	if err != nil && !errors.Is(err, api.ErrRecordNotFound) {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// the user is logged out of this browser anyway
+	if !v.Valid() {
+		app.logger.Error(fmt.Sprintf("errors: %s", string(v.Errors())))
+	}
+
+	// logging the user out
+	err = app.logout(r)
 	if err != nil {
 
 		// DEBUG
@@ -766,21 +1269,264 @@ func (app *application) logoutPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	app.sessionManager.Put(r.Context(), "flash", "You've been logged out successfully!")
+	app.sessionManager.Put(r.Context(), "flash", "You've been logged out successfully!")
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+func (app *application) logoutEverywherePost(w http.ResponseWriter, r *http.Request) {
+
+	// revoking all the user's tokens
+	v := validator.New()
+	err := app.models.TokenModel.LogoutEverywhere(app.getToken(r, authTokenSessionManager), v)
+	if err != nil {
+		switch {
+		case errors.Is(err, api.ErrRecordNotFound):
+			app.clientError(r, w, http.StatusNotFound)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	// looking for errors from the API
+	if !v.Valid() {
+		app.logger.Error(fmt.Sprintf("errors: %s", string(v.Errors())))
+		app.clientError(r, w, http.StatusBadRequest)
+		return
+	}
+
+	// logging the user out
+	err = app.logout(r)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "You've been logged out of all your devices!")
 
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
 
+func (app *application) deleteSession(w http.ResponseWriter, r *http.Request) {
+
+	// getting the id from the path
+	id, err := getPathID(r)
+	if err != nil {
+		app.clientError(r, w, http.StatusBadRequest)
+		return
+	}
+
+	// sending the request to the API
+	v := validator.New()
+	err = app.models.UserModel.DeleteSession(app.getToken(r, authTokenSessionManager), id, v)
+	if err != nil {
+		switch {
+		case errors.Is(err, api.ErrRecordNotFound):
+			app.clientError(r, w, http.StatusNotFound)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	// setting the header for json response
+	w.Header().Set("Content-Type", "application/json")
+
+	// looking for errors from the API
+	if !v.Valid() {
+
+		// sending the errors
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, err = w.Write(v.Errors())
+		if err != nil {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	// setting the response
+	message := map[string]string{
+		"message": fmt.Sprintf("session %d successfully revoked", id),
+	}
+	response, err := json.Marshal(message)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	_, err = w.Write(response)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
 func (app *application) updateUser(w http.ResponseWriter, r *http.Request) {
 
 	// retrieving basic template data
 	tmplData := app.newTemplateData(r, false, Overlay.Default)
 	tmplData.Title = "Threadive - Update user"
 
+	// fetching the state of the two-factor authentication
+	v := validator.New()
+	totp, err := app.models.UserModel.GetTOTP(app.getToken(r, authTokenSessionManager), v)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if totp == nil {
+		totp = &data.TOTP{}
+	}
+	tmplData.TOTP = totp
+
 	// render the template
 	app.render(w, r, http.StatusOK, "user-update.tmpl", tmplData)
 }
 
+func (app *application) enrollTOTP(w http.ResponseWriter, r *http.Request) {
+
+	// sending the request to the API
+	v := validator.New()
+	enrolment, err := app.models.UserModel.EnrollTOTP(app.getToken(r, authTokenSessionManager), v)
+	if err != nil {
+		switch {
+		case errors.Is(err, api.ErrRecordNotFound):
+			app.clientError(r, w, http.StatusNotFound)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	// setting the header for json response
+	w.Header().Set("Content-Type", "application/json")
+
+	// looking for errors from the API
+	if !v.Valid() {
+
+		// sending the errors
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, err = w.Write(v.Errors())
+		if err != nil {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	// sending the secret to show its QR code
+	app.writeJSON(w, r, http.StatusOK, enrolment)
+}
+
+func (app *application) confirmTOTP(w http.ResponseWriter, r *http.Request) {
+	app.sendTOTPCode(w, r, app.models.UserModel.ConfirmTOTP)
+}
+
+func (app *application) regenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	app.sendTOTPCode(w, r, app.models.UserModel.RegenerateRecoveryCodes)
+}
+
+// sendTOTPCode sends the code of the form to the API with send and answers with the recovery codes
+// (both to confirm the enrolment and to replace the codes)
+func (app *application) sendTOTPCode(w http.ResponseWriter, r *http.Request, send func(token, code string, v *validator.Validator) ([]string, error)) {
+
+	// getting the code from the form
+	form := newTOTPForm()
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(r, w, http.StatusBadRequest)
+		return
+	}
+
+	// checking the code
+	form.Check(validator.NotBlank(form.Code), "code", "This field cannot be blank")
+
+	// setting the header for json response
+	w.Header().Set("Content-Type", "application/json")
+
+	// looking for possible errors
+	if !form.Valid() {
+
+		// sending the errors
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, err = w.Write(form.Errors())
+		if err != nil {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	// sending the request to the API
+	v := validator.New()
+	codes, err := send(app.getToken(r, authTokenSessionManager), form.Code, v)
+	if err != nil {
+		switch {
+		case errors.Is(err, api.ErrRecordNotFound):
+			app.clientError(r, w, http.StatusNotFound)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	// looking for errors from the API
+	if !v.Valid() {
+
+		// sending the errors
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, err = w.Write(v.Errors())
+		if err != nil {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	// sending the recovery codes to show them once
+	app.writeJSON(w, r, http.StatusOK, map[string][]string{"recovery_codes": codes})
+}
+
+func (app *application) disableTOTPPost(w http.ResponseWriter, r *http.Request) {
+
+	// retrieving the form data
+	form := newTOTPForm()
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(r, w, http.StatusBadRequest)
+		return
+	}
+
+	// checking the code
+	form.Check(validator.NotBlank(form.Code), "code", "This field cannot be blank")
+	if !form.Valid() {
+		app.sessionManager.Put(r.Context(), "flash", "Please type a code of your app or a recovery code to disable the two-factor authentication.")
+		http.Redirect(w, r, "/user", http.StatusSeeOther)
+		return
+	}
+
+	// sending the request to the API
+	v := validator.New()
+	err = app.models.UserModel.DisableTOTP(app.getToken(r, authTokenSessionManager), form.Code, v)
+	if err != nil {
+		switch {
+		case errors.Is(err, api.ErrRecordNotFound):
+			app.clientError(r, w, http.StatusNotFound)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	// looking for errors from the API
+	if !v.Valid() {
+		app.sessionManager.Put(r.Context(), "flash", "Invalid code, the two-factor authentication is still enabled.")
+		http.Redirect(w, r, "/user", http.StatusSeeOther)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Two-factor authentication disabled.")
+
+	http.Redirect(w, r, "/user", http.StatusSeeOther)
+}
+
 func (app *application) updateUserPut(w http.ResponseWriter, r *http.Request) {
 
 	// retrieving the form data
@@ -842,14 +1588,118 @@ func (app *application) updateUserPut(w http.ResponseWriter, r *http.Request) {
 		tmplData.NonFieldErrors = form.NonFieldErrors
 		tmplData.FieldErrors = form.FieldErrors
 
-		// render the template
-		app.render(w, r, http.StatusOK, "user-update.tmpl", tmplData)
+		// render the template
+		app.render(w, r, http.StatusOK, "user-update.tmpl", tmplData)
+		return
+	}
+
+	// API request to send a reset password token
+	v := validator.New()
+	err = app.models.UserModel.Update(app.getToken(r, authTokenSessionManager), *form.Password, user, v)
+	if err != nil {
+		switch {
+		case errors.Is(err, api.ErrRecordNotFound):
+			app.clientError(r, w, http.StatusNotFound)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	// looking for errors from the API
+	if !v.Valid() {
+
+		// retrieving basic template data
+		tmplData := app.newTemplateData(r, false, Overlay.Default)
+
+		tmplData.NonFieldErrors = form.NonFieldErrors
+		tmplData.FieldErrors = form.FieldErrors
+
+		// render the template
+		app.render(w, r, http.StatusOK, "user-update.tmpl", tmplData)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Your data has been updated successfully!")
+	http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+}
+
+func (app *application) updatePrivacyPost(w http.ResponseWriter, r *http.Request) {
+
+	// retrieving the form data
+	form := newPrivacyForm()
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(r, w, http.StatusBadRequest)
+		return
+	}
+
+	// checking the data from the user
+	for _, value := range []string{form.Email, form.BirthDate, form.Friends, form.FavoriteThreads, form.FollowingTags, form.Reactions} {
+		form.Check(validator.PermittedValue(value, form.Visibilities...), "privacy", "must be public, friends or private")
+	}
+
+	if !form.Valid() {
+		app.clientError(r, w, http.StatusBadRequest)
+		return
+	}
+
+	// creating the body of the request
+	body, err := json.Marshal(form)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// API request to update the privacy settings
+	v := validator.New()
+	err = app.models.UserModel.UpdatePrivacy(app.getToken(r, authTokenSessionManager), body, v)
+	if err != nil {
+		switch {
+		case errors.Is(err, api.ErrRecordNotFound):
+			app.clientError(r, w, http.StatusNotFound)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	// looking for errors from the API
+	if !v.Valid() {
+		app.clientError(r, w, http.StatusBadRequest)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Your privacy settings have been updated successfully!")
+	http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+}
+
+// maxAvatarUploadSize leaves some room to the multipart encoding around the 5MB accepted by the API
+const maxAvatarUploadSize = 6 << 20
+
+func (app *application) updateAvatarPost(w http.ResponseWriter, r *http.Request) {
+
+	// limiting the size of the request's body (the API checks the size of the file itself)
+	r.Body = http.MaxBytesReader(w, r.Body, maxAvatarUploadSize)
+
+	// retrieving the uploaded file
+	file, header, err := r.FormFile("avatar")
+	if err != nil {
+		app.sessionManager.Put(r.Context(), "flash", "Please choose an image of less than 5MB for your avatar.")
+		http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+		return
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		app.serverError(w, r, err)
 		return
 	}
 
-	// API request to send a reset password token
+	// API request to upload the avatar
 	v := validator.New()
-	err = app.models.UserModel.Update(app.getToken(r, authTokenSessionManager), *form.Password, user, v)
+	_, err = app.models.UserModel.UpdateAvatar(app.getToken(r, authTokenSessionManager), header.Filename, content, v)
 	if err != nil {
 		switch {
 		case errors.Is(err, api.ErrRecordNotFound):
@@ -862,19 +1712,16 @@ func (app *application) updateUserPut(w http.ResponseWriter, r *http.Request) {
 
 	// looking for errors from the API
 	if !v.Valid() {
-
-		// retrieving basic template data
-		tmplData := app.newTemplateData(r, false, Overlay.Default)
-
-		tmplData.NonFieldErrors = form.NonFieldErrors
-		tmplData.FieldErrors = form.FieldErrors
-
-		// render the template
-		app.render(w, r, http.StatusOK, "user-update.tmpl", tmplData)
+		message := "Your avatar could not be updated"
+		if v.FieldErrors["avatar"] != "" {
+			message = fmt.Sprintf("%s: it %s.", message, v.FieldErrors["avatar"])
+		}
+		app.sessionManager.Put(r.Context(), "flash", message)
+		http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
 		return
 	}
 
-	app.sessionManager.Put(r.Context(), "flash", "Your data has been updated successfully!")
+	app.sessionManager.Put(r.Context(), "flash", "Your avatar has been updated successfully!")
 	http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
 }
 
@@ -1080,9 +1927,8 @@ func (app *application) createPostPost(w http.ResponseWriter, r *http.Request) {
 	if form.Content == nil {
 		form.AddFieldError("content", "must be provided")
 	} else {
-		form.StringCheck(*form.Content, 2, 1_020, true, "content")
-		content := strings.ReplaceAll(*form.Content, "\n", "<br>")
-		post.Content = template.HTML(content)
+		form.StringCheck(*form.Content, 2, 10_000, true, "content")
+		post.Content = *form.Content
 	}
 	if form.ThreadID == nil {
 		form.AddFieldError("thread_id", "must be provided")
@@ -1503,49 +2349,293 @@ func (app *application) updateThreadPut(w http.ResponseWriter, r *http.Request)
 func (app *application) updatePostPut(w http.ResponseWriter, r *http.Request) {
 
 	// retrieving the form data
-	form := newPostForm()
-	err := app.decodePostForm(r, &form)
+	form := newPostForm()
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(r, w, http.StatusBadRequest)
+		return
+	}
+
+	// creating the updated post
+	post := &data.Post{}
+
+	// checking the data from the user
+	if form.Content != nil {
+		form.StringCheck(*form.Content, 1, 10_000, false, "content")
+		post.Content = *form.Content
+	}
+	if form.ParentPostID != nil {
+		form.CheckID(*form.ParentPostID, "parent_post_id")
+		post.IDParentPost = *form.ParentPostID
+	}
+
+	// return to post-update page if there is an error
+	if !form.Valid() {
+		tmplData := app.newTemplateData(r, false, Overlay.Default)
+		tmplData.Form = form
+
+		tmplData.NonFieldErrors = form.NonFieldErrors
+		tmplData.FieldErrors = form.FieldErrors
+
+		// render the template
+		app.render(w, r, http.StatusUnprocessableEntity, "post-update.tmpl", tmplData)
+		return
+	}
+
+	// retrieving the post id from the path
+	post.ID, err = getPathID(r)
+	if err != nil {
+		app.clientError(r, w, http.StatusBadRequest)
+		return
+	}
+
+	// API request to update a post
+	v := validator.New()
+	err = app.models.PostModel.Update(app.getToken(r, authTokenSessionManager), post, v)
+	if err != nil {
+		switch {
+		case errors.Is(err, api.ErrRecordNotFound):
+			app.clientError(r, w, http.StatusNotFound)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	// looking for errors from the API
+	if !v.Valid() {
+
+		// retrieving basic template data
+		tmplData := app.newTemplateData(r, false, Overlay.Default)
+
+		tmplData.NonFieldErrors = form.NonFieldErrors
+		tmplData.FieldErrors = form.FieldErrors
+
+		// render the template
+		app.render(w, r, http.StatusOK, "post-update.tmpl", tmplData)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Post updated successfully!")
+	http.Redirect(w, r, fmt.Sprintf("/thread/%d", post.Thread.ID), http.StatusSeeOther)
+}
+
+func (app *application) updateTagPut(w http.ResponseWriter, r *http.Request) {
+
+	// retrieving the form data
+	form := newTagForm()
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(r, w, http.StatusBadRequest)
+		return
+	}
+
+	// checking the data from the user
+	if form.Name != nil {
+		form.StringCheck(*form.Name, 2, 70, false, "name")
+	}
+	if form.AddThreads != nil {
+		form.Check(validator.Unique(form.AddThreads), "threads_ids", "must be unique")
+	}
+	if form.AddThreads != nil {
+		form.Check(validator.Unique(form.AddThreads), "threads_ids", "must be unique")
+	}
+
+	// return to tag-update page if there is an error
+	if !form.Valid() {
+		tmplData := app.newTemplateData(r, false, Overlay.Default)
+		tmplData.Form = form
+
+		tmplData.NonFieldErrors = form.NonFieldErrors
+		tmplData.FieldErrors = form.FieldErrors
+
+		// render the template
+		app.render(w, r, http.StatusUnprocessableEntity, "tag-update.tmpl", tmplData)
+		return
+	}
+
+	// creating the API request body
+	body, err := json.Marshal(form)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// retrieving the tag id from the path
+	id, err := getPathID(r)
+	if err != nil {
+		app.clientError(r, w, http.StatusBadRequest)
+		return
+	}
+
+	// API request to update a tag
+	v := validator.New()
+	tag, err := app.models.TagModel.Update(app.getToken(r, authTokenSessionManager), id, body, v)
+	if err != nil {
+		switch {
+		case errors.Is(err, api.ErrRecordNotFound):
+			app.clientError(r, w, http.StatusNotFound)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	// looking for errors from the API
+	if !v.Valid() {
+
+		// retrieving basic template data
+		tmplData := app.newTemplateData(r, false, Overlay.Default)
+
+		tmplData.NonFieldErrors = form.NonFieldErrors
+		tmplData.FieldErrors = form.FieldErrors
+
+		// render the template
+		app.render(w, r, http.StatusOK, "tag-update.tmpl", tmplData)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("Tag %s updated successfully!", tag.Name))
+	http.Redirect(w, r, fmt.Sprintf("/tag/%d", tag.ID), http.StatusSeeOther)
+}
+
+func (app *application) inbox(w http.ResponseWriter, r *http.Request) {
+
+	// retrieving basic template data
+	tmplData := app.newTemplateData(r, false, Overlay.Default)
+	tmplData.Title = "Threadive - Inbox"
+
+	// fetching the conversations
+	v := validator.New()
+	var err error
+	tmplData.Inbox.List, tmplData.Inbox.Metadata, err = app.models.MessageModel.GetConversations(app.getToken(r, authTokenSessionManager), r.URL.Query(), v)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// checking API request errors
+	if !v.Valid() {
+		app.clientError(r, w, http.StatusBadRequest)
+		return
+	}
+
+	// render the template
+	app.render(w, r, http.StatusOK, "inbox.tmpl", tmplData)
+}
+
+func (app *application) inboxPost(w http.ResponseWriter, r *http.Request) {
+
+	// retrieving the form data
+	form := newConversationForm()
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(r, w, http.StatusBadRequest)
+		return
+	}
+
+	// checking the data from the user
+	if form.UserID == nil {
+		app.clientError(r, w, http.StatusBadRequest)
+		return
+	}
+	form.CheckID(*form.UserID, "user_id")
+	if !form.Valid() {
+		app.clientError(r, w, http.StatusBadRequest)
+		return
+	}
+
+	// API request to get or create the conversation
+	v := validator.New()
+	conversation, err := app.models.MessageModel.CreateConversation(app.getToken(r, authTokenSessionManager), *form.UserID, v)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// looking for errors from the API (not friends, blocked user...)
+	if !v.Valid() {
+		app.sessionManager.Put(r.Context(), "flash", "You can't send messages to this user.")
+		http.Redirect(w, r, "/inbox", http.StatusSeeOther)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/inbox/%d", conversation.ID), http.StatusSeeOther)
+}
+
+func (app *application) conversationGet(w http.ResponseWriter, r *http.Request) {
+
+	// retrieving basic template data
+	tmplData := app.newTemplateData(r, false, Overlay.Default)
+
+	// fetching the conversation id in the path
+	id, err := getPathID(r)
+	if err != nil {
+		app.clientError(r, w, http.StatusBadRequest)
+		return
+	}
+
+	// fetching the conversation and its messages (older messages with ?before=<message id>)
+	v := validator.New()
+	tmplData.Conversation, tmplData.Messages.List, tmplData.Messages.NextCursor, err = app.models.MessageModel.GetMessages(app.getToken(r, authTokenSessionManager), id, r.URL.Query(), v)
+	if err != nil {
+		switch {
+		case errors.Is(err, api.ErrRecordNotFound):
+			app.clientError(r, w, http.StatusNotFound)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	// checking API request errors
+	if !v.Valid() {
+		app.clientError(r, w, http.StatusBadRequest)
+		return
+	}
+
+	// displaying the messages in chronological order
+	slices.Reverse(tmplData.Messages.List)
+
+	// setting the page's title
+	tmplData.Title = fmt.Sprintf("Threadive - %s", tmplData.Conversation.With.Name)
+
+	// render the template
+	app.render(w, r, http.StatusOK, "conversation.tmpl", tmplData)
+}
+
+func (app *application) conversationPost(w http.ResponseWriter, r *http.Request) {
+
+	// fetching the conversation id in the path
+	id, err := getPathID(r)
+	if err != nil {
+		app.clientError(r, w, http.StatusBadRequest)
+		return
+	}
+
+	// retrieving the form data
+	form := newMessageForm()
+	err = app.decodePostForm(r, &form)
 	if err != nil {
 		app.clientError(r, w, http.StatusBadRequest)
 		return
 	}
 
-	// creating the updated post
-	post := &data.Post{}
-
 	// checking the data from the user
-	if form.Content != nil {
-		form.StringCheck(*form.Content, 1, 1_020, false, "content")
-		post.Content = template.HTML(*form.Content)
-	}
-	if form.ParentPostID != nil {
-		form.CheckID(*form.ParentPostID, "parent_post_id")
-		post.IDParentPost = *form.ParentPostID
+	if form.Content == nil {
+		form.AddFieldError("content", "must be provided")
+	} else {
+		form.StringCheck(*form.Content, 1, 1_020, true, "content")
 	}
-
-	// return to post-update page if there is an error
 	if !form.Valid() {
-		tmplData := app.newTemplateData(r, false, Overlay.Default)
-		tmplData.Form = form
-
-		tmplData.NonFieldErrors = form.NonFieldErrors
-		tmplData.FieldErrors = form.FieldErrors
-
-		// render the template
-		app.render(w, r, http.StatusUnprocessableEntity, "post-update.tmpl", tmplData)
-		return
-	}
-
-	// retrieving the post id from the path
-	post.ID, err = getPathID(r)
-	if err != nil {
-		app.clientError(r, w, http.StatusBadRequest)
+		app.sessionManager.Put(r.Context(), "flash", "Your message must contain between 1 and 1020 characters.")
+		http.Redirect(w, r, fmt.Sprintf("/inbox/%d", id), http.StatusSeeOther)
 		return
 	}
 
-	// API request to update a post
+	// API request to send the message
 	v := validator.New()
-	err = app.models.PostModel.Update(app.getToken(r, authTokenSessionManager), post, v)
+	_, err = app.models.MessageModel.Send(app.getToken(r, authTokenSessionManager), id, *form.Content, v)
 	if err != nil {
 		switch {
 		case errors.Is(err, api.ErrRecordNotFound):
@@ -1556,107 +2646,87 @@ func (app *application) updatePostPut(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// looking for errors from the API
+	// looking for errors from the API (no longer friends, blocked user...)
 	if !v.Valid() {
-
-		// retrieving basic template data
-		tmplData := app.newTemplateData(r, false, Overlay.Default)
-
-		tmplData.NonFieldErrors = form.NonFieldErrors
-		tmplData.FieldErrors = form.FieldErrors
-
-		// render the template
-		app.render(w, r, http.StatusOK, "post-update.tmpl", tmplData)
-		return
+		app.sessionManager.Put(r.Context(), "flash", "Your message couldn't be sent.")
 	}
 
-	app.sessionManager.Put(r.Context(), "flash", "Post updated successfully!")
-	http.Redirect(w, r, fmt.Sprintf("/thread/%d", post.Thread.ID), http.StatusSeeOther)
+	http.Redirect(w, r, fmt.Sprintf("/inbox/%d", id), http.StatusSeeOther)
 }
 
-func (app *application) updateTagPut(w http.ResponseWriter, r *http.Request) {
+/* #############################################################################
+/*	AJAX HANDLERS
+/* #############################################################################*/
 
-	// retrieving the form data
-	form := newTagForm()
+func (app *application) previewPost(w http.ResponseWriter, r *http.Request) {
+
+	// getting the content from the form
+	form := newPostForm()
 	err := app.decodePostForm(r, &form)
 	if err != nil {
+
+		// DEBUG
+		app.logger.Debug(fmt.Sprintf("error decoding the form: %s", err))
+
 		app.clientError(r, w, http.StatusBadRequest)
 		return
 	}
 
-	// checking the data from the user
-	if form.Name != nil {
-		form.StringCheck(*form.Name, 2, 70, false, "name")
-	}
-	if form.AddThreads != nil {
-		form.Check(validator.Unique(form.AddThreads), "threads_ids", "must be unique")
-	}
-	if form.AddThreads != nil {
-		form.Check(validator.Unique(form.AddThreads), "threads_ids", "must be unique")
+	// setting the header for json response
+	w.Header().Set("Content-Type", "application/json")
+
+	// checking the values
+	if form.Content == nil {
+		form.AddFieldError("content", "must be provided")
+	} else {
+		form.StringCheck(*form.Content, 1, 10_000, true, "content")
 	}
 
-	// return to tag-update page if there is an error
+	// looking for possible errors
 	if !form.Valid() {
-		tmplData := app.newTemplateData(r, false, Overlay.Default)
-		tmplData.Form = form
-
-		tmplData.NonFieldErrors = form.NonFieldErrors
-		tmplData.FieldErrors = form.FieldErrors
 
-		// render the template
-		app.render(w, r, http.StatusUnprocessableEntity, "tag-update.tmpl", tmplData)
+		// sending the errors
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, err = w.Write(form.Errors())
+		if err != nil {
+			app.serverError(w, r, err)
+		}
 		return
 	}
 
-	// creating the API request body
-	body, err := json.Marshal(form)
+	// sending the request to the API
+	v := validator.New()
+	contentHTML, err := app.models.PostModel.Preview(app.getToken(r, authTokenSessionManager), *form.Content, v)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
 
-	// retrieving the tag id from the path
-	id, err := getPathID(r)
-	if err != nil {
-		app.clientError(r, w, http.StatusBadRequest)
-		return
-	}
+	// looking for errors from the API
+	if !v.Valid() {
 
-	// API request to update a tag
-	v := validator.New()
-	tag, err := app.models.TagModel.Update(app.getToken(r, authTokenSessionManager), id, body, v)
-	if err != nil {
-		switch {
-		case errors.Is(err, api.ErrRecordNotFound):
-			app.clientError(r, w, http.StatusNotFound)
-		default:
+		// sending the errors
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, err = w.Write(v.Errors())
+		if err != nil {
 			app.serverError(w, r, err)
 		}
 		return
 	}
 
-	// looking for errors from the API
-	if !v.Valid() {
-
-		// retrieving basic template data
-		tmplData := app.newTemplateData(r, false, Overlay.Default)
-
-		tmplData.NonFieldErrors = form.NonFieldErrors
-		tmplData.FieldErrors = form.FieldErrors
-
-		// render the template
-		app.render(w, r, http.StatusOK, "tag-update.tmpl", tmplData)
+	// setting the response
+	response, err := json.Marshal(map[string]string{"content_html": string(contentHTML)})
+	if err != nil {
+		app.serverError(w, r, err)
 		return
 	}
 
-	app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("Tag %s updated successfully!", tag.Name))
-	http.Redirect(w, r, fmt.Sprintf("/tag/%d", tag.ID), http.StatusSeeOther)
+	_, err = w.Write(response)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
 }
 
-/* #############################################################################
-/*	AJAX HANDLERS
-/* #############################################################################*/
-
 func (app *application) reactToPost(w http.ResponseWriter, r *http.Request) {
 
 	// getting the id from the path
@@ -2043,6 +3113,82 @@ func (app *application) addToFavoritesThread(w http.ResponseWriter, r *http.Requ
 	}
 }
 
+func (app *application) updateSubscription(w http.ResponseWriter, r *http.Request) {
+
+	// getting the id from the path
+	id, err := getPathID(r)
+	if err != nil {
+		app.clientError(r, w, http.StatusBadRequest)
+		return
+	}
+
+	// getting the level from the form
+	form := newSubscriptionForm()
+	err = app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(r, w, http.StatusBadRequest)
+		return
+	}
+
+	// checking the values
+	form.Check(validator.PermittedValue(form.Level, form.Levels...), "level", "must be a permitted value")
+
+	// setting the header for json response
+	w.Header().Set("Content-Type", "application/json")
+
+	// looking for possible errors
+	if !form.Valid() {
+
+		// sending the errors
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, err = w.Write(form.Errors())
+		if err != nil {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	// sending the request to the API
+	v := validator.New()
+	err = app.models.ThreadModel.UpdateSubscription(app.getToken(r, authTokenSessionManager), id, form.Level, v)
+	if err != nil {
+		switch {
+		case errors.Is(err, api.ErrRecordNotFound):
+			app.clientError(r, w, http.StatusNotFound)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	// looking for errors from the API
+	if !v.Valid() {
+
+		// sending the errors
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, err = w.Write(v.Errors())
+		if err != nil {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	// setting the response
+	message := map[string]string{
+		"message": fmt.Sprintf("subscription to thread %d successfully set to %s", id, form.Level),
+	}
+	response, err := json.Marshal(message)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	_, err = w.Write(response)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
 func (app *application) removeFromFavoritesThread(w http.ResponseWriter, r *http.Request) {
 
 	// getting the id from the path
@@ -2130,11 +3276,12 @@ func (app *application) friendRequest(w http.ResponseWriter, r *http.Request) {
 	if !v.Valid() {
 
 		// sending the errors
+		w.WriteHeader(http.StatusUnprocessableEntity)
 		_, err = w.Write(v.Errors())
 		if err != nil {
 			app.serverError(w, r, err)
-			return
 		}
+		return
 	}
 
 	// setting the response
@@ -2178,11 +3325,12 @@ func (app *application) friendResponse(w http.ResponseWriter, r *http.Request) {
 	if !form.Valid() {
 
 		// sending the errors
+		w.WriteHeader(http.StatusUnprocessableEntity)
 		_, err = w.Write(form.Errors())
 		if err != nil {
 			app.serverError(w, r, err)
-			return
 		}
+		return
 	}
 
 	// DEBUG
@@ -2216,11 +3364,12 @@ func (app *application) friendResponse(w http.ResponseWriter, r *http.Request) {
 	if !v.Valid() {
 
 		// sending the errors
+		w.WriteHeader(http.StatusUnprocessableEntity)
 		_, err = w.Write(v.Errors())
 		if err != nil {
 			app.serverError(w, r, err)
-			return
 		}
+		return
 	}
 
 	// setting the response
@@ -2271,11 +3420,12 @@ func (app *application) friendDelete(w http.ResponseWriter, r *http.Request) {
 	if !v.Valid() {
 
 		// sending the errors
+		w.WriteHeader(http.StatusUnprocessableEntity)
 		_, err = w.Write(v.Errors())
 		if err != nil {
 			app.serverError(w, r, err)
-			return
 		}
+		return
 	}
 
 	// setting the response
@@ -2293,3 +3443,53 @@ func (app *application) friendDelete(w http.ResponseWriter, r *http.Request) {
 		app.serverError(w, r, err)
 	}
 }
+
+func (app *application) readNotifications(w http.ResponseWriter, r *http.Request) {
+
+	// getting the ids from the form (none means all notifications)
+	form := newReadNotificationsForm()
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+
+		// DEBUG
+		app.logger.Debug(fmt.Sprintf("error decoding the form: %s", err))
+
+		app.clientError(r, w, http.StatusBadRequest)
+		return
+	}
+
+	// setting the header for json response
+	w.Header().Set("Content-Type", "application/json")
+
+	// sending the request to the API
+	v := validator.New()
+	unread, err := app.models.NotificationModel.MarkAsRead(app.getToken(r, authTokenSessionManager), form.IDs, v)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// looking for errors from the API
+	if !v.Valid() {
+
+		// sending the errors
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, err = w.Write(v.Errors())
+		if err != nil {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	// setting the response
+	response, err := json.Marshal(map[string]int{"unread": unread})
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	_, err = w.Write(response)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
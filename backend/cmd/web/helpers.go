@@ -1,11 +1,15 @@
 package main
 
 import (
+	"Projet-Forum/internal/api"
 	"Projet-Forum/internal/data"
 	"Projet-Forum/internal/validator"
 	"bytes"
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/alexedwards/flow"
@@ -33,6 +37,29 @@ func (app *application) logout(r *http.Request) error {
 	return nil
 }
 
+// logIn stores the user and their tokens in the session once authenticated
+func (app *application) logIn(w http.ResponseWriter, r *http.Request, tokens *data.Tokens) {
+
+	// fetching the user id from the API
+	v := validator.New()
+	user, err := app.models.UserModel.GetByID(tokens.Authentication.Token, "me", nil, v)
+	if err != nil || !v.Valid() {
+		switch {
+		case errors.Is(err, api.ErrRecordNotFound):
+			app.clientError(r, w, http.StatusNotFound)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	// storing the user id and tokens in the user session
+	app.sessionManager.Put(r.Context(), authenticatedUserIDSessionManager, user.ID)
+	app.putToken(r, *tokens)
+
+	http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+}
+
 func newNonce() (string, error) {
 	nonceBytes := make([]byte, 16)
 	_, err := rand.Read(nonceBytes)
@@ -42,6 +69,27 @@ func newNonce() (string, error) {
 	return hex.EncodeToString(nonceBytes), nil
 }
 
+// newPKCE returns a PKCE code verifier (RFC 7636) along with its S256 code challenge
+func newPKCE() (string, string, error) {
+	verifierBytes := make([]byte, 32)
+	_, err := rand.Read(verifierBytes)
+	if err != nil {
+		return "", "", err
+	}
+	verifier := base64.RawURLEncoding.EncodeToString(verifierBytes)
+	hash := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(hash[:]), nil
+}
+
+// providerRedirectURI returns the URL of the callback the identity provider sends the user back to
+func providerRedirectURI(r *http.Request, provider string) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s/auth/%s/callback", scheme, r.Host, url.PathEscape(provider))
+}
+
 func (app *application) getNonce(r *http.Request) string {
 	nonce, ok := r.Context().Value(nonceContextKey).(string)
 	if !ok {
@@ -128,6 +176,51 @@ func (app *application) putToken(r *http.Request, tokens data.Tokens) {
 	app.sessionManager.Put(r.Context(), refreshExpirySessionManager, tokens.Refresh.Expiry.String())
 }
 
+// rotatedTokensTTL is how long the tokens obtained with a refresh token are kept for the concurrent requests using it
+const rotatedTokensTTL = 30 * time.Second
+
+func newRotatedTokens() *rotatedTokens {
+	return &rotatedTokens{
+		tokens: make(map[string]rotatedToken),
+	}
+}
+
+// refreshTokens exchanges the refresh token for new tokens, only once even if several requests of the user need it
+func (app *application) refreshTokens(tokens *data.Tokens, v *validator.Validator) error {
+
+	app.rotatedTokens.mu.Lock()
+	defer app.rotatedTokens.mu.Unlock()
+
+	// forgetting the old rotations
+	for refreshToken, rotated := range app.rotatedTokens.tokens {
+		if time.Now().After(rotated.expiry) {
+			delete(app.rotatedTokens.tokens, refreshToken)
+		}
+	}
+
+	// the refresh token was just exchanged by another request
+	refreshToken := tokens.Refresh.Token
+	if rotated, ok := app.rotatedTokens.tokens[refreshToken]; ok {
+		*tokens = rotated.tokens
+		return nil
+	}
+
+	// request new tokens from API with refresh token
+	err := app.models.TokenModel.Refresh(tokens, v)
+	if err != nil {
+		return err
+	}
+
+	if v.Valid() {
+		app.rotatedTokens.tokens[refreshToken] = rotatedToken{
+			tokens: *tokens,
+			expiry: time.Now().Add(rotatedTokensTTL),
+		}
+	}
+
+	return nil
+}
+
 func (app *application) getTokens(r *http.Request) (*data.Tokens, error) {
 	var tokens data.Tokens
 	authToken := app.getToken(r, authTokenSessionManager)
@@ -195,6 +288,12 @@ func newUserUpdateForm() *userUpdateForm {
 	}
 }
 
+func newTOTPForm() *totpForm {
+	return &totpForm{
+		Validator: *validator.New(),
+	}
+}
+
 func newForgotPasswordForm() *forgotPasswordForm {
 	return &forgotPasswordForm{
 		Validator: *validator.New(),
@@ -238,6 +337,24 @@ func newReactToPostForm() *reactToPostForm {
 	}
 }
 
+func newReadNotificationsForm() *readNotificationsForm {
+	return &readNotificationsForm{
+		Validator: *validator.New(),
+	}
+}
+
+func newConversationForm() *conversationForm {
+	return &conversationForm{
+		Validator: *validator.New(),
+	}
+}
+
+func newMessageForm() *messageForm {
+	return &messageForm{
+		Validator: *validator.New(),
+	}
+}
+
 func newFriendResponseForm() *friendResponseForm {
 	return &friendResponseForm{
 		Validator:      *validator.New(),
@@ -245,6 +362,26 @@ func newFriendResponseForm() *friendResponseForm {
 	}
 }
 
+func newSubscriptionForm() *subscriptionForm {
+	return &subscriptionForm{
+		Validator: *validator.New(),
+		Levels:    []string{"none", "in_app", "immediate", "daily"},
+	}
+}
+
+func newPrivacyForm() *privacyForm {
+	return &privacyForm{
+		Validator:    *validator.New(),
+		Visibilities: []string{"public", "friends", "private"},
+	}
+}
+
+func newSearchForm() *searchForm {
+	return &searchForm{
+		Validator: *validator.New(),
+	}
+}
+
 func (app *application) newTemplateData(r *http.Request, allUser bool, overlay string) templateData {
 
 	// checking is the user is authenticated
@@ -270,6 +407,17 @@ func (app *application) newTemplateData(r *http.Request, allUser bool, overlay s
 		}
 		user, _ = app.models.UserModel.GetByID(token, "me", query, v)
 	}
+
+	// retrieving the latest notifications for the header
+	var notifications []*data.Notification
+	var unread int
+	if isAuthenticated {
+		var err error
+		notifications, unread, _, err = app.models.NotificationModel.Get(token, url.Values{"page_size": {"8"}}, v)
+		if err != nil {
+			app.logger.Error(err.Error())
+		}
+	}
 	categories, metadata, err := app.models.CategoryModel.Get(token, nil, v)
 	if err != nil {
 		app.logger.Error(err.Error())
@@ -280,7 +428,7 @@ func (app *application) newTemplateData(r *http.Request, allUser bool, overlay s
 	}
 
 	// returning the templateData with all information
-	return templateData{
+	tmplData := templateData{
 		Overlay:         overlay,
 		CurrentYear:     time.Now().Year(),
 		Flash:           app.sessionManager.PopString(r.Context(), "flash"),
@@ -303,6 +451,35 @@ func (app *application) newTemplateData(r *http.Request, allUser bool, overlay s
 			List     []*data.Category
 		}{Metadata: metadata, List: categories},
 	}
+	tmplData.Notifications.Unread = unread
+	tmplData.Notifications.List = notifications
+
+	// retrieving the identity providers for the login buttons
+	if overlay == Overlay.Login {
+		tmplData.IdentityProviders, err = app.models.OIDCModel.GetProviders(v)
+		if err != nil {
+			app.logger.Error(err.Error())
+		}
+	}
+
+	return tmplData
+}
+
+// writeJSON sends the response of the AJAX endpoints
+func (app *application) writeJSON(w http.ResponseWriter, r *http.Request, status int, payload any) {
+
+	response, err := json.Marshal(payload)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(status)
+
+	_, err = w.Write(response)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
 }
 
 func (app *application) render(w http.ResponseWriter, r *http.Request, status int, page string, data templateData) {
@@ -128,6 +128,7 @@ func main() {
 		formDecoder:    formDecoder,
 		config:         &cfg,
 		models:         data.NewModels(cfg.apiURL, *clientToken, pemKey),
+		rotatedTokens:  newRotatedTokens(),
 	}
 
 	server := http.Server{
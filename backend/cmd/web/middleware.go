@@ -16,6 +16,10 @@ const (
 	refreshTokenSessionManager        = "refresh_token"
 	refreshExpirySessionManager       = "refresh_expiry"
 	authenticatedUserIDSessionManager = "authenticated_user_id"
+	mfaTokenSessionManager            = "mfa_token"
+	oidcStateSessionManager           = "oidc_state"
+	oidcNonceSessionManager           = "oidc_nonce"
+	oidcVerifierSessionManager        = "oidc_verifier"
 )
 
 func commonHeaders(next http.Handler) http.Handler {
@@ -110,9 +114,9 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 				// checking the refresh token validity
 				if time.Until(tokens.Refresh.Expiry) > 0 {
 
-					// request new tokens from API with refresh token
+					// request new tokens from API with refresh token (rotated at each use)
 					v := validator.New()
-					err := app.models.TokenModel.Refresh(tokens.Refresh.Token, tokens, v)
+					err := app.refreshTokens(tokens, v)
 					if err != nil {
 						app.serverError(w, r, err)
 						return
@@ -129,7 +133,7 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 						return
 					}
 
-					// replacing the tokens in the user session
+					// replacing the tokens in the user session, the old refresh token cannot be used anymore
 					app.putToken(r, *tokens)
 				} else {
 
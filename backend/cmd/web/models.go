@@ -7,6 +7,9 @@ import (
 	"github.com/go-playground/form/v4"
 	"html/template"
 	"log/slog"
+	"slices"
+	"sync"
+	"time"
 )
 
 type config struct {
@@ -26,6 +29,20 @@ type application struct {
 	sessionManager *scs.SessionManager
 	models         data.Models
 	config         *config
+	rotatedTokens  *rotatedTokens
+}
+
+// rotatedTokens remembers for a short while the tokens each refresh token was exchanged for.
+// The API accepts each refresh token only once, so the concurrent requests of a browser
+// still holding the old refresh token in their session reuse the new tokens instead.
+type rotatedTokens struct {
+	mu     sync.Mutex
+	tokens map[string]rotatedToken
+}
+
+type rotatedToken struct {
+	tokens data.Tokens
+	expiry time.Time
 }
 
 type overlayEnum struct {
@@ -34,6 +51,7 @@ type overlayEnum struct {
 	Register       string
 	ForgotPassword string
 	ResetPassword  string
+	MFA            string
 }
 
 var Overlay = overlayEnum{
@@ -42,6 +60,7 @@ var Overlay = overlayEnum{
 	Register:       "register",
 	ForgotPassword: "forgot-password",
 	ResetPassword:  "reset-password",
+	MFA:            "mfa",
 }
 
 type templateData struct {
@@ -64,6 +83,7 @@ type templateData struct {
 	NonFieldErrors    []string
 	User              data.User
 	Search            string
+	Branch            int
 	CategoriesNavLeft []*data.Category
 	PopularTags       []*data.Tag
 	PopularThreads    []*data.Thread
@@ -83,9 +103,41 @@ type templateData struct {
 		Metadata data.Metadata
 		List     []*data.Tag
 	}
-	Category *data.Category
-	Thread   *data.Thread
-	Tag      *data.Tag
+	SearchResults struct {
+		Metadata   data.Metadata
+		Threads    []*data.SearchHit
+		Posts      []*data.SearchHit
+		Tags       []*data.SearchHit
+		Categories []*data.SearchHit
+	}
+	Notifications struct {
+		Unread int
+		List   []*data.Notification
+	}
+	Inbox struct {
+		Metadata data.Metadata
+		List     []*data.Conversation
+	}
+	Messages struct {
+		List       []*data.Message
+		NextCursor int
+	}
+	SearchFacets struct {
+		Categories []*data.Category
+		Tags       []*data.Tag
+		Authors    []data.User
+	}
+	Category     *data.Category
+	Thread       *data.Thread
+	Tag          *data.Tag
+	Conversation *data.Conversation
+	Profile      *data.User
+	Privacy      *data.Privacy
+	Sessions     []*data.Session
+	Subscription string
+	TOTP         *data.TOTP
+
+	IdentityProviders []*data.IdentityProvider
 }
 
 type userLoginForm struct {
@@ -94,6 +146,11 @@ type userLoginForm struct {
 	validator.Validator `form:"-"`
 }
 
+type totpForm struct {
+	Code                string `form:"code"`
+	validator.Validator `form:"-"`
+}
+
 type userUpdateForm struct {
 	Username             *string `form:"username,omitempty"`
 	Email                *string `form:"email,omitempty"`
@@ -166,8 +223,64 @@ type reactToPostForm struct {
 	validator.Validator `form:"-"`
 }
 
+type readNotificationsForm struct {
+	IDs                 []int `form:"ids"`
+	validator.Validator `form:"-"`
+}
+
+type conversationForm struct {
+	UserID              *int `form:"user_id,omitempty"`
+	validator.Validator `form:"-"`
+}
+
+type messageForm struct {
+	Content             *string `form:"content,omitempty"`
+	validator.Validator `form:"-"`
+}
+
 type friendResponseForm struct {
 	Status              string   `form:"status"`
 	FriendStatuses      []string `form:"-"`
 	validator.Validator `form:"-"`
 }
+
+type subscriptionForm struct {
+	Level               string   `form:"level"`
+	Levels              []string `form:"-"`
+	validator.Validator `form:"-"`
+}
+
+type privacyForm struct {
+	Email               string   `form:"email" json:"email"`
+	BirthDate           string   `form:"birth_date" json:"birth_date"`
+	Friends             string   `form:"friends" json:"friends"`
+	FavoriteThreads     string   `form:"favorite_threads" json:"favorite_threads"`
+	FollowingTags       string   `form:"following_tags" json:"following_tags"`
+	Reactions           string   `form:"reactions" json:"reactions"`
+	Visibilities        []string `form:"-" json:"-"`
+	validator.Validator `form:"-" json:"-"`
+}
+
+type searchForm struct {
+	Search               string   `form:"q"`
+	Types                []string `form:"type"`
+	CategoryID           int      `form:"category"`
+	IncludeSubcategories bool     `form:"include_subcategories"`
+	TagIDs               []int    `form:"tag_id[]"`
+	TagMode              string   `form:"tag_mode"`
+	Status               string   `form:"status"`
+	AuthorID             int      `form:"author"`
+	CreatedAfter         string   `form:"created_after"`
+	CreatedBefore        string   `form:"created_before"`
+	validator.Validator  `form:"-"`
+}
+
+// HasType tells whether the search is restricted to the hit type (used to check the facets' boxes)
+func (f *searchForm) HasType(hitType string) bool {
+	return slices.Contains(f.Types, hitType)
+}
+
+// HasTag tells whether the tag is among the filtered tags (used to check the facets' boxes)
+func (f *searchForm) HasTag(id int) bool {
+	return slices.Contains(f.TagIDs, id)
+}
@@ -41,6 +41,8 @@ func (app *application) routes() http.Handler {
 
 	router.HandleFunc("/search", app.search, http.MethodGet) // search page
 
+	router.HandleFunc("/stream", app.stream, http.MethodGet) // real-time events (Server-Sent Events)
+
 	/* #############################################################################
 	/*	USER ACCESS
 	/* #############################################################################*/
@@ -48,6 +50,12 @@ func (app *application) routes() http.Handler {
 	router.HandleFunc("/login", app.login, http.MethodGet)      // login page
 	router.HandleFunc("/login", app.loginPost, http.MethodPost) // login treatment route
 
+	router.HandleFunc("/login/mfa", app.loginMFA, http.MethodGet)      // two-factor authentication code prompt
+	router.HandleFunc("/login/mfa", app.loginMFAPost, http.MethodPost) // two-factor authentication treatment route
+
+	router.HandleFunc("/auth/:provider", app.loginProvider, http.MethodGet)                  // redirection to the identity provider (OpenID Connect or GitHub)
+	router.HandleFunc("/auth/:provider/callback", app.loginProviderCallback, http.MethodGet) // identity provider's callback route
+
 	router.HandleFunc("/register", app.register, http.MethodGet)      // register page
 	router.HandleFunc("/register", app.registerPost, http.MethodPost) // register treatment route
 
@@ -60,16 +68,24 @@ func (app *application) routes() http.Handler {
 	router.HandleFunc("/reset-password/:token", app.resetPassword, http.MethodGet) // reset password page
 	router.HandleFunc("/reset-password", app.resetPasswordPost, http.MethodPost)   // reset password treatment route
 
+	router.HandleFunc("/unsubscribe/:token", app.unsubscribe, http.MethodGet) // one-click unsubscribe route (from the emails)
+	router.HandleFunc("/unlock/:token", app.unlock, http.MethodGet)           // account unlock route (from the lockout emails)
+
 	/* #############################################################################
 	/*	RESTRICTED
 	/* #############################################################################*/
 
 	router.Use(app.requireAuthentication)
 
-	router.HandleFunc("/dashboard", app.dashboard, http.MethodGet) // dashboard page
-	router.HandleFunc("/logout", app.logoutPost, http.MethodPost)  // logout route
-	router.HandleFunc("/user", app.updateUser, http.MethodGet)     // update user page
-	router.HandleFunc("/user", app.updateUserPut, http.MethodPut)  // update user treatment route
+	router.HandleFunc("/dashboard", app.dashboard, http.MethodGet)                     // dashboard page
+	router.HandleFunc("/logout", app.logoutPost, http.MethodPost)                      // logout route
+	router.HandleFunc("/logout/everywhere", app.logoutEverywherePost, http.MethodPost) // logout of all devices route
+	router.HandleFunc("/user", app.updateUser, http.MethodGet)                         // update user page
+	router.HandleFunc("/user", app.updateUserPut, http.MethodPut)                      // update user treatment route
+	router.HandleFunc("/user/avatar", app.updateAvatarPost, http.MethodPost)           // avatar upload route
+	router.HandleFunc("/user/privacy", app.updatePrivacyPost, http.MethodPost)         // privacy settings route
+	router.HandleFunc("/user/totp/disable", app.disableTOTPPost, http.MethodPost)      // two-factor authentication disabling route
+	router.HandleFunc("/user/:id", app.userGet, http.MethodGet)                        // public profile page
 
 	router.HandleFunc("/post/create", app.createPost, http.MethodGet)         // post creation page
 	router.HandleFunc("/tag/create", app.createTag, http.MethodGet)           // tag creation page
@@ -91,10 +107,18 @@ func (app *application) routes() http.Handler {
 	router.HandleFunc("/post/:id/update", app.updatePostPut, http.MethodPut)         // category update treatment route
 	router.HandleFunc("/tag/:id/update", app.updateTagPut, http.MethodPut)           // thread update treatment route
 
+	router.HandleFunc("/inbox", app.inbox, http.MethodGet)                 // inbox page
+	router.HandleFunc("/inbox", app.inboxPost, http.MethodPost)            // conversation opening route
+	router.HandleFunc("/inbox/:id", app.conversationGet, http.MethodGet)   // conversation page
+	router.HandleFunc("/inbox/:id", app.conversationPost, http.MethodPost) // message sending route
+
 	/* #############################################################################
 	/*	AJAX endpoints
 	/* #############################################################################*/
 
+	// Post preview
+	router.HandleFunc("/posts/preview", app.previewPost, http.MethodPost)
+
 	// Post reactions
 	router.HandleFunc("/posts/:id/react", app.reactToPost, http.MethodPost)
 	router.HandleFunc("/posts/:id/react", app.changeReactionPost, http.MethodPatch)
@@ -108,10 +132,24 @@ func (app *application) routes() http.Handler {
 	router.HandleFunc("/threads/:id/favorite", app.addToFavoritesThread, http.MethodPost)
 	router.HandleFunc("/threads/:id/favorite", app.removeFromFavoritesThread, http.MethodDelete)
 
+	// Thread subscription
+	router.HandleFunc("/threads/:id/subscription", app.updateSubscription, http.MethodPut)
+
 	// Friends
 	router.HandleFunc("/users/:id/friend", app.friendRequest, http.MethodPost)
 	router.HandleFunc("/users/:id/friend", app.friendResponse, http.MethodPut)
 	router.HandleFunc("/users/:id/friend", app.friendDelete, http.MethodDelete)
 
+	// Two-factor authentication
+	router.HandleFunc("/user/totp", app.enrollTOTP, http.MethodPost)
+	router.HandleFunc("/user/totp", app.confirmTOTP, http.MethodPut)
+	router.HandleFunc("/user/totp/recovery-codes", app.regenerateRecoveryCodes, http.MethodPost)
+
+	// Sessions
+	router.HandleFunc("/user/sessions/:id", app.deleteSession, http.MethodDelete)
+
+	// Notifications
+	router.HandleFunc("/notifications/read", app.readNotifications, http.MethodPut)
+
 	return router
 }
@@ -3,15 +3,50 @@ package main
 import (
 	"Projet-Forum/internal/data"
 	"Projet-Forum/ui"
+	"fmt"
 	"html/template"
 	"io/fs"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"time"
 )
 
 var functions = template.FuncMap{
 	"humanDate":       humanDate,
 	"getUserReaction": getUserReaction,
+	"friendStatus":    friendStatus,
+	"highlight":       highlight,
+	"linkMentions":    linkMentions,
+	"postNode":        newPostNode,
+}
+
+// postNode is the data needed to render a post and its replies recursively
+type postNode struct {
+	User      data.User
+	Post      *data.Post
+	CSRFToken string
+	ThreadID  int
+}
+
+var (
+	// htmlTagRX matches the tags of the rendered content
+	htmlTagRX = regexp.MustCompile(`<[^>]*>`)
+
+	// unlinkedTagRX matches the tags of the elements whose text is never linked
+	unlinkedTagRX = regexp.MustCompile(`^</?(?i:a|code|pre)[\s>]`)
+
+	// mentionRX matches an @username or a #tagname in the escaped text of the rendered content
+	mentionRX = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_@#&/;])([@#][\p{L}\p{N}_-]+(?:\.[\p{L}\p{N}_-]+)*)`)
+)
+
+func newPostNode(user data.User, post *data.Post, csrfToken string, threadID int) postNode {
+	return postNode{
+		User:      user,
+		Post:      post,
+		CSRFToken: csrfToken,
+		ThreadID:  threadID,
+	}
 }
 
 func humanDate(t time.Time) string {
@@ -28,6 +63,52 @@ func getUserReaction(user data.User, postID int) string {
 	return ""
 }
 
+// friendStatus returns the relation between the user and the user with the id:
+// "self", "friend", "sent" or "received" (pending request) or "none"
+func friendStatus(user data.User, id int) string {
+	if user.ID == id {
+		return "self"
+	}
+	for _, friend := range user.Friends {
+		if friend.ID == id {
+			return "friend"
+		}
+	}
+	for _, friend := range user.Invitations.Sent {
+		if friend.ID == id {
+			return "sent"
+		}
+	}
+	for _, friend := range user.Invitations.Received {
+		if friend.ID == id {
+			return "received"
+		}
+	}
+	return "none"
+}
+
+// highlight escapes the snippet of a search hit and wraps its highlighted terms in <mark> tags
+func highlight(hit *data.SearchHit) template.HTML {
+
+	runes := []rune(hit.Snippet)
+
+	var builder strings.Builder
+	last := 0
+	for _, h := range hit.Highlights {
+		if h.Offset < last || h.Offset+h.Length > len(runes) {
+			continue
+		}
+		builder.WriteString(template.HTMLEscapeString(string(runes[last:h.Offset])))
+		builder.WriteString("<mark>")
+		builder.WriteString(template.HTMLEscapeString(string(runes[h.Offset : h.Offset+h.Length])))
+		builder.WriteString("</mark>")
+		last = h.Offset + h.Length
+	}
+	builder.WriteString(template.HTMLEscapeString(string(runes[last:])))
+
+	return template.HTML(builder.String())
+}
+
 func newTemplateCache() (map[string]*template.Template, error) {
 
 	cache := map[string]*template.Template{}
@@ -56,3 +137,68 @@ func newTemplateCache() (map[string]*template.Template, error) {
 
 	return cache, nil
 }
+
+// linkMentions returns the rendered content of the post with its mentions turned into links
+// to the user's profile or the tag's page (the mentions in links and code are left as is)
+func linkMentions(post *data.Post) template.HTML {
+
+	if len(post.Mentions) == 0 {
+		return post.ContentHTML
+	}
+
+	links := make(map[string]string)
+	for _, mention := range post.Mentions {
+		switch mention.Type {
+		case "user":
+			links["@"+strings.ToLower(mention.Name)] = fmt.Sprintf("/user/%d", mention.ID)
+		case "tag":
+			links["#"+strings.ToLower(mention.Name)] = fmt.Sprintf("/tag/%d", mention.ID)
+		}
+	}
+
+	linkText := func(builder *strings.Builder, text string) {
+		last := 0
+		for _, match := range mentionRX.FindAllStringSubmatchIndex(text, -1) {
+			href, ok := links[strings.ToLower(text[match[2]:match[3]])]
+			if !ok {
+				continue
+			}
+			builder.WriteString(text[last:match[2]])
+			builder.WriteString(fmt.Sprintf(`<a href="%s" class="mention">%s</a>`, href, text[match[2]:match[3]]))
+			last = match[3]
+		}
+		builder.WriteString(text[last:])
+	}
+
+	content := string(post.ContentHTML)
+
+	var builder strings.Builder
+	unlinked := 0
+	last := 0
+	for _, loc := range htmlTagRX.FindAllStringIndex(content, -1) {
+		if unlinked == 0 {
+			linkText(&builder, content[last:loc[0]])
+		} else {
+			builder.WriteString(content[last:loc[0]])
+		}
+
+		tag := content[loc[0]:loc[1]]
+		builder.WriteString(tag)
+
+		if unlinkedTagRX.MatchString(tag) {
+			if strings.HasPrefix(tag, "</") {
+				unlinked--
+			} else {
+				unlinked++
+			}
+		}
+		last = loc[1]
+	}
+	if unlinked == 0 {
+		linkText(&builder, content[last:])
+	} else {
+		builder.WriteString(content[last:])
+	}
+
+	return template.HTML(builder.String())
+}
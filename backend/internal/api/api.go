@@ -3,11 +3,13 @@ package api
 import (
 	"Projet-Forum/internal/validator"
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log"
+	"mime/multipart"
 	"net/http"
 	"net/url"
 	"os"
@@ -23,6 +25,8 @@ const (
 
 var (
 	client                = http.Client{Timeout: time.Second * 5}
+	streamClient          = http.Client{} // no timeout: the stream lasts as long as the request's context
+	uploadClient          = http.Client{Timeout: time.Second * 60}
 	permittedMethods      = []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
 	lock                  = &sync.Mutex{}
 	ErrUnmarshallAPIError = errors.New("error unmarshalling API error response")
@@ -195,6 +199,38 @@ func (api *API) Get(userToken, endpoint string, query url.Values) ([]byte, int,
 	return body, res.StatusCode, nil
 }
 
+// Stream opens a long-lived GET request (e.g. Server-Sent Events) bound to ctx.
+// The caller is responsible for closing the response's body.
+func (api *API) Stream(ctx context.Context, userToken, endpoint string, query url.Values) (*http.Response, error) {
+
+	// building the url request
+	urlRequest := strings.TrimSpace(fmt.Sprintf("%s/v1%s", api.url, endpoint))
+
+	// creating the request
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, urlRequest, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	// adding the query if necessary
+	if query != nil {
+		req.URL.RawQuery = query.Encode()
+	}
+
+	// fetching the user token
+	var userAuth string
+	if userToken != "" {
+		userAuth = fmt.Sprintf(",Bearer %s", userToken)
+	}
+
+	// setting the authorization header
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s%s", api.clientToken, userAuth))
+
+	// sending the request
+	return streamClient.Do(req)
+}
+
 func (api *API) Request(userToken, method, endpoint string, body []byte, isEncrypted bool) ([]byte, int, error) {
 
 	// checking the method
@@ -260,6 +296,74 @@ func (api *API) Request(userToken, method, endpoint string, body []byte, isEncry
 	return body, res.StatusCode, nil
 }
 
+// Upload sends the content as the file field of a multipart form, for the file uploads
+func (api *API) Upload(userToken, method, endpoint, field, filename string, content []byte) ([]byte, int, error) {
+
+	// checking the method
+	if !slices.Contains(permittedMethods, method) {
+		return nil, StatusFailedRequest, errors.New("invalid method")
+	}
+
+	// building the url request
+	urlRequest := strings.TrimSpace(fmt.Sprintf("%s/v1%s", api.url, endpoint))
+
+	// building the multipart body
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	part, err := writer.CreateFormFile(field, filename)
+	if err != nil {
+		return nil, StatusFailedRequest, err
+	}
+	_, err = part.Write(content)
+	if err != nil {
+		return nil, StatusFailedRequest, err
+	}
+	err = writer.Close()
+	if err != nil {
+		return nil, StatusFailedRequest, err
+	}
+
+	// creating the request
+	req, err := http.NewRequest(method, urlRequest, &body)
+	if err != nil {
+		return nil, StatusFailedRequest, err
+	}
+
+	// setting content related headers
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Accept", "application/json")
+
+	// fetching the user token
+	var userAuth string
+	if userToken != "" {
+		userAuth = fmt.Sprintf(",Bearer %s", userToken)
+	}
+
+	// setting the authorization header
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s%s", api.clientToken, userAuth))
+
+	// sending the request (uploads may take longer than the other requests)
+	res, err := uploadClient.Do(req)
+	if err != nil {
+		return nil, StatusFailedRequest, err
+	}
+	defer res.Body.Close()
+
+	// checking for error 404
+	if res.StatusCode == http.StatusNotFound {
+		return nil, res.StatusCode, ErrRecordNotFound
+	}
+
+	// reading the body of the response
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, StatusFailedRequest, err
+	}
+
+	return resBody, res.StatusCode, nil
+}
+
 func GetErr(statusCode int, body []byte, v *validator.Validator) error {
 
 	// No error (status 2XX)
@@ -278,6 +382,16 @@ func GetErr(statusCode int, body []byte, v *validator.Validator) error {
 		return nil
 	}
 
+	// Bad Request on invalid query parameters (field errors)
+	if statusCode == 400 {
+		var apiErr = make(map[string]map[string]string)
+		err := json.Unmarshal(body, &apiErr)
+		if err == nil && apiErr["errors"] != nil {
+			v.FieldErrors = apiErr["errors"]
+			return nil
+		}
+	}
+
 	// All other errors
 	var apiErr = make(map[string]string)
 	err := json.Unmarshal(body, &apiErr)
@@ -0,0 +1,168 @@
+package data
+
+import (
+	"Projet-Forum/internal/api"
+	"Projet-Forum/internal/validator"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+type MessageModel struct {
+	uri         string
+	endpoint    string
+	clientToken string
+	pemKey      []byte
+}
+
+func (m *MessageModel) api() *api.API {
+	return api.GetInstance(m.uri, m.clientToken, m.pemKey)
+}
+
+func (m *MessageModel) GetConversations(token string, query url.Values, v *validator.Validator) ([]*Conversation, Metadata, error) {
+
+	// making the request
+	res, status, err := m.api().Get(token, m.endpoint, query)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	// checking for errors
+	err = api.GetErr(status, res, v)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	var response struct {
+		Metadata      Metadata        `json:"_metadata"`
+		Conversations []*Conversation `json:"conversations"`
+	}
+	if v.Valid() {
+
+		// retrieving the conversations
+		err = json.Unmarshal(res, &response)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+	}
+
+	return response.Conversations, response.Metadata, nil
+}
+
+// CreateConversation returns the conversation with the user userID, creating it if necessary
+func (m *MessageModel) CreateConversation(token string, userID int, v *validator.Validator) (*Conversation, error) {
+
+	// creating the request body
+	body := envelope{
+		"user_id": userID,
+	}
+	reqBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	// making the request
+	res, status, err := m.api().Request(token, http.MethodPost, m.endpoint, reqBody, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// checking for errors
+	err = api.GetErr(status, res, v)
+	if err != nil {
+		return nil, err
+	}
+	var conversation *Conversation
+	if v.Valid() {
+
+		// retrieving the conversation
+		var response = make(map[string]*Conversation)
+		err = json.Unmarshal(res, &response)
+		if err != nil {
+			return nil, err
+		}
+		conversation = response["conversation"]
+		if conversation == nil || conversation.ID < 1 {
+			return nil, errors.New("invalid conversation id")
+		}
+	}
+
+	return conversation, nil
+}
+
+// GetMessages returns the conversation, its messages (newest first) and the cursor to get older messages (0 if none)
+func (m *MessageModel) GetMessages(token string, id int, query url.Values, v *validator.Validator) (*Conversation, []*Message, int, error) {
+
+	// building the endpoint's specific URL
+	endpoint := fmt.Sprintf("%s/%d/messages", m.endpoint, id)
+
+	// making the request
+	res, status, err := m.api().Get(token, endpoint, query)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	// checking for errors
+	err = api.GetErr(status, res, v)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	var response struct {
+		Conversation *Conversation `json:"conversation"`
+		Messages     []*Message    `json:"messages"`
+		NextCursor   int           `json:"next_cursor"`
+	}
+	if v.Valid() {
+
+		// retrieving the messages
+		err = json.Unmarshal(res, &response)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+	}
+
+	return response.Conversation, response.Messages, response.NextCursor, nil
+}
+
+func (m *MessageModel) Send(token string, id int, content string, v *validator.Validator) (*Message, error) {
+
+	// creating the request body
+	body := envelope{
+		"content": content,
+	}
+	reqBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	// building the endpoint's specific URL
+	endpoint := fmt.Sprintf("%s/%d/messages", m.endpoint, id)
+
+	// making the request
+	res, status, err := m.api().Request(token, http.MethodPost, endpoint, reqBody, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// checking for errors
+	err = api.GetErr(status, res, v)
+	if err != nil {
+		return nil, err
+	}
+	var message *Message
+	if v.Valid() {
+
+		// retrieving the message
+		var response = make(map[string]*Message)
+		err = json.Unmarshal(res, &response)
+		if err != nil {
+			return nil, err
+		}
+		message = response["message"]
+	}
+
+	return message, nil
+}
@@ -6,12 +6,17 @@ import (
 )
 
 type Models struct {
-	TokenModel    *TokenModel
-	UserModel     *UserModel
-	CategoryModel *CategoryModel
-	ThreadModel   *ThreadModel
-	PostModel     *PostModel
-	TagModel      *TagModel
+	TokenModel        *TokenModel
+	UserModel         *UserModel
+	CategoryModel     *CategoryModel
+	ThreadModel       *ThreadModel
+	PostModel         *PostModel
+	TagModel          *TagModel
+	SearchModel       *SearchModel
+	NotificationModel *NotificationModel
+	MessageModel      *MessageModel
+	StreamModel       *StreamModel
+	OIDCModel         *OIDCModel
 }
 
 func NewModels(uri, clientToken string, pemKey []byte) Models {
@@ -52,6 +57,36 @@ func NewModels(uri, clientToken string, pemKey []byte) Models {
 			clientToken: clientToken,
 			pemKey:      pemKey,
 		},
+		SearchModel: &SearchModel{
+			uri:         uri,
+			endpoint:    "/search",
+			clientToken: clientToken,
+			pemKey:      pemKey,
+		},
+		NotificationModel: &NotificationModel{
+			uri:         uri,
+			endpoint:    "/notifications",
+			clientToken: clientToken,
+			pemKey:      pemKey,
+		},
+		MessageModel: &MessageModel{
+			uri:         uri,
+			endpoint:    "/conversations",
+			clientToken: clientToken,
+			pemKey:      pemKey,
+		},
+		StreamModel: &StreamModel{
+			uri:         uri,
+			endpoint:    "/stream",
+			clientToken: clientToken,
+			pemKey:      pemKey,
+		},
+		OIDCModel: &OIDCModel{
+			uri:         uri,
+			endpoint:    "/oidc",
+			clientToken: clientToken,
+			pemKey:      pemKey,
+		},
 	}
 }
 
@@ -66,8 +101,15 @@ type Metadata struct {
 type envelope map[string]any
 
 type Tokens struct {
-	Authentication Token `json:"authentication_token"`
-	Refresh        Token `json:"refresh_token"`
+	Authentication Token  `json:"authentication_token"`
+	Refresh        Token  `json:"refresh_token"`
+	MFA            *Token `json:"mfa_token,omitempty"` // only set when the user must send a two-factor authentication code to log in
+}
+
+// IdentityProvider is an OpenID Connect provider (or GitHub) the users may log in with
+type IdentityProvider struct {
+	Key  string `json:"key"`
+	Name string `json:"name"`
 }
 
 type Token struct {
@@ -102,6 +144,47 @@ type User struct {
 	} `json:"invitations,omitempty"`
 }
 
+// Privacy is the visibility ("public", "friends" or "private") of the profile fields and activity of a user
+type Privacy struct {
+	Email           string `json:"email"`
+	BirthDate       string `json:"birth_date"`
+	Friends         string `json:"friends"`
+	FavoriteThreads string `json:"favorite_threads"`
+	FollowingTags   string `json:"following_tags"`
+	Reactions       string `json:"reactions"`
+}
+
+// Session is a device on which the user is logged in
+type Session struct {
+	ID         int       `json:"id"`
+	Device     string    `json:"device"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	Current    bool      `json:"current"`
+}
+
+// TOTP is the state of the two-factor authentication of the user
+type TOTP struct {
+	Enabled           bool       `json:"enabled"`
+	ConfirmedAt       *time.Time `json:"confirmed_at,omitempty"`
+	RecoveryCodesLeft int        `json:"recovery_codes_left"`
+}
+
+// TOTPEnrolment is the secret to add to an authenticator app before confirming it with a first code
+type TOTPEnrolment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+	QRCode string `json:"qr_code"` // base64 encoded PNG image
+}
+
+// Subscription is the level ("none", "in_app", "immediate" or "daily") of the notifications of a user about a thread
+type Subscription struct {
+	ThreadID int    `json:"thread_id"`
+	Level    string `json:"level"`
+}
+
 type Friend struct {
 	ID     int    `json:"id"`
 	Name   string `json:"name"`
@@ -164,7 +247,10 @@ type Thread struct {
 
 type Post struct {
 	ID           int            `json:"id"`
-	Content      template.HTML  `json:"content"`
+	Content      string         `json:"content"`
+	ContentHTML  template.HTML  `json:"content_html"`
+	Mentions     []Mention      `json:"mentions,omitempty"`
+	Attachments  []Attachment   `json:"attachments,omitempty"`
 	CreatedAt    time.Time      `json:"created_at"`
 	UpdatedAt    time.Time      `json:"updated_at"`
 	Author       User           `json:"author"`
@@ -173,4 +259,91 @@ type Post struct {
 	Reactions    map[string]int `json:"reactions,omitempty"`
 	Popularity   int            `json:"popularity,omitempty"`
 	Version      int            `json:"version,omitempty"`
+	Depth        int            `json:"depth,omitempty"`
+	ReplyCount   int            `json:"reply_count,omitempty"`
+	Replies      []*Post        `json:"replies,omitempty"`
+}
+
+// Mention is an @username or #tagname of a post's content, Offset and Length being in bytes
+type Mention struct {
+	Type   string `json:"type"`
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	Offset int    `json:"offset"`
+	Length int    `json:"length"`
+}
+
+// Attachment is a file uploaded to a post, images coming with a thumbnail
+type Attachment struct {
+	ID           int    `json:"id"`
+	Filename     string `json:"filename"`
+	ContentType  string `json:"content_type"`
+	Size         int64  `json:"size"`
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnail_url,omitempty"`
+	Width        int    `json:"width,omitempty"`
+	Height       int    `json:"height,omitempty"`
+}
+
+type Highlight struct {
+	Offset int `json:"offset"`
+	Length int `json:"length"`
+}
+
+type Notification struct {
+	ID    int    `json:"id"`
+	Type  string `json:"type"`
+	Actor struct {
+		ID     int    `json:"id"`
+		Name   string `json:"name"`
+		Avatar string `json:"avatar,omitempty"`
+	} `json:"actor"`
+	Thread struct {
+		ID    int    `json:"id,omitempty"`
+		Title string `json:"title,omitempty"`
+	} `json:"thread,omitempty"`
+	PostID    int       `json:"post_id,omitempty"`
+	IsRead    bool      `json:"is_read"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Conversation struct {
+	ID   int `json:"id"`
+	With struct {
+		ID     int    `json:"id"`
+		Name   string `json:"name"`
+		Avatar string `json:"avatar,omitempty"`
+	} `json:"with"`
+	LastMessage *Message  `json:"last_message,omitempty"`
+	Unread      int       `json:"unread"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type Message struct {
+	ID             int       `json:"id"`
+	ConversationID int       `json:"conversation_id"`
+	AuthorID       int       `json:"author_id"`
+	Content        string    `json:"content"`
+	IsRead         bool      `json:"is_read"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type SearchHit struct {
+	Type       string      `json:"type"`
+	ID         int         `json:"id"`
+	Title      string      `json:"title"`
+	Snippet    string      `json:"snippet"`
+	Highlights []Highlight `json:"highlights,omitempty"`
+	Status     string      `json:"status,omitempty"`
+	Score      float64     `json:"score"`
+	CreatedAt  time.Time   `json:"created_at"`
+	Author     struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	} `json:"author"`
+	Thread struct {
+		ID    int    `json:"id,omitempty"`
+		Title string `json:"title,omitempty"`
+	} `json:"thread,omitempty"`
 }
@@ -0,0 +1,95 @@
+package data
+
+import (
+	"Projet-Forum/internal/api"
+	"Projet-Forum/internal/validator"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+type NotificationModel struct {
+	uri         string
+	endpoint    string
+	clientToken string
+	pemKey      []byte
+}
+
+func (m *NotificationModel) api() *api.API {
+	return api.GetInstance(m.uri, m.clientToken, m.pemKey)
+}
+
+func (m *NotificationModel) Get(token string, query url.Values, v *validator.Validator) ([]*Notification, int, Metadata, error) {
+
+	// making the request
+	res, status, err := m.api().Get(token, m.endpoint, query)
+	if err != nil {
+		return nil, 0, Metadata{}, err
+	}
+
+	// checking for errors
+	err = api.GetErr(status, res, v)
+	if err != nil {
+		return nil, 0, Metadata{}, err
+	}
+
+	var response struct {
+		Metadata      Metadata        `json:"_metadata"`
+		Unread        int             `json:"unread"`
+		Notifications []*Notification `json:"notifications"`
+	}
+	if v.Valid() {
+
+		// retrieving the notifications
+		err = json.Unmarshal(res, &response)
+		if err != nil {
+			return nil, 0, Metadata{}, err
+		}
+	}
+
+	return response.Notifications, response.Unread, response.Metadata, nil
+}
+
+// MarkAsRead marks the notifications with the given ids as read (all of them if ids is empty)
+// and returns the number of notifications still unread
+func (m *NotificationModel) MarkAsRead(token string, ids []int, v *validator.Validator) (int, error) {
+
+	// creating the request body
+	body := envelope{
+		"ids": ids,
+	}
+	reqBody, err := json.Marshal(body)
+	if err != nil {
+		return 0, err
+	}
+
+	// building the endpoint's specific URL
+	endpoint := fmt.Sprintf("%s/read", m.endpoint)
+
+	// making the request
+	res, status, err := m.api().Request(token, http.MethodPut, endpoint, reqBody, false)
+	if err != nil {
+		return 0, err
+	}
+
+	// checking for errors
+	err = api.GetErr(status, res, v)
+	if err != nil {
+		return 0, err
+	}
+
+	var response struct {
+		Unread int `json:"unread"`
+	}
+	if v.Valid() {
+
+		// retrieving the unread count
+		err = json.Unmarshal(res, &response)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return response.Unread, nil
+}
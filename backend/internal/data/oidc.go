@@ -0,0 +1,88 @@
+package data
+
+import (
+	"Projet-Forum/internal/api"
+	"Projet-Forum/internal/validator"
+	"encoding/json"
+	"fmt"
+	"net/url"
+)
+
+type OIDCModel struct {
+	uri         string
+	endpoint    string
+	clientToken string
+	pemKey      []byte
+}
+
+func (m *OIDCModel) api() *api.API {
+	return api.GetInstance(m.uri, m.clientToken, m.pemKey)
+}
+
+// GetProviders returns the identity providers (OpenID Connect and GitHub) the users may log in with
+func (m *OIDCModel) GetProviders(v *validator.Validator) ([]*IdentityProvider, error) {
+
+	// building the endpoint's specific URL
+	endpoint := fmt.Sprintf("%s/providers", m.endpoint)
+
+	// making the request
+	res, status, err := m.api().Get("", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// checking for errors
+	err = api.GetErr(status, res, v)
+	if err != nil {
+		return nil, err
+	}
+	var providers []*IdentityProvider
+	if v.Valid() {
+
+		// retrieving the providers
+		var response = make(map[string]any)
+		err = json.Unmarshal(res, &response)
+		if err != nil {
+			return nil, err
+		}
+		err = api.UnmarshallSlice(response["providers"], &providers)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return providers, nil
+}
+
+// GetAuthorizationURL returns the URL of the provider to redirect the user to,
+// the query holding the redirect_uri, the state, the code_challenge and the nonce
+func (m *OIDCModel) GetAuthorizationURL(provider string, query url.Values, v *validator.Validator) (string, error) {
+
+	// building the endpoint's specific URL
+	endpoint := fmt.Sprintf("%s/%s/authorization", m.endpoint, url.PathEscape(provider))
+
+	// making the request
+	res, status, err := m.api().Get("", endpoint, query)
+	if err != nil {
+		return "", err
+	}
+
+	// checking for errors
+	err = api.GetErr(status, res, v)
+	if err != nil {
+		return "", err
+	}
+	var authURL string
+	if v.Valid() {
+
+		// retrieving the URL
+		var response = make(map[string]string)
+		err = json.Unmarshal(res, &response)
+		if err != nil {
+			return "", err
+		}
+		authURL = response["url"]
+	}
+
+	return authURL, nil
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"html/template"
 	"net/http"
 	"net/url"
 )
@@ -194,6 +195,79 @@ func (m *PostModel) GetByID(token string, id int, v *validator.Validator) (*Post
 	return post, nil
 }
 
+func (m *PostModel) GetReplies(token string, id int, query url.Values, v *validator.Validator) ([]*Post, error) {
+
+	// building the endpoint's specific URL
+	endpoint := fmt.Sprintf("%s/%d/replies", m.endpoint, id)
+
+	// making the request
+	res, status, err := m.api().Get(token, endpoint, query)
+	if err != nil {
+		return nil, err
+	}
+
+	// checking for errors
+	err = api.GetErr(status, res, v)
+	if err != nil {
+		return nil, err
+	}
+	var replies []*Post
+	if v.Valid() {
+
+		// retrieving the replies
+		var response = make(map[string][]*Post)
+		err = json.Unmarshal(res, &response)
+		if err != nil {
+			return nil, err
+		}
+		replies = response["replies"]
+	}
+
+	return replies, nil
+}
+
+// Preview returns the content rendered by the API as it would appear once posted
+func (m *PostModel) Preview(token, content string, v *validator.Validator) (template.HTML, error) {
+
+	// creating the request body
+	body := envelope{
+		"content": content,
+	}
+	reqBody, err := json.Marshal(body)
+	if err != nil {
+		return "", err
+	}
+
+	// building the endpoint's specific URL
+	endpoint := fmt.Sprintf("%s/preview", m.endpoint)
+
+	// making the request
+	res, status, err := m.api().Request(token, http.MethodPost, endpoint, reqBody, false)
+	if err != nil {
+		return "", err
+	}
+
+	// checking for errors
+	err = api.GetErr(status, res, v)
+	if err != nil {
+		return "", err
+	}
+	if !v.Valid() {
+		return "", nil
+	}
+
+	// retrieving the rendered content
+	var response struct {
+		ContentHTML template.HTML `json:"content_html"`
+	}
+	err = json.Unmarshal(res, &response)
+	if err != nil {
+		return "", err
+	}
+
+	return response.ContentHTML, nil
+}
+
 func (m *PostModel) React(token, reaction string, id int, v *validator.Validator) error {
 
 	// creating the request body
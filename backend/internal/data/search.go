@@ -0,0 +1,55 @@
+package data
+
+import (
+	"Projet-Forum/internal/api"
+	"Projet-Forum/internal/validator"
+	"encoding/json"
+	"net/url"
+)
+
+type SearchModel struct {
+	uri         string
+	endpoint    string
+	clientToken string
+	pemKey      []byte
+}
+
+func (m *SearchModel) api() *api.API {
+	return api.GetInstance(m.uri, m.clientToken, m.pemKey)
+}
+
+func (m *SearchModel) Get(token string, query url.Values, v *validator.Validator) ([]*SearchHit, Metadata, error) {
+
+	// making the request
+	res, status, err := m.api().Get(token, m.endpoint, query)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	// checking for errors
+	err = api.GetErr(status, res, v)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	var hits []*SearchHit
+	var metadata Metadata
+	if v.Valid() {
+
+		// retrieving the results
+		var response = make(map[string]any)
+		err = json.Unmarshal(res, &response)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		err = api.UnmarshallSlice(response["results"], &hits)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		err = api.Unmarshall(response["_metadata"], &metadata)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+	}
+
+	return hits, metadata, nil
+}
@@ -0,0 +1,30 @@
+package data
+
+import (
+	"Projet-Forum/internal/api"
+	"context"
+	"net/http"
+	"net/url"
+)
+
+type StreamModel struct {
+	uri         string
+	endpoint    string
+	clientToken string
+	pemKey      []byte
+}
+
+func (m *StreamModel) api() *api.API {
+	return api.GetInstance(m.uri, m.clientToken, m.pemKey)
+}
+
+// Open subscribes to the API's event stream for the given channels (e.g. "thread:12", "notifications").
+// The stream is closed when ctx is done or when the response's body is closed.
+func (m *StreamModel) Open(ctx context.Context, token string, channels []string) (*http.Response, error) {
+
+	query := url.Values{
+		"channels[]": channels,
+	}
+
+	return m.api().Stream(ctx, token, m.endpoint, query)
+}
@@ -234,3 +234,107 @@ func (m *ThreadModel) RemoveFromFavorite(token string, id int, v *validator.Vali
 
 	return nil
 }
+
+// GetSubscription returns the level of the authenticated user's subscription to the thread
+func (m *ThreadModel) GetSubscription(token string, id int, v *validator.Validator) (string, error) {
+
+	// building the endpoint's specific URL
+	endpoint := fmt.Sprintf("%s/%d/subscription", m.endpoint, id)
+
+	// making the request
+	res, status, err := m.api().Get(token, endpoint, nil)
+	if err != nil {
+		return "", err
+	}
+
+	// checking for errors
+	err = api.GetErr(status, res, v)
+	if err != nil {
+		return "", err
+	}
+	var subscription *Subscription
+	if v.Valid() {
+
+		// retrieving the subscription
+		var response = make(map[string]*Subscription)
+		err = json.Unmarshal(res, &response)
+		if err != nil {
+			return "", err
+		}
+		subscription = response["subscription"]
+	}
+
+	if subscription == nil {
+		return "", nil
+	}
+
+	return subscription.Level, nil
+}
+
+// UpdateSubscription sets the level of the authenticated user's subscription to the thread
+func (m *ThreadModel) UpdateSubscription(token string, id int, level string, v *validator.Validator) error {
+
+	// creating the request body
+	body := envelope{
+		"level": level,
+	}
+	reqBody, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	// building the endpoint's specific URL
+	endpoint := fmt.Sprintf("%s/%d/subscription", m.endpoint, id)
+
+	// making the request
+	res, status, err := m.api().Request(token, http.MethodPut, endpoint, reqBody, false)
+	if err != nil {
+		return err
+	}
+
+	// checking for errors
+	err = api.GetErr(status, res, v)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Unsubscribe unsubscribes the user from the thread with the signed token of the link sent by email
+func (m *ThreadModel) Unsubscribe(unsubscribeToken string, v *validator.Validator) (string, error) {
+
+	// creating the request body
+	body := envelope{
+		"token": unsubscribeToken,
+	}
+	reqBody, err := json.Marshal(body)
+	if err != nil {
+		return "", err
+	}
+
+	// making the request
+	res, status, err := m.api().Request("", http.MethodPut, "/subscriptions/unsubscribe", reqBody, false)
+	if err != nil {
+		return "", err
+	}
+
+	// checking for errors
+	err = api.GetErr(status, res, v)
+	if err != nil {
+		return "", err
+	}
+	var message string
+	if v.Valid() {
+
+		// retrieving the confirmation message
+		var response = make(map[string]string)
+		err = json.Unmarshal(res, &response)
+		if err != nil {
+			return "", err
+		}
+		message = response["message"]
+	}
+
+	return message, nil
+}
@@ -54,7 +54,43 @@ func (m *TokenModel) Authenticate(body map[string]string, v *validator.Validator
 	return tokens, nil
 }
 
-func (m *TokenModel) Refresh(token string, tokens *Tokens, v *validator.Validator) error {
+// AuthenticateMFA sends the two-factor authentication code of the user along with the token obtained with their password
+func (m *TokenModel) AuthenticateMFA(body map[string]string, v *validator.Validator) (*Tokens, error) {
+
+	// converting the body to JSON format
+	reqBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	// building the endpoint's specific URL
+	endpoint := fmt.Sprintf("%s/authentication/mfa", m.endpoint)
+
+	// making the request
+	res, status, err := m.api().Request("", http.MethodPost, endpoint, reqBody, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// checking for errors
+	err = api.GetErr(status, res, v)
+	if err != nil {
+		return nil, err
+	}
+	var tokens *Tokens
+	if v.Valid() {
+
+		// retrieving the tokens
+		err = json.Unmarshal(res, &tokens)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return tokens, nil
+}
+
+func (m *TokenModel) Refresh(tokens *Tokens, v *validator.Validator) error {
 
 	// creating the request body
 	body := envelope{
@@ -68,8 +104,8 @@ func (m *TokenModel) Refresh(token string, tokens *Tokens, v *validator.Validato
 	// building the endpoint's specific URL
 	endpoint := fmt.Sprintf("%s/refresh", m.endpoint)
 
-	// making the request
-	res, status, err := m.api().Request(token, http.MethodPost, endpoint, reqBody, false)
+	// making the request (the refresh token is in the body, it cannot authenticate the user)
+	res, status, err := m.api().Request("", http.MethodPost, endpoint, reqBody, false)
 	if err != nil {
 		return err
 	}
@@ -91,8 +127,27 @@ func (m *TokenModel) Refresh(token string, tokens *Tokens, v *validator.Validato
 	return nil
 }
 
+// Logout revokes the session of the token, leaving the user's other devices logged in
 func (m *TokenModel) Logout(token string, v *validator.Validator) error {
 
+	// making the request
+	res, status, err := m.api().Request(token, http.MethodDelete, "/users/me/sessions/current", nil, false)
+	if err != nil {
+		return err
+	}
+
+	// checking for errors
+	err = api.GetErr(status, res, v)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// LogoutEverywhere revokes every token of the user, on all their devices
+func (m *TokenModel) LogoutEverywhere(token string, v *validator.Validator) error {
+
 	// building the endpoint's specific URL
 	endpoint := fmt.Sprintf("%s/revoke/me", m.endpoint)
 
@@ -110,3 +165,54 @@ func (m *TokenModel) Logout(token string, v *validator.Validator) error {
 
 	return nil
 }
+
+// AuthenticateOIDC sends the authorization code of the identity provider along with the PKCE code verifier and the nonce.
+// The new users whose email address isn't verified by the provider get no token but an activation email (in the non-field error).
+func (m *TokenModel) AuthenticateOIDC(body map[string]string, v *validator.Validator) (*Tokens, error) {
+
+	// converting the body to JSON format
+	reqBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	// building the endpoint's specific URL
+	endpoint := fmt.Sprintf("%s/oidc", m.endpoint)
+
+	// making the request
+	res, status, err := m.api().Request("", http.MethodPost, endpoint, reqBody, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// checking for errors
+	err = api.GetErr(status, res, v)
+	if err != nil {
+		return nil, err
+	}
+	var tokens *Tokens
+	if v.Valid() {
+
+		// retrieving the tokens
+		var response struct {
+			Tokens
+			User *struct {
+				Message string `json:"message"`
+			} `json:"user"`
+		}
+		err = json.Unmarshal(res, &response)
+		if err != nil {
+			return nil, err
+		}
+
+		// the account must be activated first
+		if response.User != nil {
+			v.AddNonFieldError(response.User.Message)
+			return nil, nil
+		}
+
+		tokens = &response.Tokens
+	}
+
+	return tokens, nil
+}
@@ -111,6 +111,266 @@ func (m *UserModel) Update(token, previousPassword string, user *User, v *valida
 	return nil
 }
 
+// UpdateAvatar uploads the new avatar of the authenticated user and returns its URL
+func (m *UserModel) UpdateAvatar(token, filename string, content []byte, v *validator.Validator) (string, error) {
+
+	// building the endpoint's specific URL
+	endpoint := fmt.Sprintf("%s/me/avatar", m.endpoint)
+
+	// making the request
+	res, status, err := m.api().Upload(token, http.MethodPut, endpoint, "avatar", filename, content)
+	if err != nil {
+		return "", err
+	}
+
+	// checking for errors
+	err = api.GetErr(status, res, v)
+	if err != nil {
+		return "", err
+	}
+
+	var avatar string
+	if v.Valid() {
+
+		// retrieving the avatar's URL
+		var response = make(map[string]string)
+		err = json.Unmarshal(res, &response)
+		if err != nil {
+			return "", err
+		}
+		avatar = response["avatar"]
+	}
+
+	return avatar, nil
+}
+
+// GetPrivacy returns the privacy settings of the authenticated user
+func (m *UserModel) GetPrivacy(token string, v *validator.Validator) (*Privacy, error) {
+
+	// building the endpoint's specific URL
+	endpoint := fmt.Sprintf("%s/me/privacy", m.endpoint)
+
+	// making the request
+	res, status, err := m.api().Get(token, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// checking for errors
+	err = api.GetErr(status, res, v)
+	if err != nil {
+		return nil, err
+	}
+	var privacy *Privacy
+	if v.Valid() {
+
+		// retrieving the privacy settings
+		var response = make(map[string]*Privacy)
+		err = json.Unmarshal(res, &response)
+		if err != nil {
+			return nil, err
+		}
+		privacy = response["privacy"]
+	}
+
+	return privacy, nil
+}
+
+// UpdatePrivacy sends the new privacy settings of the authenticated user
+func (m *UserModel) UpdatePrivacy(token string, body []byte, v *validator.Validator) error {
+
+	// building the endpoint's specific URL
+	endpoint := fmt.Sprintf("%s/me/privacy", m.endpoint)
+
+	// making the request
+	res, status, err := m.api().Request(token, http.MethodPut, endpoint, body, false)
+	if err != nil {
+		return err
+	}
+
+	// checking for errors
+	err = api.GetErr(status, res, v)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetSessions returns the devices on which the authenticated user is logged in
+func (m *UserModel) GetSessions(token string, v *validator.Validator) ([]*Session, error) {
+
+	// building the endpoint's specific URL
+	endpoint := fmt.Sprintf("%s/me/sessions", m.endpoint)
+
+	// making the request
+	res, status, err := m.api().Get(token, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// checking for errors
+	err = api.GetErr(status, res, v)
+	if err != nil {
+		return nil, err
+	}
+	var sessions []*Session
+	if v.Valid() {
+
+		// retrieving the sessions
+		var response = make(map[string][]*Session)
+		err = json.Unmarshal(res, &response)
+		if err != nil {
+			return nil, err
+		}
+		sessions = response["sessions"]
+	}
+
+	return sessions, nil
+}
+
+// DeleteSession logs the authenticated user out of one of their devices
+func (m *UserModel) DeleteSession(token string, id int, v *validator.Validator) error {
+
+	// building the endpoint's specific URL
+	endpoint := fmt.Sprintf("%s/me/sessions/%d", m.endpoint, id)
+
+	// making the request
+	res, status, err := m.api().Request(token, http.MethodDelete, endpoint, nil, false)
+	if err != nil {
+		return err
+	}
+
+	// checking for errors
+	err = api.GetErr(status, res, v)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetTOTP returns the state of the two-factor authentication of the authenticated user
+func (m *UserModel) GetTOTP(token string, v *validator.Validator) (*TOTP, error) {
+
+	// building the endpoint's specific URL
+	endpoint := fmt.Sprintf("%s/me/totp", m.endpoint)
+
+	// making the request
+	res, status, err := m.api().Get(token, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// checking for errors
+	err = api.GetErr(status, res, v)
+	if err != nil {
+		return nil, err
+	}
+	var totp *TOTP
+	if v.Valid() {
+
+		// retrieving the two-factor authentication state
+		var response = make(map[string]*TOTP)
+		err = json.Unmarshal(res, &response)
+		if err != nil {
+			return nil, err
+		}
+		totp = response["totp"]
+	}
+
+	return totp, nil
+}
+
+// EnrollTOTP generates a new two-factor authentication secret for the authenticated user
+func (m *UserModel) EnrollTOTP(token string, v *validator.Validator) (*TOTPEnrolment, error) {
+
+	// building the endpoint's specific URL
+	endpoint := fmt.Sprintf("%s/me/totp", m.endpoint)
+
+	// making the request
+	res, status, err := m.api().Request(token, http.MethodPost, endpoint, nil, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// checking for errors
+	err = api.GetErr(status, res, v)
+	if err != nil {
+		return nil, err
+	}
+	var enrolment *TOTPEnrolment
+	if v.Valid() {
+
+		// retrieving the secret
+		var response = make(map[string]*TOTPEnrolment)
+		err = json.Unmarshal(res, &response)
+		if err != nil {
+			return nil, err
+		}
+		enrolment = response["totp"]
+	}
+
+	return enrolment, nil
+}
+
+// totpRequest sends a two-factor authentication code to the endpoint, returning the recovery codes if any
+func (m *UserModel) totpRequest(token, method, endpoint, code string, v *validator.Validator) ([]string, error) {
+
+	// creating the request body
+	body := envelope{
+		"code": code,
+	}
+	reqBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	// making the request
+	res, status, err := m.api().Request(token, method, endpoint, reqBody, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// checking for errors
+	err = api.GetErr(status, res, v)
+	if err != nil {
+		return nil, err
+	}
+	var codes []string
+	if v.Valid() {
+
+		// retrieving the recovery codes
+		var response = make(map[string]any)
+		err = json.Unmarshal(res, &response)
+		if err != nil {
+			return nil, err
+		}
+		err = api.Unmarshall(response["recovery_codes"], &codes)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return codes, nil
+}
+
+// ConfirmTOTP enables the two-factor authentication of the authenticated user with the first code of their app
+func (m *UserModel) ConfirmTOTP(token, code string, v *validator.Validator) ([]string, error) {
+	return m.totpRequest(token, http.MethodPut, fmt.Sprintf("%s/me/totp", m.endpoint), code, v)
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the authenticated user
+func (m *UserModel) RegenerateRecoveryCodes(token, code string, v *validator.Validator) ([]string, error) {
+	return m.totpRequest(token, http.MethodPost, fmt.Sprintf("%s/me/totp/recovery-codes", m.endpoint), code, v)
+}
+
+// DisableTOTP turns the two-factor authentication of the authenticated user off
+func (m *UserModel) DisableTOTP(token, code string, v *validator.Validator) error {
+	_, err := m.totpRequest(token, http.MethodDelete, fmt.Sprintf("%s/me/totp", m.endpoint), code, v)
+	return err
+}
+
 func (m *UserModel) Delete(token string, id string, v *validator.Validator) error {
 
 	// building the endpoint's specific URL
@@ -226,6 +486,36 @@ func (m *UserModel) Activate(activationToken string, v *validator.Validator) err
 	return nil
 }
 
+// Unlock lifts the lockout of the account with the link sent by email after too many failed logins
+func (m *UserModel) Unlock(unlockToken string, v *validator.Validator) error {
+
+	// creating the request body
+	body := envelope{
+		"token": unlockToken,
+	}
+	reqBody, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	// building the endpoint's specific URL
+	endpoint := fmt.Sprintf("%s/unlocked", m.endpoint)
+
+	// making the request
+	res, status, err := m.api().Request("", http.MethodPut, endpoint, reqBody, false)
+	if err != nil {
+		return err
+	}
+
+	// checking for errors
+	err = api.GetErr(status, res, v)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (m *UserModel) ForgotPassword(email string, v *validator.Validator) error {
 
 	// creating the request body
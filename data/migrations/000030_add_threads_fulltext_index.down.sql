@@ -0,0 +1,2 @@
+ALTER TABLE threads
+    DROP INDEX ft_threads_Title_Description;
\ No newline at end of file
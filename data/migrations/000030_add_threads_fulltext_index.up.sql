@@ -0,0 +1,2 @@
+ALTER TABLE threads
+    ADD FULLTEXT INDEX ft_threads_Title_Description (Title, Description);
\ No newline at end of file
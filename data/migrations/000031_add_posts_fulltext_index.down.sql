@@ -0,0 +1,2 @@
+ALTER TABLE posts
+    DROP INDEX ft_posts_Content;
\ No newline at end of file
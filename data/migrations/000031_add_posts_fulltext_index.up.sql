@@ -0,0 +1,2 @@
+ALTER TABLE posts
+    ADD FULLTEXT INDEX ft_posts_Content (Content);
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE tags
+    DROP INDEX ft_tags_Name;
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE tags
+    ADD FULLTEXT INDEX ft_tags_Name (Name);
\ No newline at end of file
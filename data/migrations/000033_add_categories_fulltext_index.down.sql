@@ -0,0 +1,2 @@
+ALTER TABLE categories
+    DROP INDEX ft_categories_Name;
\ No newline at end of file
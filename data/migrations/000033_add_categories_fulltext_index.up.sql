@@ -0,0 +1,2 @@
+ALTER TABLE categories
+    ADD FULLTEXT INDEX ft_categories_Name (Name);
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS reports;
\ No newline at end of file
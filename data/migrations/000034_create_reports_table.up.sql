@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS reports(
+                        Id_reports INTEGER UNSIGNED PRIMARY KEY AUTO_INCREMENT,
+                        Target_type VARCHAR(20) NOT NULL,
+                        Id_target INTEGER UNSIGNED NOT NULL,
+                        Id_reporter INTEGER UNSIGNED,
+                        Reason VARCHAR(500) NOT NULL,
+                        Status VARCHAR(20) NOT NULL DEFAULT 'pending',
+                        Created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                        Resolved_at DATETIME,
+                        Version INTEGER NOT NULL DEFAULT 1,
+                        INDEX idx_reports_Target (Target_type, Id_target),
+                        INDEX idx_reports_Status (Status)
+)ENGINE = INNODB;
\ No newline at end of file
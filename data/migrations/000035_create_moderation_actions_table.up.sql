@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS moderation_actions(
+                        Id_moderation_actions INTEGER UNSIGNED PRIMARY KEY AUTO_INCREMENT,
+                        Id_reports INTEGER UNSIGNED NOT NULL,
+                        Id_moderator INTEGER UNSIGNED,
+                        Action VARCHAR(20) NOT NULL,
+                        Note VARCHAR(500) NOT NULL DEFAULT '',
+                        Created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+)ENGINE = INNODB;
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE reports
+    DROP FOREIGN KEY fk_reports_Id_reporter;
\ No newline at end of file
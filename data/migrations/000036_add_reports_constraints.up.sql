@@ -0,0 +1,2 @@
+ALTER TABLE reports
+    ADD CONSTRAINT fk_reports_Id_reporter FOREIGN KEY(Id_reporter) REFERENCES users(Id_users) ON DELETE SET NULL;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE moderation_actions
+    DROP FOREIGN KEY fk_moderation_actions_Id_reports,
+    DROP FOREIGN KEY fk_moderation_actions_Id_moderator;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE moderation_actions
+    ADD CONSTRAINT fk_moderation_actions_Id_reports FOREIGN KEY(Id_reports) REFERENCES reports(Id_reports) ON DELETE CASCADE,
+    ADD CONSTRAINT fk_moderation_actions_Id_moderator FOREIGN KEY(Id_moderator) REFERENCES users(Id_users) ON DELETE SET NULL;
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE posts
+    ADD COLUMN Status VARCHAR(20) NOT NULL DEFAULT 'active';
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE users
+    DROP COLUMN Suspension_reason,
+    DROP COLUMN Suspended_until;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE users
+    ADD COLUMN Suspension_reason VARCHAR(500) NOT NULL DEFAULT '',
+    ADD COLUMN Suspended_until DATETIME;
\ No newline at end of file
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS audit_log(
+                        Id_audit_log INTEGER UNSIGNED PRIMARY KEY AUTO_INCREMENT,
+                        Id_actor INTEGER UNSIGNED,
+                        Actor_name VARCHAR(70),
+                        Id_client INTEGER UNSIGNED,
+                        Client_name VARCHAR(70),
+                        Action VARCHAR(20) NOT NULL,
+                        Entity_type VARCHAR(20) NOT NULL,
+                        Id_entity INTEGER UNSIGNED NOT NULL,
+                        Changes JSON,
+                        Ip VARCHAR(45) NOT NULL DEFAULT '',
+                        Created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                        INDEX idx_audit_log_Actor (Id_actor),
+                        INDEX idx_audit_log_Entity (Entity_type, Id_entity),
+                        INDEX idx_audit_log_Created_at (Created_at)
+)ENGINE = INNODB;
\ No newline at end of file
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS notifications(
+                        Id_notifications INTEGER UNSIGNED PRIMARY KEY AUTO_INCREMENT,
+                        Id_users INTEGER UNSIGNED NOT NULL,
+                        Id_actor INTEGER UNSIGNED,
+                        Type VARCHAR(20) NOT NULL,
+                        Id_threads INTEGER UNSIGNED,
+                        Id_posts INTEGER UNSIGNED,
+                        Is_read BOOLEAN NOT NULL DEFAULT false,
+                        Created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                        INDEX idx_notifications_Unread (Id_users, Is_read)
+)ENGINE = INNODB;
\ No newline at end of file
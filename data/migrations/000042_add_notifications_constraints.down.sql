@@ -0,0 +1,5 @@
+ALTER TABLE notifications
+    DROP FOREIGN KEY fk_notifications_Id_users,
+    DROP FOREIGN KEY fk_notifications_Id_actor,
+    DROP FOREIGN KEY fk_notifications_Id_threads,
+    DROP FOREIGN KEY fk_notifications_Id_posts;
\ No newline at end of file
@@ -0,0 +1,5 @@
+ALTER TABLE notifications
+    ADD CONSTRAINT fk_notifications_Id_users FOREIGN KEY(Id_users) REFERENCES users(Id_users) ON DELETE CASCADE,
+    ADD CONSTRAINT fk_notifications_Id_actor FOREIGN KEY(Id_actor) REFERENCES users(Id_users) ON DELETE SET NULL,
+    ADD CONSTRAINT fk_notifications_Id_threads FOREIGN KEY(Id_threads) REFERENCES threads(Id_threads) ON DELETE CASCADE,
+    ADD CONSTRAINT fk_notifications_Id_posts FOREIGN KEY(Id_posts) REFERENCES posts(Id_posts) ON DELETE CASCADE;
\ No newline at end of file
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS conversations(
+                        Id_conversations INTEGER UNSIGNED PRIMARY KEY AUTO_INCREMENT,
+                        Id_users_1 INTEGER UNSIGNED NOT NULL,
+                        Id_users_2 INTEGER UNSIGNED NOT NULL,
+                        Created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                        Updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                        UNIQUE INDEX idx_conversations_Users (Id_users_1, Id_users_2),
+                        INDEX idx_conversations_Id_users_2 (Id_users_2)
+)ENGINE = INNODB;
\ No newline at end of file
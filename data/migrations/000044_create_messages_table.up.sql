@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS messages(
+                        Id_messages INTEGER UNSIGNED PRIMARY KEY AUTO_INCREMENT,
+                        Id_conversations INTEGER UNSIGNED NOT NULL,
+                        Id_author INTEGER UNSIGNED,
+                        Content VARCHAR(1020) NOT NULL,
+                        Is_read BOOLEAN NOT NULL DEFAULT false,
+                        Created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                        INDEX idx_messages_Conversation (Id_conversations, Id_messages)
+)ENGINE = INNODB;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE conversations
+    DROP FOREIGN KEY fk_conversations_Id_users_1,
+    DROP FOREIGN KEY fk_conversations_Id_users_2;
\ No newline at end of file
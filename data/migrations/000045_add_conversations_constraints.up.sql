@@ -0,0 +1,3 @@
+ALTER TABLE conversations
+    ADD CONSTRAINT fk_conversations_Id_users_1 FOREIGN KEY(Id_users_1) REFERENCES users(Id_users) ON DELETE CASCADE,
+    ADD CONSTRAINT fk_conversations_Id_users_2 FOREIGN KEY(Id_users_2) REFERENCES users(Id_users) ON DELETE CASCADE;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE messages
+    DROP FOREIGN KEY fk_messages_Id_conversations,
+    DROP FOREIGN KEY fk_messages_Id_author;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE messages
+    ADD CONSTRAINT fk_messages_Id_conversations FOREIGN KEY(Id_conversations) REFERENCES conversations(Id_conversations) ON DELETE CASCADE,
+    ADD CONSTRAINT fk_messages_Id_author FOREIGN KEY(Id_author) REFERENCES users(Id_users) ON DELETE SET NULL;
\ No newline at end of file
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS post_revisions(
+                        Id_post_revisions INTEGER UNSIGNED PRIMARY KEY AUTO_INCREMENT,
+                        Id_posts INTEGER UNSIGNED NOT NULL,
+                        Version INTEGER NOT NULL,
+                        Content VARCHAR(1020) NOT NULL,
+                        Id_editor INTEGER UNSIGNED,
+                        Created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                        UNIQUE INDEX idx_post_revisions_Version (Id_posts, Version)
+)ENGINE = INNODB;
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS thread_revisions;
\ No newline at end of file
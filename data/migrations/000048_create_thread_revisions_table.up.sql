@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS thread_revisions(
+                        Id_thread_revisions INTEGER UNSIGNED PRIMARY KEY AUTO_INCREMENT,
+                        Id_threads INTEGER UNSIGNED NOT NULL,
+                        Version INTEGER NOT NULL,
+                        Title VARCHAR(125) NOT NULL,
+                        Description VARCHAR(1020) NOT NULL DEFAULT '',
+                        Id_editor INTEGER UNSIGNED,
+                        Created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                        UNIQUE INDEX idx_thread_revisions_Version (Id_threads, Version)
+)ENGINE = INNODB;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE post_revisions
+    DROP FOREIGN KEY fk_post_revisions_Id_posts,
+    DROP FOREIGN KEY fk_post_revisions_Id_editor;
\ No newline at end of file
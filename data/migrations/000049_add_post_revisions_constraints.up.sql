@@ -0,0 +1,3 @@
+ALTER TABLE post_revisions
+    ADD CONSTRAINT fk_post_revisions_Id_posts FOREIGN KEY(Id_posts) REFERENCES posts(Id_posts) ON DELETE CASCADE,
+    ADD CONSTRAINT fk_post_revisions_Id_editor FOREIGN KEY(Id_editor) REFERENCES users(Id_users) ON DELETE SET NULL;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE thread_revisions
+    DROP FOREIGN KEY fk_thread_revisions_Id_threads,
+    DROP FOREIGN KEY fk_thread_revisions_Id_editor;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE thread_revisions
+    ADD CONSTRAINT fk_thread_revisions_Id_threads FOREIGN KEY(Id_threads) REFERENCES threads(Id_threads) ON DELETE CASCADE,
+    ADD CONSTRAINT fk_thread_revisions_Id_editor FOREIGN KEY(Id_editor) REFERENCES users(Id_users) ON DELETE SET NULL;
\ No newline at end of file
@@ -0,0 +1,4 @@
+ALTER TABLE categories
+    DROP INDEX idx_categories_Deleted_at,
+    DROP COLUMN Deleted_at,
+    DROP COLUMN Deleted_by;
\ No newline at end of file
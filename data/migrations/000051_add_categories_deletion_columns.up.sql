@@ -0,0 +1,4 @@
+ALTER TABLE categories
+    ADD COLUMN Deleted_at DATETIME,
+    ADD COLUMN Deleted_by INTEGER UNSIGNED,
+    ADD INDEX idx_categories_Deleted_at (Deleted_at);
\ No newline at end of file
@@ -0,0 +1,4 @@
+ALTER TABLE threads
+    DROP INDEX idx_threads_Deleted_at,
+    DROP COLUMN Deleted_at,
+    DROP COLUMN Deleted_by;
\ No newline at end of file
@@ -0,0 +1,4 @@
+ALTER TABLE tags
+    DROP INDEX idx_tags_Deleted_at,
+    DROP COLUMN Deleted_at,
+    DROP COLUMN Deleted_by;
\ No newline at end of file
@@ -0,0 +1,4 @@
+ALTER TABLE posts
+    DROP INDEX idx_posts_Deleted_at,
+    DROP COLUMN Deleted_at,
+    DROP COLUMN Deleted_by;
\ No newline at end of file
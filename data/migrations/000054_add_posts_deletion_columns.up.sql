@@ -0,0 +1,4 @@
+ALTER TABLE posts
+    ADD COLUMN Deleted_at DATETIME,
+    ADD COLUMN Deleted_by INTEGER UNSIGNED,
+    ADD INDEX idx_posts_Deleted_at (Deleted_at);
\ No newline at end of file
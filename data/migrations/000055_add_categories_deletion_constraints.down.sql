@@ -0,0 +1,2 @@
+ALTER TABLE categories
+    DROP FOREIGN KEY fk_categories_Deleted_by;
\ No newline at end of file
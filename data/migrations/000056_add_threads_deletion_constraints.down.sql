@@ -0,0 +1,2 @@
+ALTER TABLE threads
+    DROP FOREIGN KEY fk_threads_Deleted_by;
\ No newline at end of file
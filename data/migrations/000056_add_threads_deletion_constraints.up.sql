@@ -0,0 +1,2 @@
+ALTER TABLE threads
+    ADD CONSTRAINT fk_threads_Deleted_by FOREIGN KEY(Deleted_by) REFERENCES users(Id_users) ON DELETE SET NULL;
\ No newline at end of file
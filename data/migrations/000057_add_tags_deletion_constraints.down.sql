@@ -0,0 +1,2 @@
+ALTER TABLE tags
+    DROP FOREIGN KEY fk_tags_Deleted_by;
\ No newline at end of file
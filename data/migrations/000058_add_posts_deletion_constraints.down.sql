@@ -0,0 +1,2 @@
+ALTER TABLE posts
+    DROP FOREIGN KEY fk_posts_Deleted_by;
\ No newline at end of file
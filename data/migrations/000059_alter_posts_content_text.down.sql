@@ -0,0 +1,2 @@
+ALTER TABLE posts
+    MODIFY Content VARCHAR(1020) NOT NULL;
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE posts
+    MODIFY Content TEXT NOT NULL;
\ No newline at end of file
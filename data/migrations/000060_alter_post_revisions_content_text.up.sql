@@ -0,0 +1,2 @@
+ALTER TABLE post_revisions
+    MODIFY Content TEXT NOT NULL;
\ No newline at end of file
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS post_mentions(
+                        Id_post_mentions INTEGER UNSIGNED PRIMARY KEY AUTO_INCREMENT,
+                        Id_posts INTEGER UNSIGNED NOT NULL,
+                        Id_users INTEGER UNSIGNED,
+                        Id_tags INTEGER UNSIGNED,
+                        Position INTEGER UNSIGNED NOT NULL,
+                        Length INTEGER UNSIGNED NOT NULL,
+                        INDEX idx_post_mentions_Id_posts (Id_posts, Position)
+)ENGINE = INNODB;
\ No newline at end of file
@@ -0,0 +1,4 @@
+ALTER TABLE post_mentions
+    DROP FOREIGN KEY fk_post_mentions_Id_posts,
+    DROP FOREIGN KEY fk_post_mentions_Id_users,
+    DROP FOREIGN KEY fk_post_mentions_Id_tags;
\ No newline at end of file
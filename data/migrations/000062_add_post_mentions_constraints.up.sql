@@ -0,0 +1,4 @@
+ALTER TABLE post_mentions
+    ADD CONSTRAINT fk_post_mentions_Id_posts FOREIGN KEY(Id_posts) REFERENCES posts(Id_posts) ON DELETE CASCADE,
+    ADD CONSTRAINT fk_post_mentions_Id_users FOREIGN KEY(Id_users) REFERENCES users(Id_users) ON DELETE CASCADE,
+    ADD CONSTRAINT fk_post_mentions_Id_tags FOREIGN KEY(Id_tags) REFERENCES tags(Id_tags) ON DELETE CASCADE;
\ No newline at end of file
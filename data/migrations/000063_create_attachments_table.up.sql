@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS attachments(
+                        Id_attachments INTEGER UNSIGNED PRIMARY KEY AUTO_INCREMENT,
+                        Id_posts INTEGER UNSIGNED,
+                        Id_users INTEGER UNSIGNED,
+                        Filename VARCHAR(255) NOT NULL,
+                        Content_type VARCHAR(100) NOT NULL,
+                        Size INTEGER UNSIGNED NOT NULL,
+                        Storage_key VARCHAR(255) NOT NULL,
+                        Thumbnail_key VARCHAR(255),
+                        Thumbnail_size INTEGER UNSIGNED NOT NULL DEFAULT 0,
+                        Width INTEGER UNSIGNED,
+                        Height INTEGER UNSIGNED,
+                        Created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                        INDEX idx_attachments_Id_posts (Id_posts),
+                        INDEX idx_attachments_Id_users (Id_users)
+)ENGINE = INNODB;
\ No newline at end of file
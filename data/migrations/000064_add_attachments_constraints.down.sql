@@ -0,0 +1,3 @@
+ALTER TABLE attachments
+    DROP FOREIGN KEY fk_attachments_Id_posts,
+    DROP FOREIGN KEY fk_attachments_Id_users;
\ No newline at end of file
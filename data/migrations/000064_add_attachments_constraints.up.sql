@@ -0,0 +1,3 @@
+ALTER TABLE attachments
+    ADD CONSTRAINT fk_attachments_Id_posts FOREIGN KEY(Id_posts) REFERENCES posts(Id_posts) ON DELETE SET NULL,
+    ADD CONSTRAINT fk_attachments_Id_users FOREIGN KEY(Id_users) REFERENCES users(Id_users) ON DELETE SET NULL;
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS user_privacy;
\ No newline at end of file
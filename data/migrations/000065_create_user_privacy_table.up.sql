@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS user_privacy(
+                        Id_users INTEGER UNSIGNED PRIMARY KEY,
+                        Email VARCHAR(20) NOT NULL DEFAULT 'private',
+                        Birth_date VARCHAR(20) NOT NULL DEFAULT 'private',
+                        Friends VARCHAR(20) NOT NULL DEFAULT 'public',
+                        Favorite_threads VARCHAR(20) NOT NULL DEFAULT 'private',
+                        Following_tags VARCHAR(20) NOT NULL DEFAULT 'public',
+                        Reactions VARCHAR(20) NOT NULL DEFAULT 'private',
+                        Updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+)ENGINE = INNODB;
\ No newline at end of file
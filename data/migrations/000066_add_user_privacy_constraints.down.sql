@@ -0,0 +1,2 @@
+ALTER TABLE user_privacy
+    DROP FOREIGN KEY fk_user_privacy_Id_users;
\ No newline at end of file
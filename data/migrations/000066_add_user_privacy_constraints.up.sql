@@ -0,0 +1,2 @@
+ALTER TABLE user_privacy
+    ADD CONSTRAINT fk_user_privacy_Id_users FOREIGN KEY(Id_users) REFERENCES users(Id_users) ON DELETE CASCADE;
\ No newline at end of file
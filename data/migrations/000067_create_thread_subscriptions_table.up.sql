@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS thread_subscriptions(
+                        Id_users INTEGER UNSIGNED,
+                        Id_threads INTEGER UNSIGNED,
+                        Level VARCHAR(20) NOT NULL DEFAULT 'in_app',
+                        Last_digest_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                        Created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                        Updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+                        PRIMARY KEY(Id_users, Id_threads),
+                        INDEX idx_thread_subscriptions_Id_threads (Id_threads, Level),
+                        INDEX idx_thread_subscriptions_Level (Level, Last_digest_at)
+)ENGINE = INNODB;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE thread_subscriptions
+    DROP FOREIGN KEY fk_thread_subscriptions_Id_users,
+    DROP FOREIGN KEY fk_thread_subscriptions_Id_threads;
\ No newline at end of file
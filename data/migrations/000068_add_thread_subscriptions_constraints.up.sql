@@ -0,0 +1,3 @@
+ALTER TABLE thread_subscriptions
+    ADD CONSTRAINT fk_thread_subscriptions_Id_users FOREIGN KEY(Id_users) REFERENCES users(Id_users) ON DELETE CASCADE,
+    ADD CONSTRAINT fk_thread_subscriptions_Id_threads FOREIGN KEY(Id_threads) REFERENCES threads(Id_threads) ON DELETE CASCADE;
\ No newline at end of file
@@ -0,0 +1 @@
+DELETE FROM thread_subscriptions WHERE Level = 'in_app';
\ No newline at end of file
@@ -0,0 +1,2 @@
+INSERT IGNORE INTO thread_subscriptions (Id_users, Id_threads, Level)
+SELECT Id_users, Id_threads, 'in_app' FROM threads_users;
\ No newline at end of file
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS user_sessions(
+                        Id_user_sessions INTEGER UNSIGNED PRIMARY KEY AUTO_INCREMENT,
+                        Id_users INTEGER UNSIGNED,
+                        Device VARCHAR(100) NOT NULL,
+                        User_agent VARCHAR(255) NOT NULL DEFAULT '',
+                        Ip VARCHAR(45) NOT NULL DEFAULT '',
+                        Created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                        Last_seen_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                        INDEX idx_user_sessions_Id_users (Id_users)
+)ENGINE = INNODB;
\ No newline at end of file
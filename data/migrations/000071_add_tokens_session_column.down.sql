@@ -0,0 +1,3 @@
+ALTER TABLE tokens
+    DROP INDEX idx_tokens_Id_user_sessions,
+    DROP COLUMN Id_user_sessions;
\ No newline at end of file
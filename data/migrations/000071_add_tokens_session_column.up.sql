@@ -0,0 +1,3 @@
+ALTER TABLE tokens
+    ADD COLUMN Id_user_sessions INTEGER UNSIGNED,
+    ADD INDEX idx_tokens_Id_user_sessions (Id_user_sessions);
\ No newline at end of file
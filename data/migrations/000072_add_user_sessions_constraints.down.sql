@@ -0,0 +1,2 @@
+ALTER TABLE user_sessions
+    DROP FOREIGN KEY fk_user_sessions_Id_users;
\ No newline at end of file
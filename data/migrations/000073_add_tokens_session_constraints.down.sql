@@ -0,0 +1,2 @@
+ALTER TABLE tokens
+    DROP FOREIGN KEY fk_tokens_Id_user_sessions;
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE tokens
+    ADD CONSTRAINT fk_tokens_Id_user_sessions FOREIGN KEY(Id_user_sessions) REFERENCES user_sessions(Id_user_sessions) ON DELETE CASCADE;
\ No newline at end of file
@@ -0,0 +1,5 @@
+ALTER TABLE tokens
+    DROP INDEX idx_tokens_Family,
+    DROP COLUMN Family,
+    DROP COLUMN Parent_hash,
+    DROP COLUMN Used_at;
\ No newline at end of file
@@ -0,0 +1,5 @@
+ALTER TABLE tokens
+    ADD COLUMN Family CHAR(32),
+    ADD COLUMN Parent_hash CHAR(64),
+    ADD COLUMN Used_at DATETIME,
+    ADD INDEX idx_tokens_Family (Family);
\ No newline at end of file
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS user_totp(
+                        Id_users INTEGER UNSIGNED PRIMARY KEY,
+                        Secret VARCHAR(64) NOT NULL,
+                        Last_step BIGINT NOT NULL DEFAULT 0,
+                        Created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                        Confirmed_at DATETIME
+)ENGINE = INNODB;
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE user_totp
+    DROP FOREIGN KEY fk_user_totp_Id_users;
\ No newline at end of file
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS user_recovery_codes(
+                        Id_user_recovery_codes INTEGER UNSIGNED PRIMARY KEY AUTO_INCREMENT,
+                        Id_users INTEGER UNSIGNED,
+                        Hash CHAR(64) NOT NULL,
+                        Created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                        Used_at DATETIME,
+                        INDEX idx_user_recovery_codes_Id_users (Id_users)
+)ENGINE = INNODB;
\ No newline at end of file
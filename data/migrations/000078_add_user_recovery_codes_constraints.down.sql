@@ -0,0 +1,2 @@
+ALTER TABLE user_recovery_codes
+    DROP FOREIGN KEY fk_user_recovery_codes_Id_users;
\ No newline at end of file
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS login_throttles(
+                        Kind VARCHAR(10) NOT NULL,
+                        Subject VARCHAR(255) NOT NULL,
+                        Failures INTEGER UNSIGNED NOT NULL DEFAULT 0,
+                        Last_failure_at DATETIME NOT NULL,
+                        Locked_until DATETIME,
+                        PRIMARY KEY(Kind, Subject)
+)ENGINE = INNODB;
\ No newline at end of file
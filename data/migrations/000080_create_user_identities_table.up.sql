@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS user_identities(
+                        Id_user_identities INTEGER UNSIGNED PRIMARY KEY AUTO_INCREMENT,
+                        Id_users INTEGER UNSIGNED,
+                        Provider VARCHAR(50) NOT NULL,
+                        Subject VARCHAR(255) NOT NULL,
+                        Email VARCHAR(150) NOT NULL,
+                        Created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                        UNIQUE KEY uq_user_identities_Provider_Subject (Provider, Subject),
+                        INDEX idx_user_identities_Id_users (Id_users)
+)ENGINE = INNODB;
\ No newline at end of file
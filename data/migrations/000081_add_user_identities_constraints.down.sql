@@ -0,0 +1,2 @@
+ALTER TABLE user_identities
+    DROP FOREIGN KEY fk_user_identities_Id_users;
\ No newline at end of file
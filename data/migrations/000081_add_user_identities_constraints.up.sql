@@ -0,0 +1,2 @@
+ALTER TABLE user_identities
+    ADD CONSTRAINT fk_user_identities_Id_users FOREIGN KEY(Id_users) REFERENCES users(Id_users) ON DELETE CASCADE;
\ No newline at end of file
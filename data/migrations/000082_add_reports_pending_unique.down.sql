@@ -0,0 +1,3 @@
+ALTER TABLE reports
+    DROP INDEX uq_reports_Target_Pending_reporter,
+    DROP COLUMN Pending_reporter;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE reports
+    ADD COLUMN Pending_reporter INTEGER UNSIGNED AS (IF(Status = 'pending', Id_reporter, NULL)) VIRTUAL,
+    ADD UNIQUE KEY uq_reports_Target_Pending_reporter (Target_type, Id_target, Pending_reporter);
\ No newline at end of file